@@ -0,0 +1,243 @@
+package logger
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// adminTimeout bounds rotate and flush commands and reading request headers
+const adminTimeout = 5 * time.Second
+
+// adminUnixPrefix selects a unix socket listener in AdminAddress
+const adminUnixPrefix = "unix:"
+
+// Admin listener state, guarded by adminMu
+var (
+	adminMu      sync.Mutex
+	adminAddress string
+	adminToken   string
+	adminServer  *http.Server
+	adminSocket  string // path of the unix socket, removed when the listener stops
+)
+
+// adminStats is the response of the stats command, its counters named like those of heartbeat records
+type adminStats struct {
+	Level          string `json:"level"`
+	File           string `json:"file,omitempty"`
+	BytesWritten   uint64 `json:"bytes_written"`
+	RecordsWritten uint64 `json:"records_written"`
+	Rotations      uint64 `json:"rotations"`
+	Dropped        uint64 `json:"dropped"`
+}
+
+// configureAdmin starts, replaces or stops the admin listener to match the configuration.
+// A running listener is kept if address and token are unchanged.
+func configureAdmin(address, token string) error {
+	adminMu.Lock()
+	defer adminMu.Unlock()
+
+	if adminServer != nil && address == adminAddress && token == adminToken {
+		return nil
+	}
+	stopAdminLocked()
+	if address == "" {
+		return nil
+	}
+
+	network, addr := "tcp", address
+	if strings.HasPrefix(address, adminUnixPrefix) {
+		network, addr = "unix", strings.TrimPrefix(address, adminUnixPrefix)
+	}
+	if network == "tcp" && token == "" {
+		return fmt.Errorf("admin token required for TCP admin listener")
+	}
+
+	var ln net.Listener
+	var err error
+	if network == "unix" {
+		ln, err = listenAdminSocket(addr)
+	} else {
+		ln, err = net.Listen(network, addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start admin listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/level", adminLevel)
+	mux.HandleFunc("/rotate", adminRotate)
+	mux.HandleFunc("/flush", adminFlush)
+	mux.HandleFunc("/stats", adminStatsHandler)
+	mux.HandleFunc("/recent", serveRecent)
+
+	server := &http.Server{
+		Handler:           adminAuth(token, mux),
+		ReadHeaderTimeout: adminTimeout,
+	}
+	go server.Serve(ln)
+
+	adminServer = server
+	adminAddress = address
+	adminToken = token
+	if network == "unix" {
+		adminSocket = addr
+	}
+	return nil
+}
+
+// listenAdminSocket listens on a unix socket with mode 0600 at path. The socket is created in a private
+// directory and moved to path once restricted, so it never accepts connections with wider permissions.
+func listenAdminSocket(path string) (net.Listener, error) {
+	// Remove a stale socket left by an unclean exit
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".admin-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "sock")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// The socket is removed by stopAdminLocked at its final path
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to restrict admin socket permissions: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// stopAdmin stops the admin listener if running
+func stopAdmin() {
+	adminMu.Lock()
+	defer adminMu.Unlock()
+	stopAdminLocked()
+}
+
+// stopAdminLocked stops the admin listener, adminMu must be held.
+// Connections are closed without waiting, as handlers may wait for the lock held by the caller.
+func stopAdminLocked() {
+	if adminServer == nil {
+		return
+	}
+	_ = adminServer.Close()
+	if adminSocket != "" {
+		os.Remove(adminSocket)
+	}
+	adminServer = nil
+	adminSocket = ""
+	adminAddress = ""
+	adminToken = ""
+}
+
+// adminAuth requires the bearer token on every request if one is configured
+func adminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminLevel returns the current level on GET and sets it from the "level" parameter on POST.
+// With a "module" parameter, POST sets the level override of the module instead.
+func adminLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		level, err := parseLevel(r.FormValue("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if module := r.FormValue("module"); module != "" {
+			setModuleLevel(module, level, false)
+			writeAdminJSON(w, map[string]string{"module": module, "level": levelToString(level)})
+			return
+		}
+		setLevel(level)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeAdminJSON(w, map[string]string{"level": levelToString(currentLevel())})
+}
+
+// adminRotate closes the current file once the queued records are written and continues in a new one
+func adminRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), adminTimeout)
+	defer cancel()
+	if err := rotateLogger(ctx); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeAdminJSON(w, map[string]string{"status": "rotated"})
+}
+
+// adminFlush writes and syncs all queued records
+func adminFlush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), adminTimeout)
+	defer cancel()
+	if err := flushLogger(ctx); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeAdminJSON(w, map[string]string{"status": "flushed"})
+}
+
+// adminStatsHandler reports the running level, file and counters
+func adminStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stats := adminStats{
+		Level:          levelToString(logLevel.Load().(int64)),
+		BytesWritten:   bytesWritten.Load(),
+		RecordsWritten: recordsWritten.Load(),
+		Rotations:      rotationCount.Load(),
+		Dropped:        droppedLogs.Load(),
+	}
+	if f, ok := currentFile.Load().(*os.File); ok && f != nil {
+		stats.File = f.Name()
+	}
+	writeAdminJSON(w, stats)
+}
+
+// writeAdminJSON writes a JSON response
+func writeAdminJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Archiver uploads log files due for deletion to long-term storage such as an S3-compatible bucket.
+// Archive returns once the file at path is stored, the file is deleted only after it succeeds.
+// It is called from a single goroutine of the logger and may take as long as the upload needs.
+type Archiver interface {
+	Archive(ctx context.Context, path string) error
+}
+
+// ArchiverFunc adapts a function to the Archiver interface.
+type ArchiverFunc func(ctx context.Context, path string) error
+
+// Archive calls f(ctx, path).
+func (f ArchiverFunc) Archive(ctx context.Context, path string) error {
+	return f(ctx, path)
+}
+
+// archiveQueueSize bounds the files waiting for upload, further files are queued by later cleanups
+const archiveQueueSize = 64
+
+// errArchivePending reports a file queued for archival, deleted once archived
+var errArchivePending = errors.New("log file archival pending")
+
+// Archival state. Files in archivePending are queued or being uploaded, files in archiveDone were uploaded
+// but could not be deleted yet. Both are guarded by archiveMu.
+var (
+	archiver       atomic.Pointer[archiverRef]
+	archiveOnce    sync.Once
+	archiveQueue   chan string
+	archiveMu      sync.Mutex
+	archivePending = make(map[string]bool)
+	archiveDone    = make(map[string]bool)
+)
+
+// archiverRef holds the archiver, as atomic.Pointer requires a concrete type
+type archiverRef struct {
+	a Archiver
+}
+
+// setArchiver sets the archiver used before deleting files, nil deletes files directly
+func setArchiver(a Archiver) {
+	if a == nil {
+		archiver.Store(nil)
+		return
+	}
+	archiveOnce.Do(func() {
+		archiveQueue = make(chan string, archiveQueueSize)
+		go archiveFiles()
+	})
+	archiver.Store(&archiverRef{a: a})
+}
+
+// deleteLogFile deletes a log file due for deletion by cleanup or retention. With an archiver, the file is
+// queued for upload instead and errArchivePending returned, it is deleted once the upload succeeded.
+func deleteLogFile(path string) error {
+	if archiver.Load() == nil {
+		return removeLogFile(path)
+	}
+
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	if archiveDone[path] {
+		err := removeLogFile(path)
+		if err == nil || os.IsNotExist(err) {
+			delete(archiveDone, path)
+		}
+		return err
+	}
+	if !archivePending[path] {
+		select {
+		case archiveQueue <- path:
+			archivePending[path] = true
+		default:
+			// Queued again by a later cleanup
+		}
+	}
+	return errArchivePending
+}
+
+// archiveFiles uploads queued files and deletes them once uploaded. Failed uploads are retried when
+// cleanup selects the file again.
+func archiveFiles() {
+	for path := range archiveQueue {
+		err := errArchivePending
+		if ref := archiver.Load(); ref != nil {
+			err = ref.a.Archive(context.Background(), path)
+		}
+
+		archiveMu.Lock()
+		delete(archivePending, path)
+		if err == nil {
+			if err := removeLogFile(path); err != nil && !os.IsNotExist(err) {
+				archiveDone[path] = true
+			}
+		}
+		archiveMu.Unlock()
+	}
+}
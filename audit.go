@@ -0,0 +1,336 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Audit chain state, only accessed by the processor goroutine and during file close.
+// The chain continues across the files of the logger, a new file starts from the final hash of the file
+// closed before, named in its audit header.
+var (
+	auditChain    bool
+	auditHasher   hash.Hash = sha256.New()
+	auditPrev     []byte    = []byte(auditGenesis) // hex hash of the previous record
+	auditCount    int64                            // records chained in the current file
+	auditPrevFile string                           // file closed with the final hash auditPrev, empty at genesis
+)
+
+// auditGenesis is the previous hash of the first record of a new chain
+const auditGenesis = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Audit hash markers
+const (
+	auditHashLen     = sha256.Size * 2
+	auditJSONHashKey = `"hash":"`
+	auditTextHashKey = " hash="
+	auditFinalKey    = "audit_final_hash"
+	auditRecordsKey  = "audit_records"
+	auditPrevKey     = "audit_prev_hash"
+	auditPrevFileKey = "audit_prev_file"
+)
+
+// resetAuditChain starts a new chain from auditGenesis.
+func resetAuditChain() {
+	auditPrev = append(auditPrev[:0], auditGenesis...)
+	auditCount = 0
+	auditPrevFile = ""
+}
+
+// auditState is a snapshot of the chain, restored when a chained record is not written to the current file
+type auditState struct {
+	prev  [auditHashLen]byte
+	count int64
+}
+
+// saveAuditState returns a snapshot of the chain
+func saveAuditState() (st auditState) {
+	copy(st.prev[:], auditPrev)
+	st.count = auditCount
+	return st
+}
+
+// restore returns the chain to the snapshot
+func (st *auditState) restore() {
+	auditPrev = append(auditPrev[:0], st.prev[:]...)
+	auditCount = st.count
+}
+
+// auditHash returns the hex SHA-256 of the previous hash followed by the record body.
+func auditHash(prev, body []byte) []byte {
+	auditHasher.Reset()
+	auditHasher.Write(prev)
+	auditHasher.Write(body)
+	var sum [sha256.Size]byte
+	return hex.AppendEncode(nil, auditHasher.Sum(sum[:0]))
+}
+
+// appendAuditHash chains the serialized record in s.buf to the previous record.
+// JSON records get a "hash" member, text records a trailing "hash=" token.
+// The hash covers the previous hash and the record as it would be written without the hash.
+func (s *serializer) appendAuditHash() []byte {
+	line := s.buf[:len(s.buf)-1] // without newline
+	isJSON := len(line) > 0 && line[0] == '{'
+	if isJSON {
+		line = line[:len(line)-1] // without closing brace
+	}
+
+	sum := auditHash(auditPrev, line)
+	auditPrev = append(auditPrev[:0], sum...)
+	auditCount++
+
+	s.buf = line
+	if isJSON {
+		if len(line) > 1 {
+			s.buf = append(s.buf, ',')
+		}
+		s.buf = append(s.buf, auditJSONHashKey...)
+		s.buf = append(s.buf, sum...)
+		s.buf = append(s.buf, '"', '}', '\n')
+	} else {
+		s.buf = append(s.buf, auditTextHashKey...)
+		s.buf = append(s.buf, sum...)
+		s.buf = append(s.buf, '\n')
+	}
+	return s.buf
+}
+
+// writeAuditHeader opens a new file continuing the chain of the file closed before, with the final hash
+// and the name of that file. A new chain has no header.
+func writeAuditHeader(f *os.File) error {
+	auditCount = 0
+	if !auditChain || auditPrevFile == "" {
+		return nil
+	}
+
+	var header []byte
+	if isJSONFormat(format) {
+		header = fmt.Appendf(nil, "{\"%s\":\"%s\",\"%s\":%q}\n", auditPrevKey, auditPrev, auditPrevFileKey, auditPrevFile)
+	} else {
+		header = fmt.Appendf(nil, "%s=%s %s=%s\n", auditPrevKey, auditPrev, auditPrevFileKey, auditPrevFile)
+	}
+	n, err := f.Write(header)
+	currentSize.Add(int64(n))
+	return err
+}
+
+// writeAuditFooter writes the final hash and record count of the chain to the file being closed.
+// The next file continues the chain from it.
+func writeAuditFooter(f *os.File) error {
+	if !auditChain || f == nil {
+		return nil
+	}
+
+	var footer []byte
+	if isJSONFormat(format) {
+		footer = fmt.Appendf(nil, "{\"%s\":\"%s\",\"%s\":%d}\n", auditFinalKey, auditPrev, auditRecordsKey, auditCount)
+	} else {
+		footer = fmt.Appendf(nil, "%s=%s %s=%d\n", auditFinalKey, auditPrev, auditRecordsKey, auditCount)
+	}
+	auditPrevFile = filepath.Base(f.Name())
+	_, err := f.Write(footer)
+	return err
+}
+
+// verifyAuditFile recomputes the hash chain of an audit log file. Records containing raw newlines are
+// joined back before verification. The chain starts from the hash of the audit header, if present, and the
+// footer must match the final hash and record count. Only the active file of the logger may lack the footer.
+// If the file named by the header is in the same directory and closed, its footer must hold the hash of the header.
+func verifyAuditFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	prev := []byte(auditGenesis)
+	var count int64
+	var pending []byte
+	lineNo, startLine := 0, 0
+	footerSeen := false
+
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+
+		if footerSeen {
+			return fmt.Errorf("line %d: data after audit footer", lineNo)
+		}
+
+		if lineNo == 1 {
+			if header, previous, ok := parseAuditHeader(line); ok {
+				if err := verifyPreviousFile(filepath.Join(filepath.Dir(path), previous), header); err != nil {
+					return fmt.Errorf("line 1: %w", err)
+				}
+				prev = []byte(header)
+				continue
+			}
+		}
+
+		if pending == nil {
+			startLine = lineNo
+			if final, records, ok := parseAuditFooter(line); ok {
+				if final != string(prev) || records != count {
+					return fmt.Errorf("line %d: audit footer mismatch, chain has %d records ending in %s", lineNo, count, prev)
+				}
+				footerSeen = true
+				continue
+			}
+			pending = append([]byte{}, line...)
+		} else {
+			pending = append(append(pending, '\n'), line...)
+		}
+
+		body, sum, ok := splitAuditRecord(pending)
+		if !ok {
+			// Record continues on next line
+			continue
+		}
+		if expected := auditHash(prev, body); !bytes.Equal(expected, sum) {
+			return fmt.Errorf("line %d: audit hash mismatch", startLine)
+		}
+		prev = sum
+		count++
+		pending = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if pending != nil {
+		return fmt.Errorf("line %d: record without audit hash", startLine)
+	}
+	if !footerSeen && !isActiveFile(path) {
+		return fmt.Errorf("missing audit footer, chain has %d records ending in %s", count, prev)
+	}
+	return nil
+}
+
+// verifyPreviousFile checks that the footer of the file closed before a file holds the hash its header
+// continues from. A file no longer present, e.g. deleted by retention, is not checked, nor a file without
+// footer, e.g. one that failed to write, whose own verification reports it.
+func verifyPreviousFile(path, hash string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	last := bytes.TrimSuffix(data, []byte{'\n'})
+	if i := bytes.LastIndexByte(last, '\n'); i >= 0 {
+		last = last[i+1:]
+	}
+	if final, _, ok := parseAuditFooter(last); ok && final != hash {
+		return fmt.Errorf("audit header does not match the footer of %s", filepath.Base(path))
+	}
+	return nil
+}
+
+// isActiveFile reports whether path is the file the logger is writing
+func isActiveFile(path string) bool {
+	f, _ := currentFile.Load().(*os.File)
+	if f == nil {
+		return false
+	}
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	fileInfo, err := f.Stat()
+	return err == nil && os.SameFile(pathInfo, fileInfo)
+}
+
+// splitAuditRecord separates a chained record into the hashed body and its hash
+func splitAuditRecord(record []byte) (body, sum []byte, ok bool) {
+	if len(record) > 0 && record[0] == '{' {
+		suffixLen := len(auditJSONHashKey) + auditHashLen + 2
+		if len(record) < suffixLen+1 || !bytes.HasSuffix(record, []byte{'"', '}'}) {
+			return nil, nil, false
+		}
+		start := len(record) - suffixLen
+		if !bytes.HasPrefix(record[start:], []byte(auditJSONHashKey)) {
+			return nil, nil, false
+		}
+		sum = record[start+len(auditJSONHashKey) : len(record)-2]
+		body = record[:start]
+		if len(body) > 1 && body[len(body)-1] == ',' {
+			body = body[:len(body)-1]
+		}
+		return body, sum, true
+	}
+
+	suffixLen := len(auditTextHashKey) + auditHashLen
+	if len(record) < suffixLen {
+		return nil, nil, false
+	}
+	start := len(record) - suffixLen
+	if !bytes.HasPrefix(record[start:], []byte(auditTextHashKey)) {
+		return nil, nil, false
+	}
+	return record[:start], record[start+len(auditTextHashKey):], true
+}
+
+// parseAuditHeader parses the JSON or text header written by writeAuditHeader
+func parseAuditHeader(line []byte) (prev, file string, ok bool) {
+	s := string(line)
+	hashPrefix, filePrefix, suffix := auditPrevKey+"=", " "+auditPrevFileKey+"=", ""
+	if strings.HasPrefix(s, "{") {
+		hashPrefix = `{"` + auditPrevKey + `":"`
+		filePrefix = `","` + auditPrevFileKey + `":`
+		suffix = "}"
+	}
+
+	if !strings.HasPrefix(s, hashPrefix) || !strings.HasSuffix(s, suffix) {
+		return "", "", false
+	}
+	s = strings.TrimSuffix(s[len(hashPrefix):], suffix)
+	if len(s) < auditHashLen || !strings.HasPrefix(s[auditHashLen:], filePrefix) {
+		return "", "", false
+	}
+	file = s[auditHashLen+len(filePrefix):]
+	if suffix != "" {
+		var err error
+		if file, err = strconv.Unquote(file); err != nil {
+			return "", "", false
+		}
+	}
+	if file == "" || strings.ContainsAny(file, `/\`) {
+		return "", "", false
+	}
+	return s[:auditHashLen], file, true
+}
+
+// parseAuditFooter parses the JSON or text footer written by writeAuditFooter
+func parseAuditFooter(line []byte) (final string, records int64, ok bool) {
+	s := string(line)
+	hashPrefix, countPrefix, suffix := auditFinalKey+"=", " "+auditRecordsKey+"=", ""
+	if strings.HasPrefix(s, "{") {
+		hashPrefix = `{"` + auditFinalKey + `":"`
+		countPrefix = `","` + auditRecordsKey + `":`
+		suffix = "}"
+	}
+
+	if !strings.HasPrefix(s, hashPrefix) || !strings.HasSuffix(s, suffix) {
+		return "", 0, false
+	}
+	s = strings.TrimSuffix(s[len(hashPrefix):], suffix)
+	if len(s) < auditHashLen || !strings.HasPrefix(s[auditHashLen:], countPrefix) {
+		return "", 0, false
+	}
+	count, err := strconv.ParseInt(s[auditHashLen+len(countPrefix):], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return s[:auditHashLen], count, true
+}
@@ -0,0 +1,101 @@
+package logger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LixenWraith/logger"
+)
+
+// queueTypes are the queues every benchmark runs on, as sub-benchmarks named after the queue
+var queueTypes = []string{logger.QueueChannel, logger.QueueRing}
+
+// initBench initializes the logger with the queue type in a temporary directory for the benchmark and shuts
+// it down after. The timer starts after initialization, so allocations of the queue are not counted.
+func initBench(b *testing.B, queueType string) context.Context {
+	ctx := context.Background()
+	cfg := &logger.LoggerConfig{
+		Name:       "bench",
+		Directory:  b.TempDir(),
+		BufferSize: 100000,
+		MaxSizeMB:  100,
+		QueueType:  queueType,
+	}
+	if err := logger.Init(ctx, cfg); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { logger.Shutdown(ctx) })
+	b.ReportAllocs()
+	b.ResetTimer()
+	return ctx
+}
+
+// benchQueues runs a benchmark on each queue type
+func benchQueues(b *testing.B, bench func(b *testing.B, ctx context.Context)) {
+	for _, queueType := range queueTypes {
+		b.Run(queueType, func(b *testing.B) {
+			bench(b, initBench(b, queueType))
+		})
+	}
+}
+
+func BenchmarkInfoArgs(b *testing.B) {
+	benchQueues(b, func(b *testing.B, ctx context.Context) {
+		for i := 0; i < b.N; i++ {
+			logger.Info(ctx, "request served", "method", "GET", "status", 200, "cached", true)
+		}
+	})
+}
+
+func BenchmarkInfoFields3(b *testing.B) {
+	benchQueues(b, func(b *testing.B, ctx context.Context) {
+		for i := 0; i < b.N; i++ {
+			logger.InfoFields(ctx, "request served",
+				logger.Str("method", "GET"), logger.Int("status", 200), logger.Bool("cached", true))
+		}
+	})
+}
+
+func BenchmarkInfoFields8(b *testing.B) {
+	benchQueues(b, func(b *testing.B, ctx context.Context) {
+		for i := 0; i < b.N; i++ {
+			logger.InfoFields(ctx, "request served",
+				logger.Str("method", "GET"), logger.Str("path", "/api/v1/items"),
+				logger.Int("status", 200), logger.Int64("bytes", 5120),
+				logger.Float64("latency_ms", 1.25), logger.Bool("cached", true),
+				logger.Uint64("seq", uint64(i)), logger.Str("proto", "HTTP/1.1"))
+		}
+	})
+}
+
+func BenchmarkInfoFieldsParallel(b *testing.B) {
+	benchQueues(b, func(b *testing.B, ctx context.Context) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				logger.InfoFields(ctx, "request served",
+					logger.Str("method", "GET"), logger.Int("status", 200))
+			}
+		})
+	})
+}
+
+// BenchmarkInfoFieldsContended runs 16 producers per CPU, the contention the ring queue is meant for
+func BenchmarkInfoFieldsContended(b *testing.B) {
+	benchQueues(b, func(b *testing.B, ctx context.Context) {
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				logger.InfoFields(ctx, "request served",
+					logger.Str("method", "GET"), logger.Int("status", 200))
+			}
+		})
+	})
+}
+
+func BenchmarkDebugFiltered(b *testing.B) {
+	benchQueues(b, func(b *testing.B, ctx context.Context) {
+		for i := 0; i < b.N; i++ {
+			logger.DebugFields(ctx, "filtered by level", logger.Int("i", i))
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Local buffer defaults
+const (
+	defaultLocalBufferSize     = 64
+	defaultLocalBufferInterval = 100 * time.Millisecond
+)
+
+// LocalBuffer collects the records of a single producer, typically one goroutine, and hands them
+// off to the logger as one batch when it is full, when the flush interval since the first buffered
+// record passed, or on Flush. Many producers logging at high rates then contend on the shared
+// channel once per batch instead of once per record.
+//
+// Records are subject to the same level and disk checks as direct logging, and are written in order
+// within a batch. Call Flush before the producer exits and before Shutdown, otherwise buffered
+// records may be lost.
+type LocalBuffer struct {
+	mu       sync.Mutex
+	records  []logRecord
+	size     int
+	interval time.Duration
+	timer    *time.Timer
+}
+
+// NewLocalBuffer creates a local buffer holding up to size records, handed off at least every
+// interval. Non-positive values select a size of 64 records and an interval of 100ms.
+func NewLocalBuffer(size int, interval time.Duration) *LocalBuffer {
+	if size < 1 {
+		size = defaultLocalBufferSize
+	}
+	if interval <= 0 {
+		interval = defaultLocalBufferInterval
+	}
+	return &LocalBuffer{size: size, interval: interval}
+}
+
+// Debug buffers a message at debug level.
+func (b *LocalBuffer) Debug(logCtx context.Context, args ...any) {
+	b.add(logCtx, flags, LevelDebug, traceDepth, args...)
+}
+
+// Info buffers a message at info level.
+func (b *LocalBuffer) Info(logCtx context.Context, args ...any) {
+	b.add(logCtx, flags, LevelInfo, traceDepth, args...)
+}
+
+// Warn buffers a message at warning level.
+func (b *LocalBuffer) Warn(logCtx context.Context, args ...any) {
+	b.add(logCtx, flags, LevelWarn, traceDepth, args...)
+}
+
+// Error buffers a message at error level.
+func (b *LocalBuffer) Error(logCtx context.Context, args ...any) {
+	b.add(logCtx, flags, LevelError, traceDepth, args...)
+}
+
+// Flush hands off all buffered records to the logger.
+func (b *LocalBuffer) Flush() {
+	b.mu.Lock()
+	batch := b.records
+	b.records = nil
+	b.mu.Unlock()
+
+	if len(batch) > 0 {
+		sendLogRecord(logRecord{Batch: batch})
+	}
+}
+
+// add buffers a record, handing off the batch when the buffer is full
+func (b *LocalBuffer) add(logCtx context.Context, flags int64, level int64, depth int64, args ...any) {
+	if !admit(logCtx, level) {
+		return
+	}
+
+	var trace string
+	var frames []traceFrame
+	if depth > 0 {
+		trace, frames = getTrace(depth, skipTrace+callerSkip)
+	}
+
+	record := logRecord{
+		LogCtx:    logCtx,
+		Flags:     flags,
+		TimeStamp: clockNow(),
+		Level:     level,
+		Trace:     trace,
+		Frames:    frames,
+		Args:      withContextArgs(logCtx, args),
+	}
+	stampSequence(&record)
+	stampGoroutine(&record)
+
+	b.mu.Lock()
+	if b.records == nil {
+		b.records = make([]logRecord, 0, b.size)
+	}
+	b.records = append(b.records, record)
+
+	if len(b.records) >= b.size {
+		batch := b.records
+		b.records = nil
+		b.mu.Unlock()
+		sendLogRecord(logRecord{Batch: batch})
+		return
+	}
+
+	// Interval starts with the first record of a batch
+	if len(b.records) == 1 {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.interval, b.Flush)
+		} else {
+			b.timer.Reset(b.interval)
+		}
+	}
+	b.mu.Unlock()
+}
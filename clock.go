@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock provides the time of records and the tickers of the processor, so tests can control time
+// instead of sleeping through flush, heartbeat and retention intervals.
+type Clock interface {
+	Now() time.Time
+	Ticker(d time.Duration) Ticker
+}
+
+// Ticker delivers the ticks of a Clock, like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// clockRef wraps the clock, atomic.Pointer requires a concrete type
+type clockRef struct {
+	Clock
+}
+
+// clock is the clock set by SetClock, the system clock if nil
+var clock atomic.Pointer[clockRef]
+
+// systemClock is the Clock of the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Ticker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// systemTicker is the Ticker of the time package
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTicker) Stop() {
+	t.t.Stop()
+}
+
+// setClock sets the clock, nil restores the system clock
+func setClock(c Clock) {
+	if c == nil {
+		clock.Store(nil)
+		return
+	}
+	clock.Store(&clockRef{c})
+}
+
+// clockNow returns the current time of the clock
+func clockNow() time.Time {
+	if c := clock.Load(); c != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// newTicker returns a ticker of the clock
+func newTicker(d time.Duration) Ticker {
+	if c := clock.Load(); c != nil {
+		return c.Ticker(d)
+	}
+	return systemClock{}.Ticker(d)
+}
@@ -0,0 +1,626 @@
+// Command lgr inspects the log files written by the logger: it merges the rotated files of a directory in
+// record order, filters them by level, time, fields and substring, pretty-prints them with colors and follows
+// the directory like tail -f, picking up files created by rotation.
+//
+// Usage:
+//
+//	lgr [flags] path...
+//
+// A path is a log file or a log directory. Files of a directory are ordered by the time stamp in their names,
+// <name>_<yymmdd>_<hhmmss>_<fraction>.<ext>, and the files of differently named loggers are merged by record time.
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+// followInterval is how often followed files and directories are polled for new records
+const followInterval = 250 * time.Millisecond
+
+// ANSI colors of the pretty output
+const (
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+	colorKey   = "\x1b[36m"
+)
+
+// levelColors are the colors of the level names, by the lowest level they apply to
+var levelColors = []struct {
+	level int64
+	color string
+}{
+	{logger.LevelError, "\x1b[31m"},
+	{logger.LevelWarn, "\x1b[33m"},
+	{logger.LevelInfo, "\x1b[32m"},
+	{logger.LevelDebug, "\x1b[34m"},
+}
+
+// fieldFlags collects repeated -field key=value flags
+type fieldFlags map[string]string
+
+func (f fieldFlags) String() string {
+	return fmt.Sprint(map[string]string(f))
+}
+
+func (f fieldFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value")
+	}
+	f[key] = value
+	return nil
+}
+
+// filter selects the printed records
+type filter struct {
+	minLevel    int64
+	filterLevel bool
+	since       time.Time
+	until       time.Time
+	contains    string
+	fields      map[string]string
+}
+
+// printer writes records in the selected output mode
+type printer struct {
+	w     *bufio.Writer
+	color bool
+	raw   bool
+	json  bool
+	files bool
+}
+
+// logFile is a log file with the logger name and creation time taken from its file name
+type logFile struct {
+	path    string
+	name    string
+	created time.Time
+}
+
+func main() {
+	var (
+		level    = flag.String("level", "", "minimum level, e.g. warn")
+		since    = flag.String("since", "", "records since a time (RFC 3339) or a duration ago, e.g. 15m")
+		until    = flag.String("until", "", "records before a time (RFC 3339) or a duration ago")
+		contains = flag.String("grep", "", "records whose line contains the substring")
+		name     = flag.String("name", "", "only files of the named logger in directories")
+		tail     = flag.Int("n", 0, "only the last n matching records, 0 for all")
+		follow   = flag.Bool("f", false, "follow the files and directories for new records")
+		color    = flag.String("color", "auto", "colorize the output: auto, always or never")
+		raw      = flag.Bool("raw", false, "print the records as written")
+		asJSON   = flag.Bool("json", false, "print the records as JSON objects with time, level, msg and fields")
+		files    = flag.Bool("files", false, "prefix the records with their file name")
+		fields   = fieldFlags{}
+	)
+	flag.Var(fields, "field", "records with the field value, key=value, repeatable")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] path...\n\nPaths are log files or directories.\n\nFlags:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f := filter{contains: *contains, fields: fields}
+	var err error
+	if *level != "" {
+		if f.minLevel, err = logger.ParseLevel(*level); err != nil {
+			fatal(err)
+		}
+		f.filterLevel = true
+	}
+	if f.since, err = parseTime(*since); err != nil {
+		fatal(fmt.Errorf("invalid -since: %w", err))
+	}
+	if f.until, err = parseTime(*until); err != nil {
+		fatal(fmt.Errorf("invalid -until: %w", err))
+	}
+
+	p := &printer{w: bufio.NewWriter(os.Stdout), raw: *raw, json: *asJSON, files: *files}
+	switch *color {
+	case "always":
+		p.color = true
+	case "auto":
+		p.color = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	case "never":
+	default:
+		fatal(fmt.Errorf("invalid -color %q: must be auto, always or never", *color))
+	}
+	defer p.w.Flush()
+
+	logs, err := listFiles(flag.Args(), *name)
+	if err != nil {
+		fatal(err)
+	}
+	entries, err := readMerged(logs, f)
+	if err != nil {
+		fatal(err)
+	}
+	if *tail > 0 && len(entries) > *tail {
+		entries = entries[len(entries)-*tail:]
+	}
+	for i := range entries {
+		p.print(&entries[i])
+	}
+	if !*follow {
+		return
+	}
+	p.w.Flush()
+	if err := followFiles(flag.Args(), *name, logs, f, p); err != nil {
+		fatal(err)
+	}
+}
+
+// fatal prints the error and exits
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "lgr:", err)
+	os.Exit(1)
+}
+
+// parseTime parses an RFC 3339 time or a duration before now, the zero time for an empty string
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// isTerminal reports whether the file is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// parseLogFileName splits a file name of the logger into the logger name and the creation time. Names are
+// <name>_<yymmdd>_<hhmmss>_<fraction>, or <name>_<yymmdd>_<hhmmss>_<nanoseconds>_<seq> if every fraction
+// was taken. Journal recovery files, <name>_recovered_..., belong to the logger name.
+func parseLogFileName(fname string) (string, time.Time, bool) {
+	base := strings.TrimSuffix(fname, filepath.Ext(fname))
+	parts := strings.Split(base, "_")
+	n := len(parts)
+	if n >= 5 && len(parts[n-2]) == 9 && isDigits(parts[n-1]) {
+		if created, ok := parseFileTime(parts[n-4], parts[n-3], parts[n-2]); ok {
+			return loggerName(parts[:n-4]), created, true
+		}
+	}
+	if n < 4 {
+		return "", time.Time{}, false
+	}
+	created, ok := parseFileTime(parts[n-3], parts[n-2], parts[n-1])
+	if !ok {
+		return "", time.Time{}, false
+	}
+	return loggerName(parts[:n-3]), created, true
+}
+
+// loggerName joins the name parts of a file name, without the tag of journal recovery files
+func loggerName(parts []string) string {
+	if len(parts) > 1 && parts[len(parts)-1] == "recovered" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, "_")
+}
+
+// parseFileTime parses the date, time and second fraction of a file name
+func parseFileTime(date, clock, fraction string) (time.Time, bool) {
+	if !isDigits(fraction) || len(fraction) > 9 {
+		return time.Time{}, false
+	}
+	created, err := time.ParseInLocation("060102_150405", date+"_"+clock, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	nanos, _ := strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction)))
+	return created.Add(time.Duration(nanos)), true
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// listFiles returns the log files of the paths. Files of directories are those named like log files of
+// the logger, of the named logger if name is set, ordered by their creation time.
+func listFiles(paths []string, name string) ([]logFile, error) {
+	var logs []logFile
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			lf := logFile{path: path, created: fi.ModTime()}
+			if n, created, ok := parseLogFileName(filepath.Base(path)); ok {
+				lf.name, lf.created = n, created
+			}
+			logs = append(logs, lf)
+			continue
+		}
+		dirEntries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range dirEntries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			n, created, ok := parseLogFileName(entry.Name())
+			if !ok || name != "" && n != name {
+				continue
+			}
+			logs = append(logs, logFile{path: filepath.Join(path, entry.Name()), name: n, created: created})
+		}
+	}
+	slices.SortStableFunc(logs, func(a, b logFile) int { return a.created.Compare(b.created) })
+	return slices.CompactFunc(logs, func(a, b logFile) bool { return a.path == b.path }), nil
+}
+
+// readMerged reads the matching records of the files. The files of each logger name are read in order
+// of creation and the records of different loggers are merged by time.
+func readMerged(logs []logFile, f filter) ([]logger.QueryEntry, error) {
+	var names []string
+	groups := make(map[string][]string)
+	for _, lf := range logs {
+		if _, ok := groups[lf.name]; !ok {
+			names = append(names, lf.name)
+		}
+		groups[lf.name] = append(groups[lf.name], lf.path)
+	}
+
+	// Query filters the level, fields and substring, the time range is applied after merging so records
+	// without time stamp keep the position of the preceding record
+	opts := logger.QueryOptions{Contains: f.contains, Fields: f.fields}
+	if f.filterLevel {
+		opts.MinLevel = logger.LevelString(f.minLevel)
+	}
+	streams := make(mergeHeap, 0, len(names))
+	for i, n := range names {
+		opts.Files = groups[n]
+		entries, err := logger.Query(opts)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			streams = append(streams, &stream{entries: entries, order: i})
+		}
+	}
+
+	heap.Init(&streams)
+	var merged []logger.QueryEntry
+	for len(streams) > 0 {
+		s := streams[0]
+		e := s.entries[0]
+		if !e.Time.IsZero() {
+			s.last = e.Time
+		}
+		if f.inRange(s.last) {
+			merged = append(merged, e)
+		}
+		if s.entries = s.entries[1:]; len(s.entries) == 0 {
+			heap.Pop(&streams)
+		} else {
+			heap.Fix(&streams, 0)
+		}
+	}
+	return merged, nil
+}
+
+// stream is the remaining records of one logger
+type stream struct {
+	entries []logger.QueryEntry
+	last    time.Time // time of the last record taken that had one
+	order   int
+}
+
+// next returns the time the next record is ordered by
+func (s *stream) next() time.Time {
+	if t := s.entries[0].Time; !t.IsZero() {
+		return t
+	}
+	return s.last
+}
+
+// mergeHeap orders the streams by the time of their next record
+type mergeHeap []*stream
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if c := h[i].next().Compare(h[j].next()); c != 0 {
+		return c < 0
+	}
+	return h[i].order < h[j].order
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(*stream)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
+
+// inRange reports whether a record time is within the time range, records without time always are
+func (f *filter) inRange(t time.Time) bool {
+	if t.IsZero() {
+		return true
+	}
+	return (f.since.IsZero() || !t.Before(f.since)) && (f.until.IsZero() || t.Before(f.until))
+}
+
+// match reports whether a parsed record passes the filter
+func (f *filter) match(e *logger.QueryEntry) bool {
+	if f.contains != "" && !strings.Contains(e.Line, f.contains) ||
+		f.filterLevel && (!e.HasLevel || e.Level < f.minLevel) ||
+		!f.inRange(e.Time) {
+		return false
+	}
+	for key, want := range f.fields {
+		v, ok := e.Fields[key]
+		if !ok {
+			return false
+		}
+		if s, isString := v.(string); isString && s != want || !isString && fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// tracked is a followed file and the offset up to which it was read
+type tracked struct {
+	path    string
+	offset  int64
+	partial []byte // line written without its newline yet
+	packed  bool   // msgpack format file, followed by record count instead of offset
+	records int    // records of a msgpack file printed or skipped
+}
+
+// followFiles polls the files and directories for records written after the initial read, picking up files
+// created by rotation, and prints the matching ones until interrupted
+func followFiles(paths []string, name string, logs []logFile, f filter, p *printer) error {
+	files := make(map[string]*tracked, len(logs))
+	for _, lf := range logs {
+		t := &tracked{path: lf.path, packed: isPacked(lf.path)}
+		if fi, err := os.Stat(lf.path); err == nil {
+			t.offset = fi.Size()
+		}
+		if t.packed {
+			lines, _ := packedLines(t.path)
+			t.records = len(lines)
+		}
+		files[lf.path] = t
+	}
+
+	for {
+		time.Sleep(followInterval)
+		logs, err := listFiles(paths, name)
+		if err != nil {
+			return err
+		}
+		listed := make(map[string]*tracked, len(logs))
+		for _, lf := range logs {
+			t, ok := files[lf.path]
+			if !ok {
+				t = &tracked{path: lf.path}
+			}
+			listed[lf.path] = t
+			if err := t.read(f, p); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		// Files deleted by cleanup or retention are no longer followed
+		files = listed
+		p.w.Flush()
+	}
+}
+
+// read prints the matching records appended to the file since the last read. A file that shrank was
+// truncated and is read again from the start.
+func (t *tracked) read(f filter, p *printer) error {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return err
+	}
+	if fi.Size() < t.offset {
+		t.offset, t.partial = 0, nil
+	}
+	if fi.Size() == t.offset {
+		return nil
+	}
+	if t.offset == 0 {
+		t.packed = isPacked(t.path)
+	}
+	if t.packed {
+		return t.readPacked(f, p, fi.Size())
+	}
+
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.NewSectionReader(file, t.offset, fi.Size()-t.offset))
+	if err != nil {
+		return err
+	}
+	t.offset += int64(len(data))
+
+	data = append(t.partial, data...)
+	last := strings.LastIndexByte(string(data), '\n')
+	t.partial = slices.Clone(data[last+1:])
+	for _, line := range strings.Split(string(data[:last+1]), "\n") {
+		e, ok := logger.ParseLine(strings.TrimRight(line, "\r"))
+		if !ok || !f.match(&e) {
+			continue
+		}
+		e.File = t.path
+		p.print(&e)
+	}
+	return nil
+}
+
+// readPacked prints the matching records added to a msgpack file since the last read. The file is converted
+// from the start, as records carry no line breaks to resume at, and the records seen before are skipped.
+func (t *tracked) readPacked(f filter, p *printer, size int64) error {
+	lines, err := packedLines(t.path)
+	if err != nil {
+		return err
+	}
+	if len(lines) < t.records {
+		t.records = 0
+	}
+	for _, line := range lines[t.records:] {
+		e, ok := logger.ParseLine(line)
+		if !ok || !f.match(&e) {
+			continue
+		}
+		e.File = t.path
+		p.print(&e)
+	}
+	t.records, t.offset = len(lines), size
+	return nil
+}
+
+// isPacked reports whether a file starts with a MessagePack map, as files of the msgpack format do
+func isPacked(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	var b [1]byte
+	if _, err := file.Read(b[:]); err != nil {
+		return false
+	}
+	return b[0]&0xf0 == 0x80 || b[0] == 0xde || b[0] == 0xdf
+}
+
+// packedLines returns the complete records of a msgpack file as json lines
+func packedLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out, _ := logger.MsgpackToJSON(data)
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\n"), "\n"), nil
+}
+
+// print writes a record in the output mode of the printer
+func (p *printer) print(e *logger.QueryEntry) {
+	if p.files {
+		p.paint(colorDim, filepath.Base(e.File))
+		p.w.WriteByte(' ')
+	}
+	switch {
+	case p.raw:
+		p.w.WriteString(e.Line)
+	case p.json:
+		out := map[string]any{"msg": e.Message}
+		if !e.Time.IsZero() {
+			out["time"] = e.Time
+		}
+		if e.HasLevel {
+			out["level"] = logger.LevelString(e.Level)
+		}
+		if len(e.Fields) > 0 {
+			out["fields"] = e.Fields
+		}
+		data, _ := json.Marshal(out)
+		p.w.Write(data)
+	default:
+		p.pretty(e)
+	}
+	p.w.WriteByte('\n')
+}
+
+// pretty writes the local time, level, message and sorted fields of a record
+func (p *printer) pretty(e *logger.QueryEntry) {
+	if !e.Time.IsZero() {
+		p.paint(colorDim, e.Time.Local().Format("2006-01-02 15:04:05.000"))
+		p.w.WriteByte(' ')
+	}
+	if e.HasLevel {
+		p.paint(levelColor(e.Level), fmt.Sprintf("%-5s", logger.LevelString(e.Level)))
+		p.w.WriteByte(' ')
+	}
+	p.w.WriteString(e.Message)
+	keys := slices.Sorted(func(yield func(string) bool) {
+		for k := range e.Fields {
+			if !yield(k) {
+				return
+			}
+		}
+	})
+	for _, k := range keys {
+		p.w.WriteByte(' ')
+		p.paint(colorKey, k+"=")
+		p.w.WriteString(formatValue(e.Fields[k]))
+	}
+}
+
+// paint writes s in the color if the output is colorized
+func (p *printer) paint(color, s string) {
+	if !p.color || color == "" {
+		p.w.WriteString(s)
+		return
+	}
+	p.w.WriteString(color)
+	p.w.WriteString(s)
+	p.w.WriteString(colorReset)
+}
+
+// levelColor returns the color of a level, none below debug
+func levelColor(level int64) string {
+	for _, lc := range levelColors {
+		if level >= lc.level {
+			return lc.color
+		}
+	}
+	return ""
+}
+
+// formatValue formats a field value, quoting strings with spaces or quotes and encoding JSON values
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		if x == "" || strings.ContainsAny(x, " \"=\n\t") {
+			return strconv.Quote(x)
+		}
+		return x
+	case map[string]any, []any:
+		data, _ := json.Marshal(x)
+		return string(data)
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Write coalescing limits: the processor takes up to writeBatchRecords queued records per wakeup and
+// writes their lines to the active file with a single write, earlier once writeBatchBytes are pending.
+const (
+	writeBatchRecords = 256
+	writeBatchBytes   = 64 * 1024
+)
+
+// Lines serialized for the active file and not written yet, owned by the processor goroutine,
+// or by the caller holding mu while the processor is stopped
+var (
+	pendingWrite   []byte
+	pendingRecords uint64
+	pendingChain   auditState // audit chain before the first pending line
+)
+
+// queueWrite appends a serialized line to the pending write, writing it once writeBatchBytes are pending
+func queueWrite(data []byte) {
+	pendingWrite = append(pendingWrite, data...)
+	pendingRecords++
+	if len(pendingWrite) >= writeBatchBytes {
+		flushWrites()
+	}
+}
+
+// flushWrites writes the pending lines to the active file and commits their journal frames. A failed write
+// drops all of them.
+// It must be called before the active file is synced, rotated or closed.
+func flushWrites() {
+	defer commitJournal()
+	if pendingRecords == 0 {
+		return
+	}
+	data, n := pendingWrite, pendingRecords
+	pendingWrite, pendingRecords = pendingWrite[:0], 0
+
+	f := currentFile.Load().(*os.File)
+	if f == nil {
+		recordDrop(n, causeFileUnavailable)
+		return
+	}
+	if _, err := f.Write(data); err != nil {
+		if err = recoverWrite(data, n, err); err != nil {
+			recordDrop(n, causeWriteError)
+			reportError(fmt.Errorf("failed to write %d records: %w", n, err))
+			emitDiag(DiagEvent{Kind: DiagWriteError, File: filepath.Base(f.Name()), Count: n, Err: err})
+			return
+		}
+	}
+	bytesWritten.Add(uint64(len(data)))
+	recordsWritten.Add(n)
+	fileRecords += n
+	currentSize.Add(int64(len(data)))
+
+	// Release a buffer grown by a burst of large records
+	if cap(pendingWrite) > 4*writeBatchBytes {
+		pendingWrite = nil
+	}
+}
+
+// syncFile writes the pending lines and syncs the active file, if any
+func syncFile() error {
+	flushWrites()
+	if f := currentFile.Load().(*os.File); f != nil {
+		return f.Sync()
+	}
+	return nil
+}
+
+// reconcileSize corrects the tracked size of the active file from the file system, e.g. after an external
+// tool truncated it. A file without records keeps size 0, so it is still never rotated.
+// It must only be called from the processor goroutine, after flushWrites.
+func reconcileSize(f *os.File) {
+	if currentSize.Load() == 0 {
+		return
+	}
+	if fi, err := f.Stat(); err == nil {
+		currentSize.Store(fi.Size())
+	}
+}
+
+// processAvailable processes up to writeBatchRecords-1 further records already queued without waiting,
+// so their lines are written together. It stops early once the queue is empty or closed.
+// It must only be called from the processor goroutine.
+func processAvailable(s *serializer, records chan logRecord) {
+	for n := 1; n < writeBatchRecords; n++ {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				return
+			}
+			processRecord(s, &record, records)
+		default:
+			return
+		}
+	}
+}
+
+// processRing processes up to writeBatchRecords records published to the ring, so their lines are written
+// together. If records are left, the processor is woken again after handling its other events.
+// It must only be called from the processor goroutine.
+func processRing(s *serializer, ring *recordRing, records chan logRecord) {
+	for n := 0; n < writeBatchRecords; n++ {
+		record, ok := ring.pop()
+		if !ok {
+			return
+		}
+		processRecord(s, &record, records)
+	}
+	select {
+	case ring.wake <- struct{}{}:
+	default:
+	}
+}
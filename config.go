@@ -2,9 +2,14 @@ package logger
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
-	"strings"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -24,27 +29,86 @@ var (
 
 // LoggerConfig defines the logger configuration parameters.
 // All fields can be configured via JSON or TOML configuration files.
+//
+// Zero values leave the default, or the running value on reconfiguration, unchanged. Fields listed in Set
+// by their config key are applied even when zero, e.g. to disable rotation with MaxSizeMB 0.
+// Decoding from JSON lists every key present in the document.
 type LoggerConfig struct {
-	Level                  int64   `json:"level" toml:"level"`                                       // LevelDebug, LevelInfo, LevelWarn, LevelError
-	Name                   string  `json:"name" toml:"name"`                                         // Base name for log files
-	Directory              string  `json:"directory" toml:"directory"`                               // Directory to store log files
-	Format                 string  `json:"format" toml:"format"`                                     // Serialized output file type: txt, json
-	Extension              string  `json:"extension" toml:"extension"`                               // Log file extension (default "log", empty = use format)
-	ShowTimestamp          bool    `json:"show_timestamp" toml:"show_timestamp"`                     // Enable time stamp (default enabled)
-	ShowLevel              bool    `json:"show_level" toml:"show_level"`                             // Enable level (default enabled)
-	BufferSize             int64   `json:"buffer_size" toml:"buffer_size"`                           // Channel buffer size
-	MaxSizeMB              int64   `json:"max_size_mb" toml:"max_size_mb"`                           // Max size of each log file in MB
-	MaxTotalSizeMB         int64   `json:"max_total_size_mb" toml:"max_total_size_mb"`               // Max total size of the log folder in MB to trigger old log deletion/pause logging
-	MinDiskFreeMB          int64   `json:"min_disk_free_mb" toml:"min_disk_free_mb"`                 // Min available free space in MB to trigger old log deletion/pause logging
-	FlushTimer             int64   `json:"flush_timer" toml:"flush_timer"`                           // Periodically forces writing logs to the disk to avoid missing logs on program shutdown
-	TraceDepth             int64   `json:"trace_depth" toml:"trace_depth"`                           // 0-10, 0 disables tracing
-	RetentionPeriod        float64 `json:"retention_period" toml:"retention_period"`                 // RetentionPeriod defines how long to keep log files in hours. Zero disables retention.
-	RetentionCheckInterval float64 `json:"retention_check_interval" toml:"retention_check_interval"` // RetentionCheckInterval defines how often to check for expired logs in minutes if retention is enabled.
+	Level                  int64             `json:"level" toml:"level"`                                       // LevelDebug, LevelInfo, LevelWarn, LevelError
+	Name                   string            `json:"name" toml:"name"`                                         // Base name for log files
+	Directory              string            `json:"directory" toml:"directory"`                               // Directory to store log files, "~" and environment variables are expanded
+	Format                 string            `json:"format" toml:"format"`                                     // Serialized output file type: txt, json, ecs, console, msgpack
+	JSONIndent             bool              `json:"json_indent" toml:"json_indent"`                           // Indent json and ecs records over multiple lines, for development only
+	MaxRecordBytes         int64             `json:"max_record_bytes" toml:"max_record_bytes"`                 // Records above the size are truncated, 0 disables the limit
+	FoldLines              bool              `json:"fold_lines" toml:"fold_lines"`                             // Write newlines of txt and console records as indented continuation lines
+	Extension              string            `json:"extension" toml:"extension"`                               // Log file extension (default "log", empty = use format)
+	TimestampFormat        string            `json:"timestamp_format" toml:"timestamp_format"`                 // rfc3339nano, rfc3339, unix, unix_ms, unix_us, unix_ns or a Go time layout
+	TimeZone               string            `json:"time_zone" toml:"time_zone"`                               // Time zone of timestamps, e.g. UTC, Local or Europe/Berlin, empty keeps local time
+	DurationNanos          bool              `json:"duration_nanos" toml:"duration_nanos"`                     // Write time.Duration values as integer nanoseconds
+	ShowTimestamp          bool              `json:"show_timestamp" toml:"show_timestamp"`                     // Enable time stamp (default enabled)
+	ShowLevel              bool              `json:"show_level" toml:"show_level"`                             // Enable level (default enabled)
+	ShowSequence           bool              `json:"show_sequence" toml:"show_sequence"`                       // Number records in the order of the logging calls
+	ShowDeadline           bool              `json:"show_deadline" toml:"show_deadline"`                       // Add remaining time until the context deadline to records with a deadline
+	BufferSize             int64             `json:"buffer_size" toml:"buffer_size"`                           // Channel buffer size
+	MaxSizeMB              int64             `json:"max_size_mb" toml:"max_size_mb"`                           // Max size of each log file in MB
+	MaxTotalSizeMB         int64             `json:"max_total_size_mb" toml:"max_total_size_mb"`               // Max total size of the log folder in MB to trigger old log deletion/pause logging
+	MinDiskFreeMB          int64             `json:"min_disk_free_mb" toml:"min_disk_free_mb"`                 // Min available free space in MB to trigger old log deletion/pause logging
+	MaxSizeBytes           int64             `json:"max_size_bytes" toml:"max_size_bytes"`                     // Max size of each log file in bytes, overrides MaxSizeMB if positive
+	MaxTotalSizeBytes      int64             `json:"max_total_size_bytes" toml:"max_total_size_bytes"`         // Max total size of the log folder in bytes, overrides MaxTotalSizeMB if positive
+	MinDiskFreeBytes       int64             `json:"min_disk_free_bytes" toml:"min_disk_free_bytes"`           // Min available free space in bytes, overrides MinDiskFreeMB if positive
+	FlushTimer             int64             `json:"flush_timer" toml:"flush_timer"`                           // Periodically forces writing logs to the disk to avoid missing logs on program shutdown
+	TraceDepth             int64             `json:"trace_depth" toml:"trace_depth"`                           // 0-10, 0 disables tracing
+	StructuredTrace        bool              `json:"structured_trace" toml:"structured_trace"`                 // Write the trace as an array of frames in json and ecs
+	TraceWithLines         bool              `json:"trace_with_lines" toml:"trace_with_lines"`                 // Add file:line to every function of the trace
+	CallerSkip             int64             `json:"caller_skip" toml:"caller_skip"`                           // Frames of wrapper packages skipped to reach the caller
+	RetentionPeriod        float64           `json:"retention_period" toml:"retention_period"`                 // RetentionPeriod defines how long to keep log files in hours. Zero disables retention.
+	RetentionCheckInterval float64           `json:"retention_check_interval" toml:"retention_check_interval"` // RetentionCheckInterval defines how often to check for expired logs in minutes if retention is enabled.
+	CleanupScope           string            `json:"cleanup_scope" toml:"cleanup_scope"`                       // Files subject to size limits and retention: name (files of this Name) or directory
+	TransientRetention     float64           `json:"transient_retention" toml:"transient_retention"`           // Minutes to keep records logged with ContextWithTransient in their own files, 0 writes them to the log file
+	RetentionTiers         []string          `json:"retention_tiers" toml:"retention_tiers"`                   // Tiers as <hours>:<level>, files older than the hours keep only records of the level and above (e.g. "24:warn")
+	RetentionExclude       []string          `json:"retention_exclude" toml:"retention_exclude"`               // Filename glob patterns never deleted by retention or disk cleanup (e.g. "*_audit_*.log")
+	StatsFile              bool              `json:"stats_file" toml:"stats_file"`                             // Maintain lifetime counters in <name>.stats in the log directory
+	EncryptionKey          string            `json:"encryption_key" toml:"encryption_key"`                     // Hex encoded AES-128/192/256 key, encrypts every record written to disk
+	Pipeline               []string          `json:"pipeline" toml:"pipeline"`                                 // Stages applied to records before writing, in order: compress, checksum, encrypt or registered names
+	LazyOpen               bool              `json:"lazy_open" toml:"lazy_open"`                               // Succeed Init if the directory is not writable yet, create the file at first write
+	AuditChain             bool              `json:"audit_chain" toml:"audit_chain"`                           // Chain records with SHA-256 hashes and write a final hash footer per file
+	FileHeaders            bool              `json:"file_headers" toml:"file_headers"`                         // Open every log file with a header record and close it with a footer record
+	RecordChecksum         bool              `json:"record_checksum" toml:"record_checksum"`                   // Append a CRC-32C checksum to every record, detecting torn writes
+	MinRotateInterval      int64             `json:"min_rotate_interval" toml:"min_rotate_interval"`           // Minimum time in milliseconds between size based rotations, 0 disables
+	DiskCheckInterval      int64             `json:"disk_check_interval" toml:"disk_check_interval"`           // Time in milliseconds the result of a disk space scan is reused, 0 scans on every record
+	Console                string            `json:"console" toml:"console"`                                   // Also write records to the console: stdout, stderr, empty disables
+	FileMinLevel           int64             `json:"file_min_level" toml:"file_min_level"`                     // Minimum level written to the log file, in addition to Level
+	ConsoleMinLevel        int64             `json:"console_min_level" toml:"console_min_level"`               // Minimum level written to the console, in addition to Level
+	SyncPolicy             string            `json:"sync_policy" toml:"sync_policy"`                           // When records are synced to disk: interval, every_write, every_error
+	QueueType              string            `json:"queue_type" toml:"queue_type"`                             // Buffer between logging calls and the processor: channel, ring
+	OverflowPolicy         string            `json:"overflow_policy" toml:"overflow_policy"`                   // Behavior when the buffer is full: drop, block, block_with_timeout
+	ShedThreshold          float64           `json:"shed_threshold" toml:"shed_threshold"`                     // Buffer occupancy from 0 to 1 from which records below ShedLevel are dropped, 0 disables
+	ShedLevel              int64             `json:"shed_level" toml:"shed_level"`                             // Records below the level are dropped first with ShedThreshold
+	OverflowTimeout        int64             `json:"overflow_timeout" toml:"overflow_timeout"`                 // Maximum time in milliseconds to block with block_with_timeout
+	SpillMaxMB             int64             `json:"spill_max_mb" toml:"spill_max_mb"`                         // Max size in MB of the on-disk queue for records overflowing the buffer, 0 drops them
+	JournalSizeKB          int64             `json:"journal_size_kb" toml:"journal_size_kb"`                   // Size in KB of the memory-mapped journal recovering queued records after a crash, 0 disables it
+	AdminAddress           string            `json:"admin_address" toml:"admin_address"`                       // Admin listener, "host:port" or "unix:/path/to.sock", empty disables
+	AdminToken             string            `json:"admin_token" toml:"admin_token"`                           // Bearer token required by the admin listener, mandatory for TCP
+	RecentSize             int64             `json:"recent_size" toml:"recent_size"`                           // Latest records kept in memory for Recent and ServeHTTP, 0 disables
+	IncludeHost            bool              `json:"include_host" toml:"include_host"`                         // Write the host name to every record
+	IncludePID             bool              `json:"include_pid" toml:"include_pid"`                           // Write the process id to every record
+	IncludeSchema          bool              `json:"include_schema" toml:"include_schema"`                     // Write schema_version and logger_version to every record
+	IncludeGoroutine       bool              `json:"include_goroutine" toml:"include_goroutine"`               // Write the id of the logging goroutine to every record
+	StaticFields           map[string]string `json:"static_fields" toml:"static_fields"`                       // Fields written to every record, e.g. service and env
+	StructuredErrors       bool              `json:"structured_errors" toml:"structured_errors"`               // Write error values in json formats as objects with message, type, causes and stack
+	DedupWindow            int64             `json:"dedup_window" toml:"dedup_window"`                         // Window in milliseconds identical level and message records are collapsed in, 0 disables
+	HeartbeatInterval      int64             `json:"heartbeat_interval" toml:"heartbeat_interval"`             // Interval in milliseconds of heartbeat records with the running counters, 0 disables
+	Signals                bool              `json:"signals" toml:"signals"`                                   // Handle SIGHUP (reopen), SIGUSR1 (debug level for SignalDebugDuration) and SIGUSR2 (log stats)
+	SignalDebugDuration    int64             `json:"signal_debug_duration" toml:"signal_debug_duration"`       // Time in milliseconds SIGUSR1 raises the level to debug
+
+	Set []string `json:"-" toml:"-"` // Config keys of fields applied even when zero
 }
 
-// configLogger initializes the logger with the provided configuration.
+// configLogger initializes the logger with the provided options applied on top of the defaults or the running config.
 // It validates the configuration and sets up the logging infrastructure including file management and buffering.
-func configLogger(ctx context.Context, cfg ...*LoggerConfig) error {
+func configLogger(ctx context.Context, opts ...Option) (err error) {
+	defer func() { setInitError(err) }()
+
 	// defaultConfig values are used if value is not provided by the user
 	defaultConfig := &LoggerConfig{
 		Level:                  LevelInfo,
@@ -58,70 +122,186 @@ func configLogger(ctx context.Context, cfg ...*LoggerConfig) error {
 		MaxSizeMB:              10,
 		MaxTotalSizeMB:         50,
 		MinDiskFreeMB:          100,
+		DiskCheckInterval:      1000,
 		FlushTimer:             100,
 		TraceDepth:             0,
 		RetentionPeriod:        0.0,
 		RetentionCheckInterval: 60.0,
+		FileMinLevel:           LevelDebug,
+		ConsoleMinLevel:        LevelDebug,
+		SyncPolicy:             SyncInterval,
+		QueueType:              QueueChannel,
+		OverflowPolicy:         OverflowDrop,
+		ShedLevel:              LevelWarn,
+		OverflowTimeout:        100,
+		SignalDebugDuration:    300000,
+		CleanupScope:           CleanupScopeName,
 	}
 
-	if len(cfg) == 0 {
+	if len(opts) == 0 {
 		return initLogger(ctx, defaultConfig)
 	}
+	if err := validateSet(opts); err != nil {
+		return invalidConfig(err)
+	}
 
-	userConfig := cfg[0]
-	var mergedCfg *LoggerConfig
-
+	baseCfg := defaultConfig
 	if isInitialized.Load() {
-		// Merge with current running config
-		currentCfg := &LoggerConfig{
-			Level:                  logLevel.Load().(int64),
+		// Options apply on top of the running config
+		baseCfg = &LoggerConfig{
+			Level:                  configuredLevel(),
 			Name:                   name,
 			Directory:              directory,
 			Format:                 format,
+			JSONIndent:             jsonIndent,
+			MaxRecordBytes:         int64(maxRecordBytes),
+			FoldLines:              foldLines,
 			Extension:              extension,
+			TimestampFormat:        timestampFormat,
+			TimeZone:               timeZone,
+			DurationNanos:          durationNanos,
 			ShowTimestamp:          flags&FlagShowTimestamp != 0,
 			ShowLevel:              flags&FlagShowLevel != 0,
+			ShowSequence:           flags&FlagShowSequence != 0,
+			ShowDeadline:           flags&FlagShowDeadline != 0,
 			BufferSize:             bufferSize.Load(),
 			MaxSizeMB:              maxSizeMB,
 			MaxTotalSizeMB:         maxTotalSizeMB,
 			MinDiskFreeMB:          minDiskFreeMB,
+			MaxSizeBytes:           maxSizeBytes,
+			MaxTotalSizeBytes:      maxTotalSizeBytes,
+			MinDiskFreeBytes:       minDiskFreeBytes,
 			FlushTimer:             int64(flushTimer / time.Millisecond),
 			TraceDepth:             traceDepth,
-			RetentionPeriod:        float64(retentionPeriod / time.Hour),
-			RetentionCheckInterval: float64(retentionCheck / time.Minute),
+			StructuredTrace:        structuredTrace,
+			TraceWithLines:         traceWithLines,
+			CallerSkip:             int64(callerSkip),
+			RetentionPeriod:        retentionPeriod.Hours(),
+			RetentionCheckInterval: retentionCheck.Minutes(),
+			CleanupScope:           cleanupScope,
+			TransientRetention:     transientRetention.Minutes(),
+			RetentionTiers:         tierSpecs,
+			RetentionExclude:       retentionExclude,
+			StatsFile:              statsEnabled,
+			EncryptionKey:          encryptionKey,
+			Pipeline:               pipelineNames,
+			LazyOpen:               lazyOpen,
+			AuditChain:             auditChain,
+			FileHeaders:            fileHeaders,
+			RecordChecksum:         recordChecksum,
+			MinRotateInterval:      int64(minRotateInterval / time.Millisecond),
+			DiskCheckInterval:      int64(diskCheckInterval / time.Millisecond),
+			Console:                console,
+			FileMinLevel:           fileMinLevel,
+			ConsoleMinLevel:        consoleMinLevel,
+			SyncPolicy:             syncPolicy,
+			QueueType:              queueType,
+			OverflowPolicy:         overflowPolicy.Load().(string),
+			ShedThreshold:          math.Float64frombits(shedThreshold.Load()),
+			ShedLevel:              shedLevel.Load(),
+			OverflowTimeout:        time.Duration(overflowTimeout.Load()).Milliseconds(),
+			SpillMaxMB:             spillMaxMB,
+			JournalSizeKB:          journalSizeKB,
+			AdminAddress:           adminAddress,
+			AdminToken:             adminToken,
+			RecentSize:             recentSize.Load(),
+			IncludeHost:            includeHost,
+			IncludePID:             includePID,
+			IncludeSchema:          includeSchema,
+			IncludeGoroutine:       includeGoroutine,
+			StaticFields:           staticFields,
+			StructuredErrors:       structuredErrors,
+			DedupWindow:            dedupWindow.Milliseconds(),
+			HeartbeatInterval:      heartbeatInterval.Milliseconds(),
+			Signals:                signalsEnabled,
+			SignalDebugDuration:    signalDebugDuration.Milliseconds(),
 		}
-		mergedCfg = mergeConfigs(currentCfg, userConfig)
-	} else {
-		mergedCfg = mergeConfigs(defaultConfig, userConfig)
 	}
 
-	return initLogger(ctx, mergedCfg)
+	for _, opt := range opts {
+		if opt != nil {
+			opt.apply(baseCfg)
+		}
+	}
+
+	return initLogger(ctx, baseCfg)
 }
 
-// mergeConfigs overrides base values for non-zero values in override
+// mergeConfigs overrides base values for non-zero or explicitly set values in override
 func mergeConfigs(base, override *LoggerConfig) *LoggerConfig {
 	return &LoggerConfig{
-		Level:                  getConfigValue(base.Level, override.Level),
-		Name:                   getConfigValue(base.Name, override.Name),
-		Directory:              getConfigValue(base.Directory, override.Directory),
-		Format:                 getConfigValue(base.Format, override.Format),
-		Extension:              getConfigValue(base.Extension, override.Extension),
-		ShowTimestamp:          getConfigValue(base.ShowTimestamp, override.ShowTimestamp),
-		ShowLevel:              getConfigValue(base.ShowLevel, override.ShowLevel),
-		BufferSize:             getConfigValue(base.BufferSize, override.BufferSize),
-		MaxSizeMB:              getConfigValue(base.MaxSizeMB, override.MaxSizeMB),
-		MaxTotalSizeMB:         getConfigValue(base.MaxTotalSizeMB, override.MaxTotalSizeMB),
-		MinDiskFreeMB:          getConfigValue(base.MinDiskFreeMB, override.MinDiskFreeMB),
-		FlushTimer:             getConfigValue(base.FlushTimer, override.FlushTimer),
-		TraceDepth:             getConfigValue(base.TraceDepth, override.TraceDepth),
-		RetentionPeriod:        getConfigValue(base.RetentionPeriod, override.RetentionPeriod),
-		RetentionCheckInterval: getConfigValue(base.RetentionCheckInterval, override.RetentionCheckInterval),
+		Level:                  getConfigValue(base.Level, override.Level, override.isSet("level")),
+		Name:                   getConfigValue(base.Name, override.Name, override.isSet("name")),
+		Directory:              getConfigValue(base.Directory, override.Directory, override.isSet("directory")),
+		Format:                 getConfigValue(base.Format, override.Format, override.isSet("format")),
+		JSONIndent:             getConfigValue(base.JSONIndent, override.JSONIndent, override.isSet("json_indent")),
+		MaxRecordBytes:         getConfigValue(base.MaxRecordBytes, override.MaxRecordBytes, override.isSet("max_record_bytes")),
+		FoldLines:              getConfigValue(base.FoldLines, override.FoldLines, override.isSet("fold_lines")),
+		Extension:              getConfigValue(base.Extension, override.Extension, override.isSet("extension")),
+		TimestampFormat:        getConfigValue(base.TimestampFormat, override.TimestampFormat, override.isSet("timestamp_format")),
+		TimeZone:               getConfigValue(base.TimeZone, override.TimeZone, override.isSet("time_zone")),
+		DurationNanos:          getConfigValue(base.DurationNanos, override.DurationNanos, override.isSet("duration_nanos")),
+		ShowTimestamp:          getConfigValue(base.ShowTimestamp, override.ShowTimestamp, override.isSet("show_timestamp")),
+		ShowLevel:              getConfigValue(base.ShowLevel, override.ShowLevel, override.isSet("show_level")),
+		ShowSequence:           getConfigValue(base.ShowSequence, override.ShowSequence, override.isSet("show_sequence")),
+		ShowDeadline:           getConfigValue(base.ShowDeadline, override.ShowDeadline, override.isSet("show_deadline")),
+		BufferSize:             getConfigValue(base.BufferSize, override.BufferSize, override.isSet("buffer_size")),
+		MaxSizeMB:              getConfigValue(base.MaxSizeMB, override.MaxSizeMB, override.isSet("max_size_mb")),
+		MaxTotalSizeMB:         getConfigValue(base.MaxTotalSizeMB, override.MaxTotalSizeMB, override.isSet("max_total_size_mb")),
+		MinDiskFreeMB:          getConfigValue(base.MinDiskFreeMB, override.MinDiskFreeMB, override.isSet("min_disk_free_mb")),
+		MaxSizeBytes:           getConfigValue(base.MaxSizeBytes, override.MaxSizeBytes, override.isSet("max_size_bytes")),
+		MaxTotalSizeBytes:      getConfigValue(base.MaxTotalSizeBytes, override.MaxTotalSizeBytes, override.isSet("max_total_size_bytes")),
+		MinDiskFreeBytes:       getConfigValue(base.MinDiskFreeBytes, override.MinDiskFreeBytes, override.isSet("min_disk_free_bytes")),
+		FlushTimer:             getConfigValue(base.FlushTimer, override.FlushTimer, override.isSet("flush_timer")),
+		TraceDepth:             getConfigValue(base.TraceDepth, override.TraceDepth, override.isSet("trace_depth")),
+		StructuredTrace:        getConfigValue(base.StructuredTrace, override.StructuredTrace, override.isSet("structured_trace")),
+		TraceWithLines:         getConfigValue(base.TraceWithLines, override.TraceWithLines, override.isSet("trace_with_lines")),
+		CallerSkip:             getConfigValue(base.CallerSkip, override.CallerSkip, override.isSet("caller_skip")),
+		RetentionPeriod:        getConfigValue(base.RetentionPeriod, override.RetentionPeriod, override.isSet("retention_period")),
+		RetentionCheckInterval: getConfigValue(base.RetentionCheckInterval, override.RetentionCheckInterval, override.isSet("retention_check_interval")),
+		CleanupScope:           getConfigValue(base.CleanupScope, override.CleanupScope, override.isSet("cleanup_scope")),
+		TransientRetention:     getConfigValue(base.TransientRetention, override.TransientRetention, override.isSet("transient_retention")),
+		RetentionTiers:         getConfigSlice(base.RetentionTiers, override.RetentionTiers, override.isSet("retention_tiers")),
+		RetentionExclude:       getConfigSlice(base.RetentionExclude, override.RetentionExclude, override.isSet("retention_exclude")),
+		StatsFile:              getConfigValue(base.StatsFile, override.StatsFile, override.isSet("stats_file")),
+		EncryptionKey:          getConfigValue(base.EncryptionKey, override.EncryptionKey, override.isSet("encryption_key")),
+		Pipeline:               getConfigSlice(base.Pipeline, override.Pipeline, override.isSet("pipeline")),
+		LazyOpen:               getConfigValue(base.LazyOpen, override.LazyOpen, override.isSet("lazy_open")),
+		AuditChain:             getConfigValue(base.AuditChain, override.AuditChain, override.isSet("audit_chain")),
+		FileHeaders:            getConfigValue(base.FileHeaders, override.FileHeaders, override.isSet("file_headers")),
+		RecordChecksum:         getConfigValue(base.RecordChecksum, override.RecordChecksum, override.isSet("record_checksum")),
+		MinRotateInterval:      getConfigValue(base.MinRotateInterval, override.MinRotateInterval, override.isSet("min_rotate_interval")),
+		DiskCheckInterval:      getConfigValue(base.DiskCheckInterval, override.DiskCheckInterval, override.isSet("disk_check_interval")),
+		Console:                getConfigValue(base.Console, override.Console, override.isSet("console")),
+		FileMinLevel:           getConfigValue(base.FileMinLevel, override.FileMinLevel, override.isSet("file_min_level")),
+		ConsoleMinLevel:        getConfigValue(base.ConsoleMinLevel, override.ConsoleMinLevel, override.isSet("console_min_level")),
+		SyncPolicy:             getConfigValue(base.SyncPolicy, override.SyncPolicy, override.isSet("sync_policy")),
+		QueueType:              getConfigValue(base.QueueType, override.QueueType, override.isSet("queue_type")),
+		OverflowPolicy:         getConfigValue(base.OverflowPolicy, override.OverflowPolicy, override.isSet("overflow_policy")),
+		ShedThreshold:          getConfigValue(base.ShedThreshold, override.ShedThreshold, override.isSet("shed_threshold")),
+		ShedLevel:              getConfigValue(base.ShedLevel, override.ShedLevel, override.isSet("shed_level")),
+		OverflowTimeout:        getConfigValue(base.OverflowTimeout, override.OverflowTimeout, override.isSet("overflow_timeout")),
+		SpillMaxMB:             getConfigValue(base.SpillMaxMB, override.SpillMaxMB, override.isSet("spill_max_mb")),
+		JournalSizeKB:          getConfigValue(base.JournalSizeKB, override.JournalSizeKB, override.isSet("journal_size_kb")),
+		AdminAddress:           getConfigValue(base.AdminAddress, override.AdminAddress, override.isSet("admin_address")),
+		AdminToken:             getConfigValue(base.AdminToken, override.AdminToken, override.isSet("admin_token")),
+		RecentSize:             getConfigValue(base.RecentSize, override.RecentSize, override.isSet("recent_size")),
+		IncludeHost:            getConfigValue(base.IncludeHost, override.IncludeHost, override.isSet("include_host")),
+		IncludePID:             getConfigValue(base.IncludePID, override.IncludePID, override.isSet("include_pid")),
+		IncludeSchema:          getConfigValue(base.IncludeSchema, override.IncludeSchema, override.isSet("include_schema")),
+		IncludeGoroutine:       getConfigValue(base.IncludeGoroutine, override.IncludeGoroutine, override.isSet("include_goroutine")),
+		StaticFields:           getConfigMap(base.StaticFields, override.StaticFields, override.isSet("static_fields")),
+		StructuredErrors:       getConfigValue(base.StructuredErrors, override.StructuredErrors, override.isSet("structured_errors")),
+		DedupWindow:            getConfigValue(base.DedupWindow, override.DedupWindow, override.isSet("dedup_window")),
+		HeartbeatInterval:      getConfigValue(base.HeartbeatInterval, override.HeartbeatInterval, override.isSet("heartbeat_interval")),
+		Signals:                getConfigValue(base.Signals, override.Signals, override.isSet("signals")),
+		SignalDebugDuration:    getConfigValue(base.SignalDebugDuration, override.SignalDebugDuration, override.isSet("signal_debug_duration")),
 	}
 }
 
 // initLogger configures and starts the logging infrastructure with the provided configuration.
 // It handles initialization of files, channels, and background processing while ensuring thread safety.
-func initLogger(ctx context.Context, cfg *LoggerConfig) error {
+func initLogger(ctx context.Context, cfg *LoggerConfig) (err error) {
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -129,43 +309,233 @@ func initLogger(ctx context.Context, cfg *LoggerConfig) error {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
+		// On reconfiguration, the records queued so far are written and the active file is closed
+		// with the running config, so a file never mixes formats or pipelines
+		reconfig := isInitialized.Load()
+		var previousFile, previousFormat string
+		if err := validateConfig(cfg); err != nil {
+			return invalidConfig(err)
+		}
+		if reconfig {
+			stopProcessor(context.Background())
+			closeTransientFile()
+			previousFile, previousFormat = retireCurrentFile(ctx, cfg.Format)
+			defer func() {
+				// Keep logging with the running config, the file is created at the next write
+				if err != nil {
+					startProcessor(ctx)
+				}
+			}()
+		}
+
 		if err := applyConfig(ctx, cfg); err != nil {
+			return invalidConfig(err)
+		}
+
+		if err := configureAdmin(cfg.AdminAddress, cfg.AdminToken); err != nil {
 			return err
 		}
+		configureSignals(cfg.Signals, time.Duration(cfg.SignalDebugDuration)*time.Millisecond)
 
-		if err := os.MkdirAll(directory, 0755); err != nil {
-			return fmt.Errorf("failed to create log directory: %w", err)
+		// With lazy open, directory and file errors are deferred to the first write
+		dirErr := os.MkdirAll(directory, 0755)
+		if dirErr != nil && !lazyOpen {
+			return fmt.Errorf("failed to create log directory: %w", dirErr)
 		}
 
-		// Handle reconfiguration
-		if isInitialized.Load() {
-			if processCancel != nil {
-				processCancel()
-			}
+		if err := initStats(cfg.StatsFile); err != nil && !lazyOpen {
+			return fmt.Errorf("failed to write stats file: %w", err)
+		}
 
-			if bufferSize.Load() != cfg.BufferSize {
-				close(logChannel)
-			}
+		if err := openSpill(cfg.SpillMaxMB); err != nil && !lazyOpen {
+			return err
+		}
+		recoveryFile, recovered, err := openJournal(cfg.JournalSizeKB, reconfig)
+		if err != nil && !lazyOpen {
+			return err
 		}
 
 		// Initialize new log file and logger instance
-		logFile, err := createNewLogFile(ctx)
-		if err != nil {
+		if !reconfig {
+			startedAt = clockNow()
+			startRun()
+		}
+		var logFile *os.File
+		if dirErr == nil {
+			logFile, err = createNewLogFile(ctx)
+		}
+		if err != nil && !lazyOpen {
 			return fmt.Errorf("failed to create initial log file: %w", err)
 		}
+		err = nil
+		nextOpenAttempt, openRetryDelay = time.Time{}, 0
 
-		currentFile.Store(logFile)
-		logChannel = make(chan logRecord, bufferSize.Load())
+		startFile(logFile, previousFile)
+		if logFile != nil && previousFormat != "" {
+			writeMigrationMarker(previousFormat, format, previousFile)
+		}
+		if logFile != nil && recoveryFile != "" {
+			writeRecoveryMarker(recoveryFile, recovered)
+		}
 
-		processCtx, processCancel = context.WithCancel(ctx)
-		go processLogs()
+		// The queue is kept unless its type or size changes, records queued meanwhile move to the new one
+		if !reconfig || queueChanged() {
+			previous, previousRing := logChannel, logRing
+			newQueue()
+			if reconfig {
+				moveQueued(previous, previousRing)
+			}
+		}
+		startProcessor(ctx)
 
+		// Successful initialization re-enables a logger disabled by shutdown or a failed auto-initialization
+		initFailedAt.Store(0)
+		loggerDisabled.Store(false)
 		isInitialized.Store(true)
+
+		// Hand records logged before the first Init to the processor
+		if !preInitDone.Load() {
+			if early := takePreInit(); len(early) > 0 {
+				if !enqueue(logRecord{Batch: early}) {
+					recordDrop(uint64(len(early)), causeBufferFull)
+				}
+			}
+		}
 		return nil
 	}
 }
 
-// applyConfig sets the running config
+// validateConfig checks a config before it replaces the running one, so a rejected config leaves the running
+// logger and its active file untouched
+func validateConfig(cfg *LoggerConfig) error {
+	if !isValidFormat(cfg.Format) {
+		return fmt.Errorf("invalid format: %s", cfg.Format)
+	}
+	if _, err := resolveDirectory(cfg.Directory); err != nil {
+		return err
+	}
+	if cfg.Name == "" {
+		return fmt.Errorf("invalid name: must not be empty")
+	}
+	if cfg.Extension != "" {
+		if err := validateExtension(cfg.Extension); err != nil {
+			return err
+		}
+	}
+
+	if cfg.FlushTimer <= 0 {
+		return fmt.Errorf("invalid flush timer: must be positive")
+	}
+	if _, err := parseRetentionTiers(cfg.RetentionTiers, time.Duration(cfg.RetentionPeriod*float64(time.Hour))); err != nil {
+		return err
+	}
+	for _, pattern := range cfg.RetentionExclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid retention exclude pattern %q: %w", pattern, err)
+		}
+	}
+	switch cfg.CleanupScope {
+	case CleanupScopeName, CleanupScopeDirectory:
+	default:
+		return fmt.Errorf("invalid cleanup scope: %s", cfg.CleanupScope)
+	}
+	if cfg.TransientRetention < 0 {
+		return fmt.Errorf("invalid transient retention: must not be negative")
+	}
+
+	if cfg.JSONIndent && cfg.AuditChain {
+		return fmt.Errorf("invalid configuration: json indent not supported with the audit chain")
+	}
+	if cfg.Format == FormatMsgpack && cfg.AuditChain {
+		return fmt.Errorf("invalid configuration: audit chain not supported with the msgpack format")
+	}
+	if cfg.FoldLines && (cfg.AuditChain || cfg.RecordChecksum) {
+		return fmt.Errorf("invalid configuration: fold lines not supported with the audit chain or record checksum")
+	}
+	if cfg.RecordChecksum && cfg.AuditChain {
+		return fmt.Errorf("invalid configuration: record checksum not supported with the audit chain")
+	}
+	if cfg.RecordChecksum && (cfg.JSONIndent || cfg.Format == FormatMsgpack) {
+		return fmt.Errorf("invalid configuration: record checksum not supported with json indent or the msgpack format")
+	}
+	if cfg.MaxRecordBytes != 0 && cfg.MaxRecordBytes < minRecordBytes {
+		return fmt.Errorf("invalid max record bytes: must be 0 or at least %d", minRecordBytes)
+	}
+	if _, err := newPipeline(cfg.Pipeline, cfg.EncryptionKey); err != nil {
+		return err
+	}
+
+	if sizeLimit(cfg.MaxTotalSizeMB, cfg.MaxTotalSizeBytes) < 0 || sizeLimit(cfg.MinDiskFreeMB, cfg.MinDiskFreeBytes) < 0 ||
+		cfg.MaxSizeBytes < 0 || cfg.MaxTotalSizeBytes < 0 || cfg.MinDiskFreeBytes < 0 {
+		return fmt.Errorf("invalid disk space configuration")
+	}
+	if cfg.MinRotateInterval < 0 {
+		return fmt.Errorf("invalid min rotate interval: must not be negative")
+	}
+	if cfg.DiskCheckInterval < 0 {
+		return fmt.Errorf("invalid disk check interval: must not be negative")
+	}
+	if cfg.SpillMaxMB < 0 {
+		return fmt.Errorf("invalid spill size: must not be negative")
+	}
+	if cfg.JournalSizeKB < 0 {
+		return fmt.Errorf("invalid journal size: must not be negative")
+	}
+
+	if _, err := newConsoleWriter(cfg.Console); err != nil {
+		return err
+	}
+	switch cfg.SyncPolicy {
+	case SyncInterval, SyncEveryWrite, SyncEveryError:
+	default:
+		return fmt.Errorf("invalid sync policy: %s", cfg.SyncPolicy)
+	}
+	switch cfg.QueueType {
+	case QueueChannel, QueueRing:
+	default:
+		return fmt.Errorf("invalid queue type: %s", cfg.QueueType)
+	}
+	switch cfg.OverflowPolicy {
+	case OverflowDrop, OverflowBlock, OverflowBlockWithTimeout:
+	default:
+		return fmt.Errorf("invalid overflow policy: %s", cfg.OverflowPolicy)
+	}
+	if cfg.OverflowPolicy == OverflowBlockWithTimeout && cfg.OverflowTimeout <= 0 {
+		return fmt.Errorf("invalid overflow timeout: must be positive with %s", OverflowBlockWithTimeout)
+	}
+	if cfg.ShedThreshold < 0 || cfg.ShedThreshold > 1 {
+		return fmt.Errorf("invalid shed threshold: must be between 0 and 1")
+	}
+	if cfg.RecentSize < 0 {
+		return fmt.Errorf("invalid recent size: must not be negative")
+	}
+
+	if cfg.TraceDepth < 0 || cfg.TraceDepth > 10 {
+		return fmt.Errorf("invalid trace depth: must be between 0 and 10")
+	}
+	if cfg.CallerSkip < 0 || cfg.CallerSkip > 10 {
+		return fmt.Errorf("invalid caller skip: must be between 0 and 10")
+	}
+	if _, _, _, _, err := parseTimestamp(cfg.TimestampFormat, cfg.TimeZone); err != nil {
+		return err
+	}
+	if err := validateStaticFields(cfg.IncludeHost, cfg.IncludePID, cfg.IncludeSchema, cfg.StaticFields); err != nil {
+		return err
+	}
+
+	if cfg.DedupWindow < 0 {
+		return fmt.Errorf("invalid dedup window: must not be negative")
+	}
+	if cfg.HeartbeatInterval < 0 {
+		return fmt.Errorf("invalid heartbeat interval: must not be negative")
+	}
+	if cfg.Signals && cfg.SignalDebugDuration <= 0 {
+		return fmt.Errorf("invalid signal debug duration: must be positive")
+	}
+	return nil
+}
+
+// applyConfig sets the running config, which validateConfig accepted
 func applyConfig(ctx context.Context, cfg *LoggerConfig) error {
 	flags = 0
 	if cfg.ShowLevel {
@@ -174,23 +544,33 @@ func applyConfig(ctx context.Context, cfg *LoggerConfig) error {
 	if cfg.ShowTimestamp {
 		flags |= FlagShowTimestamp
 	}
-
-	directory = cfg.Directory
-	if directory == "" {
-		directory = "."
+	if cfg.ShowDeadline {
+		flags |= FlagShowDeadline
+	}
+	if cfg.ShowSequence {
+		flags |= FlagShowSequence
 	}
 
+	dir, err := resolveDirectory(cfg.Directory)
+	if err != nil {
+		return err
+	}
+	directory = dir
 	name = cfg.Name
 	format = cfg.Format
 
 	if cfg.Extension != "" {
-		if strings.HasPrefix(cfg.Extension, ".") {
-			return fmt.Errorf("extension should not start with dot: %s", cfg.Extension)
-		}
 		extension = cfg.Extension
 	} else if cfg.Format != "" {
-		// Use format as extension if no explicit extension provided
+		// Use format as extension if no explicit extension provided, json for other json formats
 		extension = cfg.Format
+		if cfg.Format == FormatMsgpack {
+			extension = FormatMsgpack
+		} else if isJSONFormat(cfg.Format) {
+			extension = "json"
+		} else if cfg.Format == FormatConsole {
+			extension = "log"
+		}
 	} else {
 		extension = "log"
 	}
@@ -198,41 +578,235 @@ func applyConfig(ctx context.Context, cfg *LoggerConfig) error {
 	maxSizeMB = cfg.MaxSizeMB
 	maxTotalSizeMB = cfg.MaxTotalSizeMB
 	minDiskFreeMB = cfg.MinDiskFreeMB
+	maxSizeBytes = cfg.MaxSizeBytes
+	maxTotalSizeBytes = cfg.MaxTotalSizeBytes
+	minDiskFreeBytes = cfg.MinDiskFreeBytes
+	maxSize = sizeLimit(maxSizeMB, maxSizeBytes)
+	maxTotalSize = sizeLimit(maxTotalSizeMB, maxTotalSizeBytes)
+	minDiskFree = sizeLimit(minDiskFreeMB, minDiskFreeBytes)
 	flushTimer = time.Duration(cfg.FlushTimer) * time.Millisecond
 	retentionPeriod = time.Duration(cfg.RetentionPeriod * float64(time.Hour))
 	retentionCheck = time.Duration(cfg.RetentionCheckInterval * float64(time.Minute))
+	tiers, err := parseRetentionTiers(cfg.RetentionTiers, retentionPeriod)
+	if err != nil {
+		return err
+	}
+	retentionTiers, tierSpecs = tiers, cfg.RetentionTiers
+	retentionExclude = cfg.RetentionExclude
+	cleanupScope = cfg.CleanupScope
+	transientRetention = time.Duration(cfg.TransientRetention * float64(time.Minute))
+
+	lazyOpen = cfg.LazyOpen
+	if cfg.AuditChain && !auditChain {
+		resetAuditChain()
+	}
+	auditChain = cfg.AuditChain
+	fileHeaders = cfg.FileHeaders
+	recordChecksum = cfg.RecordChecksum
+	jsonIndent = cfg.JSONIndent && (cfg.Format == "json" || cfg.Format == "ecs")
+	foldLines = cfg.FoldLines && (cfg.Format == "txt" || cfg.Format == FormatConsole)
+	maxRecordBytes = int(cfg.MaxRecordBytes)
+
+	stages, err := newPipeline(cfg.Pipeline, cfg.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	pipeline = stages
+	pipelineNames = cfg.Pipeline
+	encryptionKey = cfg.EncryptionKey
 
 	newBufferSize := cfg.BufferSize
 	if newBufferSize < 1 {
 		newBufferSize = 1000
 	}
 
-	if maxTotalSizeMB < 0 || minDiskFreeMB < 0 {
-		return fmt.Errorf("invalid disk space configuration")
-	}
+	minRotateInterval = time.Duration(cfg.MinRotateInterval) * time.Millisecond
+	diskCheckInterval = time.Duration(cfg.DiskCheckInterval) * time.Millisecond
+	invalidateDiskCheck()
 
-	if cfg.TraceDepth < 0 || cfg.TraceDepth > 10 {
-		return fmt.Errorf("invalid trace depth: must be between 0 and 10")
+	writer, err := newConsoleWriter(cfg.Console)
+	if err != nil {
+		return err
 	}
+	console = cfg.Console
+	consoleWriter = writer
+	consoleColor = isColorTerminal(writer)
+	fileMinLevel = cfg.FileMinLevel
+	consoleMinLevel = cfg.ConsoleMinLevel
+
+	syncPolicy = cfg.SyncPolicy
+	queueType = cfg.QueueType
+	overflowPolicy.Store(cfg.OverflowPolicy)
+	overflowTimeout.Store(int64(time.Duration(cfg.OverflowTimeout) * time.Millisecond))
+	shedThreshold.Store(math.Float64bits(cfg.ShedThreshold))
+	shedLevel.Store(cfg.ShedLevel)
+	setRecentSize(int(cfg.RecentSize))
+
 	traceDepth = cfg.TraceDepth
+	structuredTrace = cfg.StructuredTrace
+	traceWithLines = cfg.TraceWithLines
+	callerSkip = int(cfg.CallerSkip)
+
+	if err := configureTimestamp(cfg.TimestampFormat, cfg.TimeZone); err != nil {
+		return err
+	}
+	durationNanos = cfg.DurationNanos
+
+	if err := configureMetadata(cfg.IncludeHost, cfg.IncludePID, cfg.IncludeSchema, cfg.StaticFields); err != nil {
+		return err
+	}
+	includeGoroutine = cfg.IncludeGoroutine
+	structuredErrors = cfg.StructuredErrors
+	headerConfig = snapshotConfig(cfg)
+
+	dedupWindow = time.Duration(cfg.DedupWindow) * time.Millisecond
+	heartbeatInterval = time.Duration(cfg.HeartbeatInterval) * time.Millisecond
 
+	cancelDebugBoost()
 	logLevel.Store(cfg.Level)
 	bufferSize.Store(newBufferSize)
 
 	return nil
 }
 
-// getConfigValue returns defaultVal if cfgVal equals the zero value for type T,
+// getConfigValue returns defaultVal if cfgVal equals the zero value for type T and is not explicitly set,
 // otherwise returns cfgVal. Type T must satisfy the comparable constraint.
 // This is commonly used for merging configuration values with their defaults.
-func getConfigValue[T comparable](defaultVal, cfgVal T) T {
+func getConfigValue[T comparable](defaultVal, cfgVal T, set bool) T {
 	var zero T
-	if cfgVal == zero {
+	if cfgVal == zero && !set {
+		return defaultVal
+	}
+	return cfgVal
+}
+
+// getConfigMap returns defaultVal if cfgVal is nil and not explicitly set, otherwise returns cfgVal.
+// An empty non-nil map is kept, allowing the map to be cleared on reconfiguration.
+func getConfigMap[K comparable, V any](defaultVal, cfgVal map[K]V, set bool) map[K]V {
+	if cfgVal == nil && !set {
 		return defaultVal
 	}
 	return cfgVal
 }
 
+// getConfigSlice returns defaultVal if cfgVal is nil and not explicitly set, otherwise returns cfgVal.
+// An empty non-nil slice is kept, allowing a list to be cleared on reconfiguration.
+func getConfigSlice[T any](defaultVal, cfgVal []T, set bool) []T {
+	if cfgVal == nil && !set {
+		return defaultVal
+	}
+	return cfgVal
+}
+
+// MarkSet adds config keys whose values are applied even when zero and returns the config.
+func (c *LoggerConfig) MarkSet(keys ...string) *LoggerConfig {
+	for _, key := range keys {
+		if !c.isSet(key) {
+			c.Set = append(c.Set, key)
+		}
+	}
+	return c
+}
+
+// isSet reports whether a config key is explicitly set
+func (c *LoggerConfig) isSet(key string) bool {
+	return slices.Contains(c.Set, key)
+}
+
+// levelKeys are the config keys holding levels, accepting level names such as "debug" when decoded
+var levelKeys = []string{"level", "file_min_level", "console_min_level", "shed_level"}
+
+// UnmarshalJSON decodes the config and marks every field present in the document as set,
+// so zero values in configuration files are applied. Levels are given as numbers or as names
+// accepted by ParseLevel, e.g. "debug" or "INFO+2".
+func (c *LoggerConfig) UnmarshalJSON(data []byte) error {
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return err
+	}
+	if named, err := resolveLevelNames(present); err != nil {
+		return err
+	} else if named {
+		if data, err = json.Marshal(present); err != nil {
+			return err
+		}
+	}
+
+	type plain LoggerConfig
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+
+	keys := configKeys()
+	for key := range present {
+		if slices.Contains(keys, key) {
+			c.MarkSet(key)
+		}
+	}
+	slices.Sort(c.Set)
+	return nil
+}
+
+// UnmarshalTOML decodes the config from a TOML table for decoders passing the decoded table to it, such as
+// github.com/BurntSushi/toml. Like UnmarshalJSON, it accepts level names and marks every key present as set.
+func (c *LoggerConfig) UnmarshalTOML(data any) error {
+	table, ok := data.(map[string]any)
+	if !ok {
+		return fmt.Errorf("invalid config: expected a table, got %T", data)
+	}
+	doc, err := json.Marshal(table)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return c.UnmarshalJSON(doc)
+}
+
+// resolveLevelNames replaces level names of the level keys in a decoded document by their numeric values,
+// reporting whether any name was replaced
+func resolveLevelNames(present map[string]json.RawMessage) (bool, error) {
+	named := false
+	for _, key := range levelKeys {
+		var name string
+		if raw, ok := present[key]; !ok || json.Unmarshal(raw, &name) != nil {
+			continue
+		}
+		level, err := parseLevel(name)
+		if err != nil {
+			return false, invalidConfig(fmt.Errorf("invalid %s: %s", key, name))
+		}
+		present[key] = json.RawMessage(strconv.FormatInt(level, 10))
+		named = true
+	}
+	return named, nil
+}
+
+// configKeys returns the config keys of all configurable fields
+func configKeys() []string {
+	t := reflect.TypeOf(LoggerConfig{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("json"); key != "" && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// validateSet rejects unknown keys in the Set lists of configs passed as options
+func validateSet(opts []Option) error {
+	keys := configKeys()
+	for _, opt := range opts {
+		if cfg, ok := opt.(*LoggerConfig); ok && cfg != nil {
+			for _, key := range cfg.Set {
+				if !slices.Contains(keys, key) {
+					return fmt.Errorf("unknown config key in Set: %s", key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // shutdownOnce ensures the logger shutdown routine executes exactly once,
 // even if multiple shutdown paths are triggered simultaneously.
 var shutdownOnce sync.Once
@@ -247,23 +821,47 @@ func shutdownLogger(ctx context.Context) error {
 		return nil
 	}
 
-	timer := time.NewTimer(2 * flushTimer)
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-timer.C:
-	}
-
 	loggerDisabled.Store(true)
 	isInitialized.Store(false)
+	stopAdmin()
+	stopSignals()
+	endDebugBoost()
 
-	if processCancel != nil {
-		processCancel()
-	}
+	// The processor writes the queued records until the queue is empty or the context is done
+	flushed, abandoned := stopProcessor(ctx)
+	closeTransientFile()
 	close(logChannel)
 
-	// Final file operations
+	// Final file operations, spilled records not drained yet are kept for the next start
+	err := closeCurrentFile(ctx)
+	closeSpill()
+	closeJournal()
+	stopRun()
+	if err != nil || abandoned > 0 {
+		saveStats(shutdownError)
+	} else {
+		saveStats(shutdownClean)
+	}
+	if abandoned > 0 {
+		if err == nil {
+			err = ctx.Err()
+		}
+		return &ShutdownError{Flushed: flushed, Abandoned: abandoned, Err: err}
+	}
+	return err
+}
+
+// closeCurrentFile syncs and closes the active log file, respecting context cancellation.
+func closeCurrentFile(ctx context.Context) error {
+	flushWrites()
 	if currentFile := currentFile.Load().(*os.File); currentFile != nil {
+		if err := writeFileFooter(currentFile, ""); err != nil {
+			return fmt.Errorf("failed to write file footer: %w", err)
+		}
+		if err := writeAuditFooter(currentFile); err != nil {
+			return fmt.Errorf("failed to write audit footer: %w", err)
+		}
+
 		syncDone := make(chan error, 1)
 		go func() {
 			syncDone <- currentFile.Sync()
@@ -286,4 +884,4 @@ func shutdownLogger(ctx context.Context) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Console targets usable in LoggerConfig.Console
+const (
+	ConsoleStdout = "stdout"
+	ConsoleStderr = "stderr"
+)
+
+// Output routing state, only replaced during initialization while the processor is stopped
+var (
+	console         string
+	consoleWriter   io.Writer // nil when console output is disabled
+	fileMinLevel    int64
+	consoleMinLevel int64
+)
+
+// newConsoleWriter returns the writer of a console target, nil if the target is empty
+func newConsoleWriter(target string) (io.Writer, error) {
+	switch target {
+	case "":
+		return nil, nil
+	case ConsoleStdout:
+		return os.Stdout, nil
+	case ConsoleStderr:
+		return os.Stderr, nil
+	default:
+		return nil, fmt.Errorf("invalid console: %s", target)
+	}
+}
+
+// writeConsole writes a serialized record to the console.
+// It must only be called from the processor goroutine.
+func writeConsole(line []byte) {
+	_, _ = consoleWriter.Write(line)
+}
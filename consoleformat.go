@@ -0,0 +1,205 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+// FormatConsole is the format of aligned, human-readable records for development, color-coded on a terminal console
+const FormatConsole = "console"
+
+// consoleMessageWidth is the column width the message is padded to when fields follow, so fields line up
+const consoleMessageWidth = 40
+
+// ANSI escape sequences of the console format
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiKey   = "\x1b[36m"
+)
+
+// consoleColor enables colors in the console output of the console format, set with the console writer
+var consoleColor bool
+
+// levelColors are the colors of the level names, by the lowest level they apply to
+var levelColors = [...]struct {
+	level int64
+	color string
+}{
+	{LevelError, "\x1b[1;31m"},
+	{LevelWarn, "\x1b[33m"},
+	{LevelInfo, "\x1b[32m"},
+	{LevelDebug, "\x1b[34m"},
+}
+
+// isColorTerminal reports whether w is a terminal and colors are not disabled by the NO_COLOR convention
+func isColorTerminal(w any) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// levelColor returns the color of a level, none below debug
+func levelColor(level int64) string {
+	for _, lc := range levelColors {
+		if level >= lc.level {
+			return lc.color
+		}
+	}
+	return ""
+}
+
+// serializeConsole formats records for reading: time, the level padded to a fixed width, the message padded
+// so fields line up, then the metadata and fields as key=value. With color, the time is dimmed and the level
+// and keys are color-coded.
+func (s *serializer) serializeConsole(r *logRecord, color bool) []byte {
+	if r.Flags&FlagShowTimestamp != 0 {
+		s.paint(color, ansiDim)
+		start := len(s.buf)
+		s.writeTimestamp(r.TimeStamp, false)
+
+		// Layouts such as rfc3339nano trim trailing zeros, pad to the widest time written so far
+		width := len(s.buf) - start
+		s.timeWidth = max(s.timeWidth, width)
+		s.paint(color, ansiReset)
+		for ; width <= s.timeWidth; width++ {
+			s.buf = append(s.buf, ' ')
+		}
+	}
+
+	if r.Flags&FlagShowLevel != 0 {
+		name := levelToString(r.Level)
+		s.paint(color, levelColor(r.Level))
+		s.buf = append(s.buf, name...)
+		s.paint(color, ansiReset)
+		for n := len(name); n < 5; n++ {
+			s.buf = append(s.buf, ' ')
+		}
+		s.buf = append(s.buf, ' ')
+	}
+
+	msg, hasMsg, args := splitMessage(r)
+	start := len(s.buf)
+	if hasMsg {
+		s.writeString(msg)
+	}
+	fieldsStart := len(s.buf)
+
+	if r.Seq != 0 {
+		s.writeConsoleKey(color, "seq")
+		s.buf = strconv.AppendUint(s.buf, r.Seq, 10)
+	}
+	for i := range metaFields {
+		s.writeConsoleKey(color, metaFields[i].Key)
+		s.writeTextFieldValue(&metaFields[i])
+	}
+	if r.Goroutine != 0 {
+		s.writeConsoleKey(color, "goroutine")
+		s.buf = strconv.AppendUint(s.buf, r.Goroutine, 10)
+	}
+	if worker := recordWorker(r); worker != "" {
+		s.writeConsoleKey(color, "worker")
+		s.writeTextString(worker)
+	}
+	if s.truncatedFrom != 0 {
+		s.writeConsoleKey(color, "truncated")
+		s.buf = append(s.buf, "true"...)
+		s.writeConsoleKey(color, "original_bytes")
+		s.buf = strconv.AppendInt(s.buf, int64(s.truncatedFrom), 10)
+	}
+	if r.Trace != "" {
+		s.writeConsoleKey(color, "trace")
+		s.writeTextString(r.Trace)
+	}
+	if remaining, ok := deadlineRemaining(r); ok {
+		s.writeConsoleKey(color, "deadline_remaining")
+		s.buf = appendDuration(s.buf, remaining)
+	}
+
+	// Key/value pairs as in the json format
+	for i := 0; i < len(args); i++ {
+		switch key := args[i].(type) {
+		case Field:
+			s.writeConsoleField(color, "", &key)
+		case string:
+			if i+1 == len(args) {
+				s.writeConsoleKey(color, badKey)
+				s.writeTextString(key)
+				break
+			}
+			s.writeConsoleKey(color, key)
+			s.writeTextValue(args[i+1])
+			i++
+		default:
+			s.writeConsoleKey(color, badKey)
+			s.writeTextValue(key)
+		}
+	}
+	for i := range r.Fields {
+		s.writeConsoleField(color, "", &r.Fields[i])
+	}
+
+	// Pad the message to align the fields, they were written after it with a leading space each
+	if fields := len(s.buf) - fieldsStart; fields > 0 && hasMsg {
+		if pad := consoleMessageWidth - utf8.RuneCount(s.buf[start:fieldsStart]); pad > 0 {
+			s.buf = append(s.buf, make([]byte, pad)...)
+			copy(s.buf[fieldsStart+pad:], s.buf[fieldsStart:fieldsStart+fields])
+			for i := fieldsStart; i < fieldsStart+pad; i++ {
+				s.buf[i] = ' '
+			}
+		}
+	} else if fields > 0 {
+		// Without a message the fields follow the level directly
+		copy(s.buf[fieldsStart:], s.buf[fieldsStart+1:])
+		s.buf = s.buf[:len(s.buf)-1]
+	}
+
+	s.buf = append(s.buf, '\n')
+	return s.buf
+}
+
+// writeConsoleKey writes the separating space and the key of a field followed by '='
+func (s *serializer) writeConsoleKey(color bool, key string) {
+	s.buf = append(s.buf, ' ')
+	s.paint(color, ansiKey)
+	s.writeTextString(key)
+	s.buf = append(s.buf, '=')
+	s.paint(color, ansiReset)
+}
+
+// writeConsoleField writes a typed field with its key prefixed by the names of its enclosing groups,
+// the fields of a group as key=value pairs of their own
+func (s *serializer) writeConsoleField(color bool, prefix string, f *Field) {
+	if f.kind == kindGroup {
+		for _, member := range f.any.([]Field) {
+			s.writeConsoleField(color, prefix+f.Key+".", &member)
+		}
+		return
+	}
+	s.writeConsoleKey(color, prefix+f.Key)
+	s.writeTextFieldValue(f)
+}
+
+// paint writes an escape sequence if colors are enabled
+func (s *serializer) paint(color bool, seq string) {
+	if color && seq != "" {
+		s.buf = append(s.buf, seq...)
+	}
+}
+
+// consoleLine returns the line written to the console for a record serialized in s.buf. Records of the console
+// format are rendered again with colors for a color terminal into a separate buffer, s.buf is kept for the file.
+func (s *serializer) consoleLine(r *logRecord) []byte {
+	if format != FormatConsole || !consoleColor || r.Raw != nil {
+		return s.buf
+	}
+	file := s.buf
+	s.buf = s.colored[:0]
+	s.serializeConsole(r, true)
+	s.colored, s.buf = s.buf, file
+	return s.colored
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"context"
+	"slices"
+)
+
+// fieldsKey is the context key of the fields attached with ContextWithFields
+type fieldsKey struct{}
+
+// contextWithFields attaches the fields built from args to the context, after the fields of a parent context.
+// A field replaces a parent field of the same key.
+func contextWithFields(ctx context.Context, args ...any) context.Context {
+	added := argFields(args)
+	if len(added) == 0 {
+		return ctx
+	}
+	parent := contextFields(ctx)
+	fields := make([]Field, 0, len(parent)+len(added))
+	for _, f := range parent {
+		if !slices.ContainsFunc(added, func(a Field) bool { return a.Key == f.Key }) {
+			fields = append(fields, f)
+		}
+	}
+	fields = append(fields, added...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// contextFields returns the fields attached to the context, nil if none
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+	return fields
+}
+
+// withContextArgs returns the args of a record followed by the fields of its context, each a Field arg
+func withContextArgs(ctx context.Context, args []any) []any {
+	fields := contextFields(ctx)
+	if len(fields) == 0 {
+		return args
+	}
+	merged := make([]any, 0, len(args)+len(fields))
+	merged = append(merged, args...)
+	for _, f := range fields {
+		merged = append(merged, f)
+	}
+	return merged
+}
+
+// withContextFields returns the fields of a record followed by the fields of its context
+func withContextFields(ctx context.Context, fields []Field) []Field {
+	if extra := contextFields(ctx); len(extra) > 0 {
+		return append(fields[:len(fields):len(fields)], extra...)
+	}
+	return fields
+}
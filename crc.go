@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"slices"
+)
+
+// recordChecksum appends a CRC-32C checksum to every record written to the log file
+var recordChecksum bool
+
+// Record checksum markers
+const (
+	checksumLen     = crc32.Size * 2
+	checksumJSONKey = `"crc":"`
+	checksumTextKey = " crc="
+)
+
+// appendChecksum appends the hex CRC-32C of the serialized record in s.buf. JSON records get a "crc" member,
+// text records a trailing "crc=" token. The checksum covers the record as it would be written without it.
+func (s *serializer) appendChecksum() []byte {
+	line := s.buf[:len(s.buf)-1] // without newline
+	isJSON := len(line) > 0 && line[0] == '{'
+	if isJSON {
+		line = line[:len(line)-1] // without closing brace
+	}
+
+	var sum [crc32.Size]byte
+	putChecksum(sum[:], line)
+
+	s.buf = line
+	if isJSON {
+		if len(line) > 1 {
+			s.buf = append(s.buf, ',')
+		}
+		s.buf = append(s.buf, checksumJSONKey...)
+		s.buf = hex.AppendEncode(s.buf, sum[:])
+		s.buf = append(s.buf, '"', '}', '\n')
+	} else {
+		s.buf = append(s.buf, checksumTextKey...)
+		s.buf = hex.AppendEncode(s.buf, sum[:])
+		s.buf = append(s.buf, '\n')
+	}
+	return s.buf
+}
+
+// putChecksum writes the big-endian CRC-32C of data to sum
+func putChecksum(sum, data []byte) {
+	binary.BigEndian.PutUint32(sum, crc32.Checksum(data, crc32c))
+}
+
+// splitChecksum separates a record written with a checksum into the checksummed body and the hex checksum,
+// reporting false if the line has no checksum. The line is given without newline. JSON records are told by
+// the suffix, so the remainder of a torn record fails the check instead of being read as a text record.
+func splitChecksum(line []byte) (body, sum []byte, ok bool) {
+	if bytes.HasSuffix(line, []byte{'"', '}'}) {
+		suffixLen := len(checksumJSONKey) + checksumLen + 2
+		if len(line) < suffixLen+1 {
+			return nil, nil, false
+		}
+		start := len(line) - suffixLen
+		if !bytes.HasPrefix(line[start:], []byte(checksumJSONKey)) {
+			return nil, nil, false
+		}
+		body = line[:start]
+		if len(body) > 1 && body[len(body)-1] == ',' {
+			body = body[:len(body)-1]
+		}
+		return body, line[start+len(checksumJSONKey) : len(line)-2], true
+	}
+
+	suffixLen := len(checksumTextKey) + checksumLen
+	if len(line) < suffixLen {
+		return nil, nil, false
+	}
+	start := len(line) - suffixLen
+	if !bytes.HasPrefix(line[start:], []byte(checksumTextKey)) {
+		return nil, nil, false
+	}
+	return line[:start], line[start+len(checksumTextKey):], true
+}
+
+// verifyRecord checks the checksum of a line written with RecordChecksum, given with or without newline, and
+// returns the record as it was serialized, without checksum and newline.
+func verifyRecord(line []byte) ([]byte, error) {
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	body, sum, ok := splitChecksum(line)
+	if !ok {
+		return nil, fmt.Errorf("record without checksum")
+	}
+	var want, got [crc32.Size]byte
+	if _, err := hex.Decode(got[:], sum); err != nil {
+		return nil, fmt.Errorf("invalid record checksum: %w", err)
+	}
+	putChecksum(want[:], body)
+	if want != got {
+		return nil, fmt.Errorf("record checksum mismatch")
+	}
+	if bytes.HasSuffix(line, []byte{'"', '}'}) {
+		return append(slices.Clip(body), '}'), nil
+	}
+	return body, nil
+}
@@ -0,0 +1,124 @@
+// Package decrypt reads log files written by the logger with EncryptionKey configured.
+//
+// Encrypted files start with the magic header "LGE1", followed by one frame per record:
+// a 4-byte big-endian frame length, a 12-byte nonce and the AES-GCM sealed record.
+package decrypt
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// magic identifies encrypted log files
+const magic = "LGE1"
+
+// maxFrameSize guards against reading garbage lengths from corrupted files
+const maxFrameSize = 64 << 20
+
+// ParseKey decodes a hex encoded 16, 24 or 32 byte key, as used in LoggerConfig.EncryptionKey.
+func ParseKey(hexKey string) ([]byte, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: must be hex encoded")
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("invalid key size: %d bytes", len(key))
+	}
+}
+
+// Reader decrypts an encrypted log stream record by record.
+type Reader struct {
+	r    *bufio.Reader
+	aead cipher.AEAD
+	buf  []byte // remaining plaintext of the current record
+}
+
+// NewReader validates the file header and returns a reader producing the plaintext log lines.
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(header) != magic {
+		return nil, fmt.Errorf("not an encrypted log file")
+	}
+
+	return &Reader{r: br, aead: aead}, nil
+}
+
+// Next returns the next decrypted record, or io.EOF at the end of the stream.
+func (d *Reader) Next() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(d.r, size[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("truncated frame header")
+		}
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	nonceSize := d.aead.NonceSize()
+	if n < uint32(nonceSize+d.aead.Overhead()) || n > maxFrameSize {
+		return nil, fmt.Errorf("invalid frame size: %d", n)
+	}
+
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(d.r, frame); err != nil {
+		return nil, fmt.Errorf("truncated frame: %w", err)
+	}
+
+	plaintext, err := d.aead.Open(frame[nonceSize:nonceSize], frame[:nonceSize], frame[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt record: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Read implements io.Reader over the concatenated plaintext records.
+func (d *Reader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		record, err := d.Next()
+		if err != nil {
+			return 0, err
+		}
+		d.buf = record
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// File decrypts a whole log file and returns its plaintext content.
+func File(path string, key []byte) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := NewReader(f, key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"time"
+)
+
+// dedupMaxEntries bounds the messages tracked within a dedup window, records of further messages are
+// written without deduplication
+const dedupMaxEntries = 1024
+
+// dedupWindow is the window identical records are collapsed in, 0 disables deduplication
+var dedupWindow time.Duration
+
+// dedupKey identifies identical records
+type dedupKey struct {
+	level int64
+	msg   string
+}
+
+// dedupEntry tracks a written record and the identical records suppressed after it
+type dedupEntry struct {
+	first time.Time // time of the written record, the window starts there
+	last  logRecord // latest suppressed record, written as the summary
+	count int64     // suppressed records
+}
+
+// dedupEntries are the messages of the running windows, only used by the processor goroutine
+var dedupEntries = make(map[dedupKey]*dedupEntry)
+
+// dedupRecord reports whether the record repeats the level and message of a record written within the
+// window and is suppressed. A repeat after the window writes the summary of the window, then the record
+// starts a new window.
+// It must only be called from the processor goroutine.
+func dedupRecord(s *serializer, record *logRecord) bool {
+	if dedupWindow <= 0 {
+		return false
+	}
+	msg, ok, _ := splitMessage(record)
+	if !ok {
+		return false
+	}
+	key := dedupKey{level: record.Level, msg: msg}
+
+	if e, found := dedupEntries[key]; found {
+		if record.TimeStamp.Sub(e.first) < dedupWindow {
+			e.last = *record
+			e.count++
+			return true
+		}
+		writeDedupSummary(s, e)
+		delete(dedupEntries, key)
+	}
+	if len(dedupEntries) < dedupMaxEntries {
+		dedupEntries[key] = &dedupEntry{first: record.TimeStamp}
+	}
+	return false
+}
+
+// expireDedup writes the summaries of windows ended by now, or of all windows with a zero now.
+// It must only be called from the processor goroutine.
+func expireDedup(s *serializer, now time.Time) {
+	for key, e := range dedupEntries {
+		if !now.IsZero() && now.Sub(e.first) < dedupWindow {
+			continue
+		}
+		writeDedupSummary(s, e)
+		delete(dedupEntries, key)
+	}
+}
+
+// writeDedupSummary writes the latest suppressed record of a window with the number of suppressed
+// records as "repeat_count", nothing if no record was suppressed.
+// It must only be called from the processor goroutine.
+func writeDedupSummary(s *serializer, e *dedupEntry) {
+	if e.count == 0 {
+		return
+	}
+	record := e.last
+	count := Int64("repeat_count", e.count)
+	if record.HasMsg {
+		record.Fields = append(record.Fields, count)
+	} else {
+		// Copy the args so the logged slice is not modified
+		args := make([]any, 0, len(record.Args)+1)
+		record.Args = append(append(args, record.Args...), count)
+	}
+	writeRecord(s, &record)
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DiagKind identifies the kind of a diagnostic event
+type DiagKind string
+
+// Diagnostic event kinds
+const (
+	DiagRotation   DiagKind = "rotation"    // a new log file was started, by size, interval, Rotate or a write error
+	DiagRetention  DiagKind = "retention"   // a file older than the retention period was deleted
+	DiagCleanup    DiagKind = "cleanup"     // a file was deleted to meet the disk space limits
+	DiagWriteError DiagKind = "write_error" // records were lost to a write that failed after recovery
+	DiagDrop       DiagKind = "drop"        // records dropped since the previous report were reported in the log
+)
+
+// DiagEvent is an internal event of the logger received from Diagnostics. Fields not applying to the kind are zero.
+type DiagEvent struct {
+	Kind     DiagKind
+	Time     time.Time
+	File     string    // file started by a rotation, or deleted by retention or cleanup
+	Previous string    // file closed by a rotation
+	ModTime  time.Time // modification time of a deleted file
+	Bytes    int64     // size of a deleted file
+	Count    uint64    // records dropped, or lost by a failed write
+	Cause    string    // comma separated causes of the dropped records
+	Err      error     // error of a failed write
+}
+
+// diagBufferSize is the number of events buffered for the diagnostics consumer, later events are discarded
+const diagBufferSize = 64
+
+// Diagnostics state, events are only sent once Diagnostics was called
+var (
+	diagChan    = make(chan DiagEvent, diagBufferSize)
+	diagEnabled atomic.Bool
+)
+
+// diagnostics enables diagnostic events and returns their channel
+func diagnostics() <-chan DiagEvent {
+	diagEnabled.Store(true)
+	return diagChan
+}
+
+// emitDiag sends an event to the diagnostics channel without blocking, it is discarded if the buffer is full
+func emitDiag(e DiagEvent) {
+	if !diagEnabled.Load() {
+		return
+	}
+	e.Time = clockNow()
+	select {
+	case diagChan <- e:
+	default:
+	}
+}
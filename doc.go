@@ -13,13 +13,44 @@
 // - Unique timestamp-based log file naming with nanosecond precision
 // - Thread-safe operations using atomic counters
 // - Context-aware logging with cancellation support
-// - Multiple log levels (Debug, Info, Warn, Error) matching slog levels
-// - Efficient JSON and TXT structured logging with zero allocations
-// - Function call trace support with configurable depth
-// - Graceful shutdown with context support
-// - Runtime reconfiguration
-// - Disk full protection with logging pause
+// - Multiple log levels (Debug, Info, Warn, Error) matching slog levels, with per-module overrides
+// - Optional console output with independent file and console level thresholds
+// - Colorized, aligned console format for development
+// - Efficient JSON and TXT structured logging with a zero-allocation typed field API and nested groups
+// - Maps, slices and structs encoded as JSON objects and arrays in the json formats
+// - json.Marshaler and encoding.TextMarshaler values written in the form they define
+// - Time values in the record time stamp format, durations as strings or nanoseconds
+// - Record size limit truncating oversized records with a marker
+// - Escaped or folded multi-line strings in text records
+// - Compact MessagePack format with conversion back to JSON
+// - Elastic Common Schema output for direct ingestion by Elasticsearch
+// - GELF sink for Graylog over chunked UDP or TCP in the gelf package
+// - Batching Grafana Loki push sink with backoff and buffering in the loki package
+// - Kafka sink over a pluggable producer with keyed partitioning and a fallback file in the kafka package
+// - OTLP log export to OpenTelemetry collectors over HTTP in the otlp package
+// - net/http request logging middleware with panic recovery in the httplog package
+// - gRPC interceptors and a grpclog.LoggerV2 adapter in the separate grpclog module
+// - Function call trace support with configurable depth, as a string or an array of frames
+// - Caller frame skip for packages wrapping the logging functions
+// - Goroutine ids and context worker labels grouping records of concurrent workers
+// - Request-scoped fields attached to a context with ContextWithFields
+// - Graceful shutdown with context support, Exit writing queued records first, and a Stats run summary
+// - Sentinel errors for errors.Is: ErrNotInitialized, ErrShutdown, ErrDiskFull and ErrInvalidConfig
+// - Diagnostics channel of typed events for rotations, retention and cleanup deletions, write errors and drops
+// - Runtime reconfiguration through a config struct or functional options
+// - Optional authenticated admin listener for level, rotation, flush, stats and recent records
+// - Query API reading records back from txt, json and ecs log files
+// - lgr command tailing, filtering, merging and pretty-printing log directories
+// - Opt-in signal controls: SIGHUP reopen, SIGUSR1 temporary debug level, SIGUSR2 stats
+// - Disk full protection with logging pause, Paused and Resume, and a record of the records lost when it ends
 // - Log retention management with configurable period and check interval
+// - Retention exclusion patterns protecting files from automatic deletion
+// - Pluggable archival uploading files to object storage before deletion
+// - Composable record pipeline with compression, checksum and AES-GCM encryption stages
+// - Tamper-evident SHA-256 hash chaining for audit logs
+// - Optional per-file header and footer records for forensic reconstruction
+// - Optional per-record CRC-32C checksums detecting torn writes
+// - Memory-mapped journal recovering queued records after the process is killed
 //
 // Lixen Wraith, 2024
-package logger
\ No newline at end of file
+package logger
@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"context"
+	"math/bits"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// dropEvent identifies drop reports in the "event" field
+const dropEvent = "drops"
+
+// dropCause is a bit set of the reasons records were dropped
+type dropCause uint32
+
+// Drop causes, reported by name in the "cause" field of drop reports
+const (
+	causeBufferFull dropCause = 1 << iota
+	causeDiskFull
+	causeDisabled
+	causeFileUnavailable
+	causeWriteError
+	causeShed
+)
+
+// dropCauseNames are the reported names of drop causes, in bit order
+var dropCauseNames = [...]string{"buffer_full", "disk_full", "logger_disabled", "file_unavailable", "write_error", "shed"}
+
+// Drop window state. A window starts with the previous report and collects the time of its first and
+// last drop, in Unix nanoseconds, and the causes of its drops.
+var (
+	firstDropAt  atomic.Int64
+	lastDropAt   atomic.Int64
+	lastReportAt atomic.Int64
+	dropCauses   atomic.Uint32
+)
+
+// dropsByCause counts the dropped records of each cause over the process lifetime, in bit order
+var dropsByCause [len(dropCauseNames)]atomic.Uint64
+
+// recordDrop counts dropped records and notes the drop time and cause for the next report
+func recordDrop(n uint64, cause dropCause) {
+	now := clockNow().UnixNano()
+	firstDropAt.CompareAndSwap(0, now)
+	lastDropAt.Store(now)
+	dropCauses.Or(uint32(cause))
+	dropsByCause[bits.TrailingZeros32(uint32(cause))].Add(n)
+	droppedLogs.Add(n)
+}
+
+// writeGapMarker writes a drop report ahead of the next record when records were dropped since the
+// previous report, marking the position of the gap in the file.
+// It must only be called from the processor goroutine, before the next record is serialized.
+func writeGapMarker(s *serializer) {
+	total := droppedLogs.Load()
+	reported := loggedDrops.Load()
+	if total <= reported {
+		return
+	}
+	// Update first, a failing marker write counts as a drop of the next window
+	loggedDrops.Store(total)
+
+	marker := dropReport(total-reported, total)
+	writeRecord(s, &marker)
+	emitDiag(DiagEvent{Kind: DiagDrop, Count: total - reported, Cause: marker.Fields[1].str})
+}
+
+// dropReport builds the structured record reporting drops since the previous report:
+//
+//	event=drops cause=C dropped_count=N total_dropped=T first_drop=RFC3339 last_drop=RFC3339 outage_ms=O window_ms=W
+//
+// The outage spans from the first drop to now, the window from the previous report, or the first drop
+// if there was none, to now.
+func dropReport(dropped, total uint64) logRecord {
+	now := clockNow()
+	first := time.Unix(0, firstDropAt.Swap(0))
+	last := time.Unix(0, lastDropAt.Load())
+	if last.Before(first) {
+		last = first
+	}
+	windowStart := first
+	if prev := lastReportAt.Swap(now.UnixNano()); prev != 0 {
+		windowStart = time.Unix(0, prev)
+	}
+
+	var causes []string
+	bits := dropCause(dropCauses.Swap(0))
+	for i, name := range dropCauseNames {
+		if bits&(1<<i) != 0 {
+			causes = append(causes, name)
+		}
+	}
+
+	record := logRecord{
+		LogCtx:    context.Background(),
+		Flags:     FlagDefault,
+		TimeStamp: now,
+		Level:     LevelError,
+		HasMsg:    true,
+		Msg:       "Logs were dropped",
+	}
+	record.Fields = []Field{
+		Str("event", dropEvent),
+		Str("cause", strings.Join(causes, ",")),
+		Uint64("dropped_count", dropped),
+		Uint64("total_dropped", total),
+		Str("first_drop", first.UTC().Format(time.RFC3339Nano)),
+		Str("last_drop", last.UTC().Format(time.RFC3339Nano)),
+		Int64("outage_ms", now.Sub(first).Milliseconds()),
+		Int64("window_ms", now.Sub(windowStart).Milliseconds()),
+	}
+	return record
+}
@@ -0,0 +1,233 @@
+package logger
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ecsVersion is the Elastic Common Schema version written as "ecs.version"
+const ecsVersion = "8.11.0"
+
+// ecsMember is a key/value pair of an ecs record, nested by the dots in its key
+type ecsMember struct {
+	key     string
+	field   Field // typed value if isField
+	isField bool
+	value   any
+	done    bool
+}
+
+// ecsErrorKeys are the keys whose value becomes the "error" object
+var ecsErrorKeys = []string{"error", "err"}
+
+// ecsStackKeys are the keys whose value becomes "error.stack_trace" when the record has an error
+var ecsStackKeys = []string{"stack", "stack_trace"}
+
+// serializeECS formats log entries as Elastic Common Schema JSON: "@timestamp", "log.level", "message" and
+// "ecs.version", the trace as "log.origin.function", or with StructuredTrace the innermost frame as
+// "log.origin.function", "log.origin.file.name" and "log.origin.file.line" and all frames as
+// "log.origin.frames", an error as "error.message" and "error.type", and the
+// host name and process id as "host.hostname" and "process.pid", and the sequence number as "event.sequence".
+// Fields are nested by the dots in their keys, so "http.request.method" is written as
+// {"http":{"request":{"method":...}}}.
+func (s *serializer) serializeECS(r *logRecord) []byte {
+	s.buf = append(s.buf, '{')
+
+	if r.Flags&FlagShowTimestamp != 0 {
+		s.writeJSONKey("@timestamp")
+		s.writeTimestamp(r.TimeStamp, true)
+	}
+	if r.Flags&FlagShowLevel != 0 {
+		s.writeJSONKey("log.level")
+		s.writeJSONString(strings.ToLower(levelToString(r.Level)))
+	}
+	msg, hasMsg, args := splitMessage(r)
+	if hasMsg {
+		s.writeJSONKey("message")
+		s.writeJSONString(msg)
+	}
+	if r.Seq != 0 {
+		s.writeJSONKey("event.sequence")
+		s.buf = strconv.AppendUint(s.buf, r.Seq, 10)
+	}
+	s.writeJSONKey("ecs.version")
+	s.writeJSONString(ecsVersion)
+	s.writeJSONTruncation()
+	if len(r.Frames) > 0 {
+		// The innermost frame is the origin, the full chain is kept in "log.origin.frames"
+		origin := r.Frames[len(r.Frames)-1]
+		s.writeJSONKey("log.origin.function")
+		s.writeJSONString(origin.function)
+		s.writeJSONKey("log.origin.file.name")
+		s.writeJSONString(origin.file)
+		s.writeJSONKey("log.origin.file.line")
+		s.buf = strconv.AppendInt(s.buf, int64(origin.line), 10)
+		s.writeJSONKey("log.origin.frames")
+		s.writeTraceFrames(r.Frames)
+	} else if r.Trace != "" {
+		s.writeJSONKey("log.origin.function")
+		s.writeJSONString(r.Trace)
+	}
+
+	s.collectECSMembers(r, args)
+	s.writeECSMembers("", false)
+
+	s.buf = append(s.buf, '}', '\n')
+	return s.buf
+}
+
+// collectECSMembers collects the key/value pairs of the record in s.members, pairing args as the json
+// format does, and maps the error of the record to "error.*" members. The error is the value of the first
+// member keyed "error" or "err", or else the first error value, including an error logged as the message.
+func (s *serializer) collectECSMembers(r *logRecord, args []any) {
+	s.members = s.members[:0]
+	for _, f := range metaFields {
+		switch {
+		case includeHost && f.Key == "host":
+			f.Key = "host.hostname"
+		case includePID && f.Key == "pid":
+			f.Key = "process.pid"
+		}
+		s.members = append(s.members, ecsMember{key: f.Key, field: f, isField: true})
+	}
+	if r.Goroutine != 0 {
+		s.members = append(s.members, ecsMember{key: "process.thread.id", field: Int64("process.thread.id", int64(r.Goroutine)), isField: true})
+	}
+	if worker := recordWorker(r); worker != "" {
+		s.members = append(s.members, ecsMember{key: "process.thread.name", field: Str("process.thread.name", worker), isField: true})
+	}
+	for i := 0; i < len(args); i++ {
+		switch key := args[i].(type) {
+		case Field:
+			s.appendECSField("", key)
+		case string:
+			if i+1 == len(args) {
+				s.members = append(s.members, ecsMember{key: badKey, value: key})
+				break
+			}
+			s.members = append(s.members, ecsMember{key: key, value: args[i+1]})
+			i++
+		default:
+			s.members = append(s.members, ecsMember{key: badKey, value: key})
+		}
+	}
+	for i := range r.Fields {
+		s.appendECSField("", r.Fields[i])
+	}
+	if remaining, ok := deadlineRemaining(r); ok {
+		s.members = append(s.members, ecsMember{key: "deadline_remaining", value: remaining.Round(time.Microsecond).String()})
+	}
+
+	errIndex := -1
+	for i := range s.members {
+		if slices.Contains(ecsErrorKeys, s.members[i].key) {
+			errIndex = i
+			break
+		}
+	}
+	if errIndex < 0 {
+		for i := range s.members {
+			if _, ok := s.members[i].val().(error); ok {
+				errIndex = i
+				break
+			}
+		}
+	}
+
+	var recordErr error
+	switch {
+	case errIndex >= 0:
+		m := &s.members[errIndex]
+		if err, ok := m.val().(error); ok {
+			m.key, m.isField, m.value = "error.message", false, err.Error()
+			s.members = append(s.members, ecsMember{key: "error.type", value: fmt.Sprintf("%T", err)})
+			recordErr = err
+		} else {
+			m.key = "error.message"
+		}
+	case !r.HasMsg && len(r.Args) > 0:
+		if err, ok := r.Args[0].(error); ok {
+			s.members = append(s.members,
+				ecsMember{key: "error.message", value: err.Error()},
+				ecsMember{key: "error.type", value: fmt.Sprintf("%T", err)})
+			errIndex = len(s.members) - 1
+			recordErr = err
+		}
+	}
+	if errIndex < 0 {
+		return
+	}
+	for i := range s.members {
+		if slices.Contains(ecsStackKeys, s.members[i].key) {
+			s.members[i].key = "error.stack_trace"
+			return
+		}
+	}
+	// Without a stack logged next to the error, take the one recorded by the error itself
+	if recordErr != nil {
+		if _, stack := errorDetail(recordErr); stack != "" {
+			s.members = append(s.members, ecsMember{key: "error.stack_trace", value: stack})
+		}
+	}
+}
+
+// appendECSField appends a typed field to s.members with its key prefixed by the names of its enclosing
+// groups, the fields of a group are nested under the group name like dotted keys
+func (s *serializer) appendECSField(prefix string, f Field) {
+	if f.kind == kindGroup {
+		for _, member := range f.any.([]Field) {
+			s.appendECSField(prefix+f.Key+".", member)
+		}
+		return
+	}
+	f.Key = prefix + f.Key
+	s.members = append(s.members, ecsMember{key: f.Key, field: f, isField: true})
+}
+
+// writeECSMembers writes the members with keys under prefix, nesting keys with further dots as objects
+// in the order of their first member. Without first, the members follow earlier ones.
+func (s *serializer) writeECSMembers(prefix string, first bool) {
+	for i := range s.members {
+		m := &s.members[i]
+		if m.done || !strings.HasPrefix(m.key, prefix) {
+			continue
+		}
+		if !first {
+			s.buf = append(s.buf, ',')
+		}
+		first = false
+
+		name, _, nested := strings.Cut(m.key[len(prefix):], ".")
+		s.writeJSONKeyString(name)
+		s.buf = append(s.buf, ':')
+		if nested {
+			s.buf = append(s.buf, '{')
+			s.writeECSMembers(m.key[:len(prefix)+len(name)+1], true)
+			s.buf = append(s.buf, '}')
+			continue
+		}
+		m.done = true
+		if m.isField {
+			s.writeJSONFieldValue(&m.field)
+		} else {
+			s.writeJSONValue(m.value)
+		}
+	}
+}
+
+// val returns the value of the member, boxing typed string values
+func (m *ecsMember) val() any {
+	if !m.isField {
+		return m.value
+	}
+	switch m.field.kind {
+	case kindString:
+		return m.field.str
+	case kindAny:
+		return m.field.any
+	}
+	return nil
+}
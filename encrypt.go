@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+)
+
+// encryptedFileMagic is written at the start of every encrypted log file.
+// Encrypted records consist of a 12-byte nonce followed by the AES-GCM ciphertext.
+const encryptedFileMagic = "LGE1"
+
+// Nonces are a random 8-byte prefix followed by a 4-byte big-endian record counter
+const (
+	noncePrefixSize = 8
+	nonceCounterMax = 1<<32 - 1
+)
+
+// Encryption key of the running config, the cipher is held by the encrypt stage
+var encryptionKey string
+
+// encryptStage seals each record with AES-GCM. Nonces count records under a random prefix, drawn again
+// when the counter is exhausted, so a key is not limited to the 2^32 records random 12-byte nonces allow.
+type encryptStage struct {
+	aead    cipher.AEAD
+	nonce   [12]byte
+	counter uint64 // records sealed under the current prefix
+}
+
+// EncryptStage returns a stage encrypting each record with AES-GCM, using a hex encoded
+// 16, 24 or 32 byte key (AES-128/192/256).
+func EncryptStage(hexKey string) (Stage, error) {
+	aead, err := newRecordCipher(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	if aead == nil {
+		return nil, fmt.Errorf("invalid encryption key: empty")
+	}
+	return &encryptStage{aead: aead}, nil
+}
+
+func (e *encryptStage) Magic() string { return encryptedFileMagic }
+
+func (e *encryptStage) Encode(dst, record []byte) ([]byte, error) {
+	if e.counter == 0 || e.counter > nonceCounterMax {
+		if _, err := rand.Read(e.nonce[:noncePrefixSize]); err != nil {
+			return nil, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		e.counter = 0
+	}
+	binary.BigEndian.PutUint32(e.nonce[noncePrefixSize:], uint32(e.counter))
+	e.counter++
+
+	dst = slices.Grow(dst, len(e.nonce)+len(record)+e.aead.Overhead())
+	dst = append(dst, e.nonce[:]...)
+	return e.aead.Seal(dst, e.nonce[:], record, nil), nil
+}
+
+func (e *encryptStage) Decode(dst, data []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(data) < nonceSize+e.aead.Overhead() {
+		return nil, fmt.Errorf("encrypted record too short")
+	}
+	plaintext, err := e.aead.Open(dst, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt record: %w", err)
+	}
+	return plaintext, nil
+}
+
+// newRecordCipher creates the AES-GCM cipher from a hex encoded 16, 24 or 32 byte key.
+// It returns nil if the key is empty.
+func newRecordCipher(hexKey string) (cipher.AEAD, error) {
+	if hexKey == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: must be hex encoded")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,22 @@
+package logger
+
+import "sync/atomic"
+
+// errorHandler receives internal errors the logger cannot report through the log itself
+var errorHandler atomic.Pointer[func(error)]
+
+// setErrorHandler sets the error handler, nil removes it
+func setErrorHandler(h func(error)) {
+	if h == nil {
+		errorHandler.Store(nil)
+		return
+	}
+	errorHandler.Store(&h)
+}
+
+// reportError passes an internal error to the error handler, if set
+func reportError(err error) {
+	if h := errorHandler.Load(); h != nil {
+		(*h)(err)
+	}
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the logger, to be tested with errors.Is. ErrInvalidConfig matches every error of
+// config validation, their messages name the invalid setting.
+var (
+	ErrNotInitialized = errors.New("logger not initialized")
+	ErrShutdown       = errors.New("logger is shutting down")
+	ErrDiskFull       = errors.New("disk full")
+	ErrInvalidConfig  = errors.New("invalid configuration")
+)
+
+// configError marks a config validation error, matching ErrInvalidConfig while keeping its message
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+func (e *configError) Unwrap() error {
+	return e.err
+}
+
+func (e *configError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// invalidConfig marks err as a config validation error, nil stays nil
+func invalidConfig(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &configError{err: err}
+}
+
+// StackTracer is implemented by errors recording the stack where they were created. With StructuredErrors,
+// the stack of the innermost error in the chain implementing it is written with the error.
+type StackTracer interface {
+	StackTrace() string
+}
+
+// structuredErrors writes error values in json formats as objects instead of their message
+var structuredErrors bool
+
+// maxErrorChain bounds the wrapped errors followed, guarding against cyclic Unwrap implementations
+const maxErrorChain = 32
+
+// errorDetail returns the messages of the errors wrapped by err, outermost first, and the stack of the
+// innermost StackTracer of the chain. The errors of a joined error are listed without their own causes.
+func errorDetail(err error) (causes []string, stack string) {
+	if st, ok := err.(StackTracer); ok {
+		stack = st.StackTrace()
+	}
+	for i := 0; i < maxErrorChain; i++ {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				if e != nil {
+					causes = append(causes, e.Error())
+				}
+			}
+			return causes, stack
+		}
+		if err = errors.Unwrap(err); err == nil {
+			return causes, stack
+		}
+		causes = append(causes, err.Error())
+		if st, ok := err.(StackTracer); ok {
+			stack = st.StackTrace()
+		}
+	}
+	return causes, stack
+}
+
+// writeJSONError writes an error as an object with its message, type, wrapped causes and stack
+func (s *serializer) writeJSONError(err error) {
+	causes, stack := errorDetail(err)
+
+	s.buf = append(s.buf, `{"message":`...)
+	s.writeJSONString(err.Error())
+	s.buf = append(s.buf, `,"type":`...)
+	s.writeJSONString(fmt.Sprintf("%T", err))
+	if len(causes) > 0 {
+		s.buf = append(s.buf, `,"causes":[`...)
+		for i, c := range causes {
+			if i > 0 {
+				s.buf = append(s.buf, ',')
+			}
+			s.writeJSONString(c)
+		}
+		s.buf = append(s.buf, ']')
+	}
+	if stack != "" {
+		s.buf = append(s.buf, `,"stack":`...)
+		s.writeJSONString(stack)
+	}
+	s.buf = append(s.buf, '}')
+}
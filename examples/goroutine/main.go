@@ -14,6 +14,8 @@ func main() {
 		Level:     logger.LevelDebug,
 		Directory: "./logs",
 		Format:    "json",
+		// Shows how far each record is from its context deadline, negative once exceeded
+		ShowDeadline: true,
 	}
 
 	if err := logger.Init(ctx, cfg); err != nil {
@@ -34,4 +36,4 @@ func main() {
 		}(i)
 	}
 
-}
\ No newline at end of file
+}
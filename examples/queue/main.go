@@ -0,0 +1,85 @@
+// queue: the program compares the channel and ring queues with many concurrent producers
+// Run with: go run ./examples/queue
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+const (
+	producers   = 1000
+	perProducer = 1000
+)
+
+func main() {
+	ctx := context.Background()
+	for _, queueType := range []string{logger.QueueChannel, logger.QueueRing} {
+		for _, policy := range []string{logger.OverflowDrop, logger.OverflowBlock} {
+			run(ctx, queueType, policy)
+		}
+	}
+	os.RemoveAll("./logs")
+}
+
+// run logs producers*perProducer records from concurrent goroutines and reports the rate seen by the
+// producers, the time until the records are on disk and the records dropped
+func run(ctx context.Context, queueType, policy string) {
+	os.RemoveAll("./logs")
+	cfg := &logger.LoggerConfig{
+		Name:           "queue",
+		Directory:      "./logs",
+		BufferSize:     8192,
+		MaxSizeMB:      100,
+		MaxTotalSizeMB: 0,
+		MinDiskFreeMB:  0,
+	}
+	cfg.MarkSet("max_total_size_mb", "min_disk_free_mb")
+	err := logger.Init(ctx, cfg, logger.WithQueueType(queueType), logger.WithOverflowPolicy(policy, 0))
+	if err != nil {
+		panic(err)
+	}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				logger.InfoFields(ctx, "record", logger.Int("producer", p), logger.Int("i", i))
+			}
+		}(p)
+	}
+	wg.Wait()
+	logged := time.Since(start)
+	logger.Flush(ctx)
+	written := time.Since(start)
+
+	logger.Shutdown(ctx)
+
+	total := producers * perProducer
+	fmt.Printf("%-8s %-6s logged %9.0f rec/s  written in %-8v lines %d/%d\n",
+		queueType, policy, float64(total)/logged.Seconds(), written.Round(time.Millisecond), countLines(), total)
+}
+
+// countLines returns the number of lines in the log files, including markers of dropped records
+func countLines() int {
+	files, _ := filepath.Glob("./logs/queue_*.log")
+	n := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		n += bytes.Count(data, []byte{'\n'})
+	}
+	return n
+}
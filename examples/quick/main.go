@@ -4,7 +4,6 @@ package main
 import (
 	"fmt"
 	"github.com/LixenWraith/logger/quick"
-	"os"
 )
 
 type customError struct {
@@ -31,8 +30,6 @@ func main() {
 		"success", true,
 		"records", 42)
 
-	// Wait for ticker to write
-	quick.Shutdown()
-	// Let the finalizer handle cleanup
-	os.Exit(0)
+	// Write the queued records before exiting, finalizers don't run on exit
+	quick.Exit(0)
 }
\ No newline at end of file
@@ -69,11 +69,8 @@ func main() {
 			"version": "1.0.0",
 		})
 
-	quick.Shutdown()
-	time.Sleep(1 * time.Second)
-
-	// Force immediate exit to test finalizer
-	os.Exit(0)
+	// Write the queued records before exiting, finalizers don't run on exit
+	quick.Exit(0)
 }
 
 type customError struct {
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"math"
+	"sync"
+)
+
+// maxInlineFields is the number of typed fields stored in the pooled array of a log record.
+// Records with up to this many fields are queued without any heap allocation.
+const maxInlineFields = 8
+
+// fieldArrays pools the field arrays of queued records, returned once the processor wrote the record
+var fieldArrays = sync.Pool{New: func() any { return new([maxInlineFields]Field) }}
+
+// getFields returns an empty slice backed by a pooled array of maxInlineFields fields
+func getFields() []Field {
+	return fieldArrays.Get().(*[maxInlineFields]Field)[:0]
+}
+
+// releaseFields returns the field array of a record written or not queued to the pool, clearing the values it
+// references. Records suppressed by deduplication keep their array.
+func releaseFields(record *logRecord) {
+	if cap(record.Fields) != maxInlineFields {
+		return
+	}
+	fields := (*[maxInlineFields]Field)(record.Fields[:maxInlineFields])
+	clear(fields[:])
+	fieldArrays.Put(fields)
+	record.Fields = nil
+}
+
+// fieldKind identifies the type of value held by a Field.
+type fieldKind uint8
+
+const (
+	kindAny fieldKind = iota
+	kindString
+	kindInt64
+	kindUint64
+	kindFloat64
+	kindBool
+	kindGroup
+)
+
+// Field is a typed key/value pair used by the *Fields logging functions.
+// Primitive values are stored without interface boxing, so building and queuing
+// fields does not allocate. Fields may also be passed to the variadic `any` API,
+// in which case they are written as a key followed by its value.
+type Field struct {
+	Key  string
+	kind fieldKind
+	num  uint64
+	str  string
+	any  any
+}
+
+// Str creates a string field.
+func Str(key, value string) Field {
+	return Field{Key: key, kind: kindString, str: value}
+}
+
+// Int creates an int field.
+func Int(key string, value int) Field {
+	return Field{Key: key, kind: kindInt64, num: uint64(value)}
+}
+
+// Int64 creates an int64 field.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, kind: kindInt64, num: uint64(value)}
+}
+
+// Uint64 creates an uint64 field.
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, kind: kindUint64, num: value}
+}
+
+// Float64 creates a float64 field.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, kind: kindFloat64, num: math.Float64bits(value)}
+}
+
+// Bool creates a bool field.
+func Bool(key string, value bool) Field {
+	var num uint64
+	if value {
+		num = 1
+	}
+	return Field{Key: key, kind: kindBool, num: num}
+}
+
+// Any creates a field holding an arbitrary value, serialized like a variadic argument.
+// Unlike the typed constructors, boxing the value may allocate.
+func Any(key string, value any) Field {
+	return Field{Key: key, kind: kindAny, any: value}
+}
+
+// Group creates a field nesting the pairs of args under name, written as {"http":{"method":"GET"}} in json
+// and as http.method GET in txt. Args pair up as key and value like the variadic API and a Field is a pair
+// on its own, so groups nest.
+func Group(name string, args ...any) Field {
+	return Field{Key: name, kind: kindGroup, any: argFields(args)}
+}
+
+// argFields converts args pairing up as key and value, or a Field on its own, to fields
+func argFields(args []any) []Field {
+	fields := make([]Field, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch key := args[i].(type) {
+		case Field:
+			fields = append(fields, key)
+		case string:
+			if i+1 == len(args) {
+				fields = append(fields, Str(badKey, key))
+				break
+			}
+			fields = append(fields, Any(key, args[i+1]))
+			i++
+		default:
+			fields = append(fields, Any(badKey, key))
+		}
+	}
+	return fields
+}
+
+// Value returns the field value as an interface, boxing primitive values.
+// The value of a group is its fields as a []Field.
+func (f Field) Value() any {
+	switch f.kind {
+	case kindString:
+		return f.str
+	case kindInt64:
+		return int64(f.num)
+	case kindUint64:
+		return f.num
+	case kindFloat64:
+		return math.Float64frombits(f.num)
+	case kindBool:
+		return f.num == 1
+	case kindGroup:
+		return f.any.([]Field)
+	default:
+		return f.any
+	}
+}
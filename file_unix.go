@@ -0,0 +1,58 @@
+//go:build !windows
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// openLogFile creates a new log file for appending, failing if it exists
+func openLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+}
+
+// lockShared takes a shared advisory lock on f, waiting for a probing exclusive lock to be released
+func lockShared(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_SH)
+}
+
+// tryLockExclusive takes an exclusive advisory lock on f without waiting, reporting false if it is
+// held by another open file, in this or another process
+func tryLockExclusive(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlockFile releases an advisory lock on f, closing f releases it as well
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// removeLogFile deletes a log file. Open files can be removed, readers keep their handle.
+func removeLogFile(path string) error {
+	return os.Remove(path)
+}
+
+// getDiskFreeSpace returns the space available to the process on the file system of path, in bytes.
+func getDiskFreeSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	// Available blocks * block size
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+// mapFile maps the first size bytes of f into memory, shared with the file so writes survive the process
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// unmapFile releases a mapping of mapFile
+func unmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
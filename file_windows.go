@@ -0,0 +1,139 @@
+//go:build windows
+
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Windows error codes not defined by the syscall package
+const (
+	errorSharingViolation syscall.Errno = 32 // file opened by another process without delete sharing
+	errorLockViolation    syscall.Errno = 33 // byte range locked by another handle
+)
+
+// LockFileEx flags
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+)
+
+// Kernel32 functions the syscall package does not wrap, resolved lazily
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	getDiskFreeSpaceEx = kernel32.NewProc("GetDiskFreeSpaceExW")
+	lockFileEx         = kernel32.NewProc("LockFileEx")
+	unlockFileEx       = kernel32.NewProc("UnlockFileEx")
+)
+
+// openLogFile creates a new log file for appending, failing if it exists. Unlike os.OpenFile, the file
+// is shared for deletion as well as reading and writing, so readers tailing it don't block rotation and
+// cleanup, and it can be removed by cleanup in other processes while open.
+func openLogFile(path string) (*os.File, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(p,
+		syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil,
+		syscall.CREATE_NEW,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
+
+// Byte range locks are mandatory on Windows, so advisory locks cover a single byte at the end of the
+// offset range where no data is ever written
+const (
+	lockOffsetLow  = 0xFFFFFFFE
+	lockOffsetHigh = 0xFFFFFFFF
+)
+
+// lockFile locks the advisory byte of f with LockFileEx flags
+func lockFile(f *os.File, flags uintptr) error {
+	ol := syscall.Overlapped{Offset: lockOffsetLow, OffsetHigh: lockOffsetHigh}
+	if r, _, err := lockFileEx.Call(f.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(&ol))); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// lockShared takes a shared advisory lock on f, waiting for a probing exclusive lock to be released
+func lockShared(f *os.File) error {
+	return lockFile(f, 0)
+}
+
+// tryLockExclusive takes an exclusive advisory lock on f without waiting, reporting false if it is
+// held by another handle, in this or another process
+func tryLockExclusive(f *os.File) (bool, error) {
+	err := lockFile(f, lockfileExclusiveLock|lockfileFailImmediately)
+	var errno syscall.Errno
+	if errors.As(err, &errno) && errno == errorLockViolation {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlockFile releases an advisory lock on f, closing f releases it as well
+func unlockFile(f *os.File) error {
+	ol := syscall.Overlapped{Offset: lockOffsetLow, OffsetHigh: lockOffsetHigh}
+	if r, _, err := unlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&ol))); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// removeLogFile deletes a log file. Files opened by another process without delete sharing, e.g. by an
+// editor, cannot be deleted on Windows and report errFileInUse.
+func removeLogFile(path string) error {
+	err := os.Remove(path)
+	var errno syscall.Errno
+	if errors.As(err, &errno) && (errno == errorSharingViolation || errno == syscall.ERROR_ACCESS_DENIED) {
+		return fmt.Errorf("%w: %s", errFileInUse, path)
+	}
+	return err
+}
+
+// getDiskFreeSpace returns the space available to the process on the volume of path, in bytes.
+func getDiskFreeSpace(path string) (int64, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	if r, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&available)), 0, 0); r == 0 {
+		return 0, err
+	}
+	return int64(available), nil
+}
+
+// mapFile maps the first size bytes of f into memory, shared with the file so writes survive the process.
+// The view keeps the mapping object alive after its handle is closed.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	h, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READWRITE,
+		uint32(uint64(size)>>32), uint32(size), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(h)
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_WRITE, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, err
+	}
+	// The view is not Go memory, its address is reinterpreted as a pointer without a uintptr conversion
+	return unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), size), nil
+}
+
+// unmapFile releases a mapping of mapFile
+func unmapFile(data []byte) error {
+	return syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(unsafe.SliceData(data))))
+}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// File header and footer events in the "event" field
+const (
+	fileHeaderEvent = "file_header"
+	fileFooterEvent = "file_footer"
+)
+
+// File header state. fileOpened and fileRecords describe the active file, they are only accessed by the
+// processor goroutine and during file close.
+var (
+	fileHeaders  bool
+	headerConfig string // running config as JSON, without secrets
+	fileOpened   time.Time
+	fileRecords  uint64 // records written to the active file
+)
+
+// snapshotConfig returns the config written to file headers, with the encryption key and admin token removed
+func snapshotConfig(cfg *LoggerConfig) string {
+	snapshot := *cfg
+	if snapshot.EncryptionKey != "" {
+		snapshot.EncryptionKey = "redacted"
+	}
+	if snapshot.AdminToken != "" {
+		snapshot.AdminToken = "redacted"
+	}
+	data, err := json.Marshal(&snapshot)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// writeFileHeader writes the header record opening a new log file, regardless of the level:
+//
+//	event=file_header start_time=T host=H pid=P logger_version=V schema_version=S previous_file=F config=C
+//
+// Host, pid and versions already written to every record as metadata are left out. previous_file names
+// the file the logger wrote before, if any.
+func writeFileHeader(f *os.File, previous string) {
+	fileOpened, fileRecords = clockNow(), 0
+	if !fileHeaders {
+		return
+	}
+
+	args := []any{"event", fileHeaderEvent, "start_time", startedAt.Format(time.RFC3339Nano)}
+	if !includeHost {
+		if hostname, err := os.Hostname(); err == nil {
+			args = append(args, "host", hostname)
+		}
+	}
+	if !includePID {
+		args = append(args, "pid", os.Getpid())
+	}
+	if !includeSchema {
+		args = append(args, "logger_version", moduleVersion(), "schema_version", SchemaVersion)
+	}
+	if previous != "" {
+		args = append(args, "previous_file", previous)
+	}
+	args = append(args, "config", headerConfig)
+
+	if err := writeFileMark(f, "Log file opened", args); err != nil {
+		reportError(fmt.Errorf("failed to write file header: %w", err))
+	}
+}
+
+// writeFileFooter writes the footer record closing a log file on rotation or shutdown:
+//
+//	event=file_footer records=R bytes=B duration_ms=D next_file=F
+//
+// records and bytes count what the file holds before the footer, next_file names the file written
+// next on rotation. Files closed by a failure, e.g. a crash or a failed write, have no footer.
+func writeFileFooter(f *os.File, next string) error {
+	if !fileHeaders || f == nil {
+		return nil
+	}
+	args := []any{
+		"event", fileFooterEvent,
+		"records", int64(fileRecords),
+		"bytes", currentSize.Load(),
+		"duration_ms", clockNow().Sub(fileOpened).Milliseconds(),
+	}
+	if next != "" {
+		args = append(args, "next_file", next)
+	}
+	return writeFileMark(f, "Log file closed", args)
+}
+
+// writeFileMark serializes a header or footer record and writes it to f directly, bypassing the coalesced
+// write, sinks and the console. It is part of the audit chain, checksummed and encoded by the pipeline like
+// any record.
+func writeFileMark(f *os.File, msg string, args []any) error {
+	record := logRecord{
+		LogCtx:    context.Background(),
+		Flags:     flags,
+		TimeStamp: clockNow(),
+		Level:     LevelInfo,
+		Args:      append([]any{msg}, args...),
+	}
+
+	s := newSerializer()
+	s.serialize(&record)
+	_, data, err := s.finishRecord()
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	bytesWritten.Add(uint64(len(data)))
+	recordsWritten.Add(1)
+	currentSize.Add(int64(len(data)))
+	fileRecords++
+	return nil
+}
@@ -1,19 +1,45 @@
 package logger
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Log format variables
 var (
-	format string
+	format     string
+	jsonIndent bool // json and ecs records are indented, see indentJSON
+	foldLines  bool // newlines of txt and console records are written as continuation lines, see writeString
 )
 
+// foldIndent starts the continuation lines of a folded string
+const foldIndent = "    "
+
+// badKey is the JSON key of values logged without a string key, following the slog convention
+const badKey = "!BADKEY"
+
 // serializer manages the buffered writing of log entries in different formats
 type serializer struct {
-	buf []byte
+	buf       []byte
+	staged    [2][]byte    // pipeline stage buffers
+	members   []ecsMember  // ecs members being nested
+	colored   []byte       // colored console rendering of the console format
+	timeWidth int          // widest time stamp written in the console format
+	packed    []byte       // msgpack encoding of the record
+	unchained []byte       // record before the audit hash, chained again after a rotation
+	composite bytes.Buffer // encoding/json output of composite values
+
+	// Truncation state of a record exceeding MaxRecordBytes, strings are cut to stringLimit bytes
+	stringLimit   int
+	truncatedFrom int // serialized size before truncation, 0 if not truncated
 }
 
 // newSerializer creates a serializer instance to be used by processor
@@ -29,101 +55,458 @@ func (s *serializer) reset() {
 }
 
 // serialize converts log entries to either JSON or text format based on configuration
-func (s *serializer) serialize(flags int64, timestamp time.Time, level int64, trace string, args []any) []byte {
+func (s *serializer) serialize(r *logRecord) []byte {
 	s.reset()
 
-	if format == "json" {
-		return s.serializeJSON(flags, timestamp, level, trace, args)
+	// Lines of WriteRaw are already serialized
+	if r.Raw != nil {
+		s.buf = append(s.buf, r.Raw...)
+		return s.buf
+	}
+
+	line := s.serializeLimited(r)
+	if format == "json" || format == "ecs" {
+		return s.indentJSON(line)
+	}
+	return line
+}
+
+// serializeFormat serializes a record in the configured format, without indentation
+func (s *serializer) serializeFormat(r *logRecord) []byte {
+	switch format {
+	case "json":
+		return s.serializeJSON(r)
+	case "ecs":
+		return s.serializeECS(r)
+	case FormatConsole:
+		return s.serializeConsole(r, false)
+	case FormatMsgpack:
+		// Serialized as json and packed for the file in writeSerialized, the console, sinks and
+		// recent records receive the json line
+		return s.serializeJSON(r)
 	}
-	return s.serializeText(flags, timestamp, level, trace, args)
+	return s.serializeText(r)
+}
+
+// indentJSON rewrites a serialized JSON record indented over multiple lines if jsonIndent is set.
+// It reparses every record and is meant for reading records during development, not for throughput.
+func (s *serializer) indentJSON(line []byte) []byte {
+	if !jsonIndent {
+		return line
+	}
+	var b bytes.Buffer
+	if err := json.Indent(&b, bytes.TrimSuffix(line, []byte{'\n'}), "", "  "); err != nil {
+		return line
+	}
+	s.buf = append(append(s.buf[:0], b.Bytes()...), '\n')
+	return s.buf
+}
+
+// isValidFormat reports whether f is a supported output format
+func isValidFormat(f string) bool {
+	return f == "txt" || f == FormatConsole || isJSONFormat(f)
+}
+
+// isJSONFormat reports whether records of the format are serialized as one JSON object per line,
+// packed before writing with the msgpack format
+func isJSONFormat(f string) bool {
+	return f == "json" || f == "ecs" || f == FormatMsgpack
+}
+
+// valueCount returns the number of positional values written for the record:
+// variadic args, the typed message and a key and a value for each typed field.
+func valueCount(r *logRecord) int {
+	n := len(r.Args) + 2*len(r.Fields)
+	if r.HasMsg {
+		n++
+	}
+	return n
 }
 
 // serializeJSON formats log entries as JSON with time, level and fields
-func (s *serializer) serializeJSON(flags int64, timestamp time.Time, level int64, trace string, args []any) []byte {
+func (s *serializer) serializeJSON(r *logRecord) []byte {
 	s.buf = append(s.buf, '{')
 
 	// Time is always first when enabled
-	if flags&FlagShowTimestamp != 0 {
-		s.buf = append(s.buf, `"time":"`...)
-		s.buf = append(s.buf, timestamp.Format(time.RFC3339Nano)...)
-		s.buf = append(s.buf, '"')
-
-		if flags&FlagShowLevel != 0 || trace != "" || len(args) > 0 {
-			s.buf = append(s.buf, ',')
-		}
+	if r.Flags&FlagShowTimestamp != 0 {
+		s.writeJSONKey("time")
+		s.writeTimestamp(r.TimeStamp, true)
 	}
 
 	// Level is after timestamp when enabled
-	if flags&FlagShowLevel != 0 {
-		s.buf = append(s.buf, `"level":"`...)
-		s.buf = append(s.buf, levelToString(level)...)
-		s.buf = append(s.buf, '"')
+	if r.Flags&FlagShowLevel != 0 {
+		s.writeJSONKey("level")
+		s.writeJSONString(levelToString(r.Level))
+	}
 
-		// Add comma if there are more fields
-		if trace != "" || len(args) > 0 {
-			s.buf = append(s.buf, ',')
-		}
+	// Sequence number is after level when enabled
+	if r.Seq != 0 {
+		s.writeJSONKey("seq")
+		s.buf = strconv.AppendUint(s.buf, r.Seq, 10)
+	}
+
+	// Host, process id and static fields are after level
+	for i := range metaFields {
+		s.writeJSONKey(metaFields[i].Key)
+		s.writeJSONFieldValue(&metaFields[i])
+	}
+
+	// Goroutine id and worker label of the logging call
+	if r.Goroutine != 0 {
+		s.writeJSONKey("goroutine")
+		s.buf = strconv.AppendUint(s.buf, r.Goroutine, 10)
+	}
+	if worker := recordWorker(r); worker != "" {
+		s.writeJSONKey("worker")
+		s.writeJSONString(worker)
+	}
+
+	// Truncation marker of a record exceeding MaxRecordBytes
+	s.writeJSONTruncation()
+
+	// Trace is after level when enabled, as an array of frames with StructuredTrace
+	if len(r.Frames) > 0 {
+		s.writeJSONKey("trace")
+		s.writeTraceFrames(r.Frames)
+	} else if r.Trace != "" {
+		s.writeJSONKey("trace")
+		s.writeJSONString(r.Trace)
 	}
 
-	// Trace is after level when enabled
-	if trace != "" {
-		s.buf = append(s.buf, `"trace":"`...)
-		s.buf = append(s.buf, trace...)
+	// Remaining time until the caller context deadline
+	if remaining, ok := deadlineRemaining(r); ok {
+		s.writeJSONKey("deadline_remaining")
+		s.buf = append(s.buf, '"')
+		s.buf = appendDuration(s.buf, remaining)
 		s.buf = append(s.buf, '"')
+	}
+
+	// Message and key/value fields are last
+	s.writeJSONFields(r)
 
-		if len(args) > 0 {
+	s.buf = append(s.buf, '}', '\n')
+	return s.buf
+}
+
+// writeJSONKey writes an object key, preceded by a comma unless it is the first member of the record
+func (s *serializer) writeJSONKey(key string) {
+	if len(s.buf) > 1 {
+		s.buf = append(s.buf, ',')
+	}
+	s.writeJSONKeyString(key)
+	s.buf = append(s.buf, ':')
+}
+
+// writeJSONKeyString writes a quoted and escaped object key. Keys are never cut by the record size limit,
+// so a truncated record keeps the keys consumers expect.
+func (s *serializer) writeJSONKeyString(key string) {
+	s.buf = append(s.buf, '"')
+	s.writeKey(key)
+	s.buf = append(s.buf, '"')
+}
+
+// writeKey writes a key escaped like writeString, without the string limit of a truncated record
+func (s *serializer) writeKey(key string) {
+	limit := s.stringLimit
+	s.stringLimit = 0
+	s.writeString(key)
+	s.stringLimit = limit
+}
+
+// writeJSONFields writes the message as "msg" and the key/value pairs as members of a "fields" object.
+// The message is the typed message, or else the first arg unless that is a Field. The remaining args pair
+// up as key and value, a Field is a complete pair on its own and values without a string key are written
+// under badKey.
+func (s *serializer) writeJSONFields(r *logRecord) {
+	msg, hasMsg, args := splitMessage(r)
+	if hasMsg {
+		s.writeJSONKey("msg")
+		s.writeJSONString(msg)
+	}
+	if len(args) == 0 && len(r.Fields) == 0 {
+		return
+	}
+
+	s.writeJSONKey("fields")
+	s.buf = append(s.buf, '{')
+	start := len(s.buf)
+	for i := 0; i < len(args); i++ {
+		if len(s.buf) > start {
+			s.buf = append(s.buf, ',')
+		}
+		switch key := args[i].(type) {
+		case Field:
+			s.writeJSONField(&key)
+		case string:
+			if i+1 == len(args) {
+				// Dangling key without a value
+				s.writeJSONKeyString(badKey)
+				s.buf = append(s.buf, ':')
+				s.writeJSONString(key)
+				break
+			}
+			s.writeJSONKeyString(key)
+			s.buf = append(s.buf, ':')
+			s.writeJSONValue(args[i+1])
+			i++
+		default:
+			s.writeJSONKeyString(badKey)
+			s.buf = append(s.buf, ':')
+			s.writeJSONValue(key)
+		}
+	}
+	for i := range r.Fields {
+		if len(s.buf) > start {
 			s.buf = append(s.buf, ',')
 		}
+		s.writeJSONField(&r.Fields[i])
 	}
+	s.buf = append(s.buf, '}')
+}
 
-	// Fields as ordered array is after trace
-	if len(args) > 0 {
-		s.buf = append(s.buf, `"fields":[`...)
-		for i, arg := range args {
-			if i > 0 {
-				s.buf = append(s.buf, ',')
-			}
-			s.writeJSONValue(arg)
+// splitMessage returns the message of the record, the typed message or else the first arg unless that is
+// a Field, and the args remaining as key/value pairs
+func splitMessage(r *logRecord) (msg string, ok bool, args []any) {
+	if r.HasMsg {
+		return r.Msg, true, r.Args
+	}
+	if len(r.Args) > 0 {
+		if _, isField := r.Args[0].(Field); !isField {
+			return stringifyMessage(r.Args[0]), true, r.Args[1:]
 		}
-		s.buf = append(s.buf, ']')
 	}
+	return "", false, r.Args
+}
 
-	s.buf = append(s.buf, '}', '\n')
-	return s.buf
+// deadlineRemaining returns the time left until the record context deadline at the time of logging,
+// negative if the deadline was already exceeded. It reports false if not enabled or there is no deadline.
+func deadlineRemaining(r *logRecord) (time.Duration, bool) {
+	if r.Flags&FlagShowDeadline == 0 || r.LogCtx == nil {
+		return 0, false
+	}
+	deadline, ok := r.LogCtx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return deadline.Sub(r.TimeStamp), true
+}
+
+// appendDuration appends the duration in its String form, e.g. 1.5s or -120ms
+func appendDuration(buf []byte, d time.Duration) []byte {
+	// Round to microseconds to keep the output short
+	return append(buf, d.Round(time.Microsecond).String()...)
 }
 
 // serializeText formats log entries as plain text with time, level and space-separated fields
-func (s *serializer) serializeText(flags int64, timestamp time.Time, level int64, trace string, args []any) []byte {
+func (s *serializer) serializeText(r *logRecord) []byte {
 	// Time stamp if enabled
-	if flags&FlagShowTimestamp != 0 {
-		s.buf = append(s.buf, timestamp.Format(time.RFC3339Nano)...)
+	if r.Flags&FlagShowTimestamp != 0 {
+		s.writeTimestamp(r.TimeStamp, false)
 		s.buf = append(s.buf, ' ')
 	}
 
 	// Level in uppercase if enabled
-	if flags&FlagShowLevel != 0 {
-		s.buf = append(s.buf, levelToString(level)...)
+	if r.Flags&FlagShowLevel != 0 {
+		s.buf = append(s.buf, levelToString(r.Level)...)
+		s.buf = append(s.buf, ' ')
+	}
+
+	// Sequence number if enabled
+	if r.Seq != 0 {
+		s.buf = append(s.buf, "seq="...)
+		s.buf = strconv.AppendUint(s.buf, r.Seq, 10)
+		s.buf = append(s.buf, ' ')
+	}
+
+	// Host, process id and static fields as key=value
+	for i := range metaFields {
+		s.buf = append(s.buf, metaFields[i].Key...)
+		s.buf = append(s.buf, '=')
+		switch metaFields[i].kind {
+		case kindString:
+			s.writeTextString(metaFields[i].str)
+		default:
+			s.writeFieldNumber(&metaFields[i])
+		}
+		s.buf = append(s.buf, ' ')
+	}
+
+	// Goroutine id and worker label of the logging call
+	if r.Goroutine != 0 {
+		s.buf = append(s.buf, "goroutine="...)
+		s.buf = strconv.AppendUint(s.buf, r.Goroutine, 10)
+		s.buf = append(s.buf, ' ')
+	}
+	if worker := recordWorker(r); worker != "" {
+		s.buf = append(s.buf, "worker="...)
+		s.writeTextString(worker)
+		s.buf = append(s.buf, ' ')
+	}
+
+	// Truncation marker of a record exceeding MaxRecordBytes
+	if s.truncatedFrom != 0 {
+		s.buf = append(s.buf, "truncated=true original_bytes="...)
+		s.buf = strconv.AppendInt(s.buf, int64(s.truncatedFrom), 10)
 		s.buf = append(s.buf, ' ')
 	}
 
 	// Trace if not empty
-	if trace != "" {
-		s.buf = append(s.buf, trace...)
+	if r.Trace != "" {
+		s.buf = append(s.buf, r.Trace...)
+		s.buf = append(s.buf, ' ')
+	}
+
+	// Remaining time until the caller context deadline
+	if remaining, ok := deadlineRemaining(r); ok {
+		s.buf = append(s.buf, "deadline_remaining="...)
+		s.buf = appendDuration(s.buf, remaining)
 		s.buf = append(s.buf, ' ')
 	}
 
 	// Fields as space-separated values
-	for i, arg := range args {
+	for i, arg := range r.Args {
 		if i > 0 {
 			s.buf = append(s.buf, ' ')
 		}
 		s.writeTextValue(arg)
 	}
+	sep := len(r.Args) > 0
+	if r.HasMsg {
+		if sep {
+			s.buf = append(s.buf, ' ')
+		}
+		s.writeTextString(r.Msg)
+		sep = true
+	}
+	for i := range r.Fields {
+		if sep {
+			s.buf = append(s.buf, ' ')
+		}
+		s.writeTextField(&r.Fields[i])
+		sep = true
+	}
 
 	s.buf = append(s.buf, '\n')
 	return s.buf
 }
 
+// writeTextString writes a string in text format, quoting it if needed
+func (s *serializer) writeTextString(str string) {
+	if needsQuotes(str) {
+		s.buf = append(s.buf, '"')
+		s.writeString(str)
+		s.buf = append(s.buf, '"')
+	} else {
+		s.writeString(str)
+	}
+}
+
+// writeJSONString writes a quoted and escaped JSON string
+func (s *serializer) writeJSONString(str string) {
+	s.buf = append(s.buf, '"')
+	s.writeString(str)
+	s.buf = append(s.buf, '"')
+}
+
+// writeTextField writes a typed field as its key followed by its value without boxing.
+// The fields of a group are written as pairs of their own, their keys prefixed by the group name and a dot.
+func (s *serializer) writeTextField(f *Field) {
+	s.writeTextPrefixedField("", f)
+}
+
+// writeTextPrefixedField writes a typed field with its key prefixed by the names of its enclosing groups
+func (s *serializer) writeTextPrefixedField(prefix string, f *Field) {
+	if f.kind == kindGroup {
+		for i, member := range f.any.([]Field) {
+			if i > 0 {
+				s.buf = append(s.buf, ' ')
+			}
+			s.writeTextPrefixedField(prefix+f.Key+".", &member)
+		}
+		return
+	}
+	key := prefix + f.Key
+	if needsQuotes(key) {
+		s.buf = append(s.buf, '"')
+		s.writeKey(key)
+		s.buf = append(s.buf, '"')
+	} else {
+		s.writeKey(key)
+	}
+	s.buf = append(s.buf, ' ')
+	s.writeTextFieldValue(f)
+}
+
+// writeTextFieldValue writes the value of a typed field in text form
+func (s *serializer) writeTextFieldValue(f *Field) {
+	switch f.kind {
+	case kindString:
+		s.writeTextString(f.str)
+	case kindAny:
+		s.writeTextValue(f.any)
+	case kindGroup:
+		s.writeTextValue(*f)
+	default:
+		s.writeFieldNumber(f)
+	}
+}
+
+// writeJSONField writes a typed field as an object member without boxing
+func (s *serializer) writeJSONField(f *Field) {
+	s.writeJSONKeyString(f.Key)
+	s.buf = append(s.buf, ':')
+	s.writeJSONFieldValue(f)
+}
+
+// writeJSONFieldValue writes the value of a typed field without boxing
+func (s *serializer) writeJSONFieldValue(f *Field) {
+	switch f.kind {
+	case kindString:
+		s.writeJSONString(f.str)
+	case kindAny:
+		s.writeJSONValue(f.any)
+	case kindGroup:
+		s.buf = append(s.buf, '{')
+		for i, member := range f.any.([]Field) {
+			if i > 0 {
+				s.buf = append(s.buf, ',')
+			}
+			s.writeJSONField(&member)
+		}
+		s.buf = append(s.buf, '}')
+	case kindFloat64:
+		s.writeJSONFloat(math.Float64frombits(f.num), 64)
+	default:
+		s.writeFieldNumber(f)
+	}
+}
+
+// writeJSONFloat writes a float of bitSize 32 or 64 as a JSON number. NaN and infinities have no JSON number,
+// they are written as the strings "NaN", "+Inf" and "-Inf".
+func (s *serializer) writeJSONFloat(f float64, bitSize int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		s.buf = append(s.buf, '"')
+		s.buf = strconv.AppendFloat(s.buf, f, 'f', -1, bitSize)
+		s.buf = append(s.buf, '"')
+		return
+	}
+	s.buf = strconv.AppendFloat(s.buf, f, 'f', -1, bitSize)
+}
+
+// writeFieldNumber writes numeric and bool field values, identical in both formats apart from non-finite floats
+func (s *serializer) writeFieldNumber(f *Field) {
+	switch f.kind {
+	case kindInt64:
+		s.buf = strconv.AppendInt(s.buf, int64(f.num), 10)
+	case kindUint64:
+		s.buf = strconv.AppendUint(s.buf, f.num, 10)
+	case kindFloat64:
+		s.buf = strconv.AppendFloat(s.buf, math.Float64frombits(f.num), 'f', -1, 64)
+	case kindBool:
+		s.buf = strconv.AppendBool(s.buf, f.num == 1)
+	}
+}
+
 // writeTextValue converts any value to its text representation with appropriate quoting
 func (s *serializer) writeTextValue(v any) {
 	switch val := v.(type) {
@@ -145,6 +528,18 @@ func (s *serializer) writeTextValue(v any) {
 		s.buf = strconv.AppendBool(s.buf, val)
 	case nil:
 		s.buf = append(s.buf, "null"...)
+	case Field:
+		s.writeTextField(&val)
+	case time.Time:
+		s.writeTimeValue(val, false)
+	case time.Duration:
+		s.writeDurationValue(val, false)
+	case encoding.TextMarshaler:
+		if text, ok := marshalText(val); ok {
+			s.writeTextString(string(text))
+			break
+		}
+		s.writeTextString(fmt.Sprintf("%+v", val))
 	default:
 		str := stringifyMessage(val)
 		if needsQuotes(str) {
@@ -169,45 +564,147 @@ func (s *serializer) writeJSONValue(v any) {
 	case int64:
 		s.buf = strconv.AppendInt(s.buf, val, 10)
 	case float64:
-		s.buf = strconv.AppendFloat(s.buf, val, 'f', -1, 64)
+		s.writeJSONFloat(val, 64)
 	case bool:
 		s.buf = strconv.AppendBool(s.buf, val)
 	case nil:
 		s.buf = append(s.buf, "null"...)
+	case Field:
+		// A field used as a value is written as an object of its own
+		s.buf = append(s.buf, '{')
+		s.writeJSONField(&val)
+		s.buf = append(s.buf, '}')
+	case time.Time:
+		s.writeTimeValue(val, true)
+	case time.Duration:
+		s.writeDurationValue(val, true)
+	case json.Marshaler, encoding.TextMarshaler:
+		// Encoded as encoding/json does, a text marshaler becomes a string
+		if s.writeJSONEncoded(val) {
+			break
+		}
+		s.writeJSONString(fmt.Sprintf("%+v", val))
+	case error:
+		if !structuredErrors {
+			s.writeJSONString(val.Error())
+			break
+		}
+		s.writeJSONError(val)
+	case float32:
+		s.writeJSONFloat(float64(val), 32)
+	case int8, int16, int32, uint, uint8, uint16, uint32, uint64:
+		s.buf = fmt.Append(s.buf, val)
+	case fmt.Stringer:
+		s.writeJSONString(val.String())
 	default:
+		if isComposite(val) && s.writeJSONEncoded(val) {
+			break
+		}
 		s.buf = append(s.buf, '"')
 		s.writeString(stringifyMessage(val))
 		s.buf = append(s.buf, '"')
 	}
 }
 
-// needsQuotes checks if a string needs to be quoted in text format
+// marshalText returns the text form of a text marshaler, false if it is a nil pointer or fails
+func marshalText(m encoding.TextMarshaler) ([]byte, bool) {
+	if v := reflect.ValueOf(m); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, false
+	}
+	text, err := m.MarshalText()
+	return text, err == nil
+}
+
+// isComposite reports whether a value is a map, slice, array, struct or pointer, written as JSON by
+// encoding/json so it becomes an object or array instead of its quoted string form
+func isComposite(v any) bool {
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Pointer:
+		return true
+	}
+	return false
+}
+
+// writeJSONEncoded writes a value as JSON with encoding/json, without escaping HTML characters. It reports
+// false for values encoding/json can't encode, such as maps with non-string keys, cycles or a json.Marshaler
+// failing or returning invalid JSON.
+func (s *serializer) writeJSONEncoded(v any) bool {
+	s.composite.Reset()
+	enc := json.NewEncoder(&s.composite)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return false
+	}
+	s.buf = append(s.buf, bytes.TrimSuffix(s.composite.Bytes(), []byte{'\n'})...)
+	return true
+}
+
+// needsQuotes checks if a string needs to be quoted in text format, also if it holds invalid UTF-8 written
+// as an escape
 func needsQuotes(s string) bool {
 	if len(s) == 0 {
 		return true
 	}
 	for _, c := range s {
-		if c <= ' ' || c == '"' || c == '\\' {
+		if c <= ' ' || c == '"' || c == '\\' || c == utf8.RuneError {
 			return true
 		}
 	}
 	return false
 }
 
-// levelToString converts the numeric levels to string to be written in the file.
+// levelNames are the names of the standard levels, higher levels first
+var levelNames = []struct {
+	name  string
+	level int64
+}{
+	{"ERROR", LevelError},
+	{"WARN", LevelWarn},
+	{"INFO", LevelInfo},
+	{"DEBUG", LevelDebug},
+}
+
+// levelToString returns the name of a level as written in the file. Levels between the standard ones
+// are named after the next lower standard level with an offset, as slog does, e.g. "INFO+2" or "DEBUG-1".
 func levelToString(level int64) string {
-	switch level {
-	case LevelDebug:
-		return "DEBUG"
-	case LevelInfo:
-		return "INFO"
-	case LevelWarn:
-		return "WARN"
-	case LevelError:
-		return "ERROR"
-	default:
-		return fmt.Sprintf("UNKNOWN (%d)", level)
+	for _, l := range levelNames {
+		if level == l.level {
+			return l.name
+		}
+		if level > l.level {
+			return l.name + "+" + strconv.FormatInt(level-l.level, 10)
+		}
+	}
+	base := levelNames[len(levelNames)-1]
+	return base.name + strconv.FormatInt(level-base.level, 10)
+}
+
+// parseLevel converts a level name to its value, accepting the names written by levelToString in any case,
+// with an optional "level" prefix such as "LevelWarn", and numeric values.
+func parseLevel(s string) (int64, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if name == "" {
+		return 0, fmt.Errorf("missing level")
+	}
+	if n, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return n, nil
+	}
+	name = strings.TrimPrefix(name, "LEVEL")
+
+	var offset int64
+	if i := strings.IndexAny(name, "+-"); i > 0 {
+		n, err := strconv.ParseInt(name[i:], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid level: %s", s)
+		}
+		name, offset = name[:i], n
+	}
+	for _, l := range levelNames {
+		if name == l.name {
+			return l.level + offset, nil
+		}
 	}
+	return 0, fmt.Errorf("invalid level: %s", s)
 }
 
 // stringifyMessage converts any type to a string representation
@@ -224,16 +721,69 @@ func stringifyMessage(msg any) string {
 	}
 }
 
-// writeString appends a string to the buffer with proper escape sequence handling
+// writeString appends a string escaped for JSON strings and quoted text values. Quotes and backslashes are
+// escaped, newlines, carriage returns and tabs are written as \n, \r and \t, other control characters as
+// \u00XX, and invalid UTF-8 is replaced by \ufffd, so records always parse. Strings of a record exceeding
+// MaxRecordBytes are cut, ending with "...", keys are never cut by writeKey. With FoldLines, newlines and tabs
+// are kept, newlines followed by foldIndent.
 func (s *serializer) writeString(str string) {
-	for i := 0; i < len(str); i++ {
-		if str[i] < 0x20 || str[i] == '"' || str[i] == '\\' {
-			s.buf = append(s.buf, '\\')
+	str, cut := s.limitString(str)
+
+	start := 0
+	for i := 0; i < len(str); {
+		c := str[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(str[i:])
+			if r == utf8.RuneError && size == 1 {
+				s.buf = append(s.buf, str[start:i]...)
+				s.buf = append(s.buf, `\ufffd`...)
+				start = i + size
+			}
+			i += size
+			continue
+		}
+		if c >= 0x20 && c != '"' && c != '\\' {
+			i++
+			continue
 		}
-		s.buf = append(s.buf, str[i])
+
+		s.buf = append(s.buf, str[start:i]...)
+		switch c {
+		case '"', '\\':
+			s.buf = append(s.buf, '\\', c)
+		case '\n':
+			if foldLines {
+				s.buf = append(s.buf, '\n')
+				s.buf = append(s.buf, foldIndent...)
+				break
+			}
+			s.buf = append(s.buf, '\\', 'n')
+		case '\r':
+			if foldLines && i+1 < len(str) && str[i+1] == '\n' {
+				break
+			}
+			s.buf = append(s.buf, '\\', 'r')
+		case '\t':
+			if foldLines {
+				s.buf = append(s.buf, '\t')
+				break
+			}
+			s.buf = append(s.buf, '\\', 't')
+		default:
+			s.buf = append(s.buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		}
+		i++
+		start = i
+	}
+	s.buf = append(s.buf, str[start:]...)
+	if cut {
+		s.buf = append(s.buf, truncationSuffix...)
 	}
 }
 
+// hexDigits are the digits of \u escapes
+const hexDigits = "0123456789abcdef"
+
 // writeValue converts any value to its string representation (deprecated - use format-specific writers)
 func (s *serializer) writeValue(v any) {
 	switch val := v.(type) {
@@ -256,4 +806,4 @@ func (s *serializer) writeValue(v any) {
 		s.writeString(stringifyMessage(val))
 		s.buf = append(s.buf, '"')
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,114 @@
+package logger_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/LixenWraith/logger"
+)
+
+// logLines logs through a logger initialized with cfg in a temporary directory and returns the lines of its file
+func logLines(t *testing.T, cfg *logger.LoggerConfig, log func(ctx context.Context)) []string {
+	t.Helper()
+	ctx := context.Background()
+	cfg.Name = "test"
+	cfg.Directory = t.TempDir()
+	if err := logger.Init(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	log(ctx)
+	if err := logger.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(logger.Stats().File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []string
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+func TestJSONNonFiniteFloats(t *testing.T) {
+	for _, format := range []string{"json", "ecs"} {
+		t.Run(format, func(t *testing.T) {
+			lines := logLines(t, &logger.LoggerConfig{Format: format}, func(ctx context.Context) {
+				logger.InfoFields(ctx, "typed",
+					logger.Float64("nan", math.NaN()),
+					logger.Float64("inf", math.Inf(1)),
+					logger.Float64("neg_inf", math.Inf(-1)))
+				logger.Info(ctx, "args", "nan", math.NaN(), "inf", math.Inf(1), "f32", float32(math.Inf(-1)))
+				logger.InfoFields(ctx, "any", logger.Any("nan", math.NaN()), logger.Float64("finite", 1.5))
+			})
+			if len(lines) != 3 {
+				t.Fatalf("got %d lines, want 3", len(lines))
+			}
+
+			want := map[string]any{"nan": "NaN", "inf": "+Inf", "neg_inf": "-Inf", "f32": "-Inf", "finite": 1.5}
+			for _, line := range lines {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("invalid JSON %q: %v", line, err)
+				}
+				values := record
+				if fields, ok := record["fields"].(map[string]any); ok {
+					values = fields
+				}
+				for key, got := range values {
+					if w, ok := want[key]; ok && got != w {
+						t.Errorf("%s = %v, want %v in %q", key, got, w, line)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTruncationKeepsKeys(t *testing.T) {
+	key := "a_rather_long_field_key_that_must_survive"
+	for _, format := range []string{"json", "ecs", "txt"} {
+		t.Run(format, func(t *testing.T) {
+			lines := logLines(t, &logger.LoggerConfig{Format: format, MaxRecordBytes: 256}, func(ctx context.Context) {
+				logger.InfoFields(ctx, "large", logger.Str(key, strings.Repeat("x", 4096)))
+				logger.Info(ctx, "args", key, strings.Repeat("y", 4096))
+			})
+			if len(lines) != 2 {
+				t.Fatalf("got %d lines, want 2", len(lines))
+			}
+			for _, line := range lines {
+				if len(line) > 256 {
+					t.Errorf("line of %d bytes exceeds the limit: %q", len(line), line)
+				}
+				if !strings.Contains(line, "truncated") {
+					t.Errorf("line not truncated: %q", line)
+				}
+				if format == "txt" {
+					continue
+				}
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("invalid JSON %q: %v", line, err)
+				}
+				values := record
+				if fields, ok := record["fields"].(map[string]any); ok {
+					values = fields
+				}
+				if _, ok := values[key]; !ok {
+					t.Errorf("key %s cut in %q", key, line)
+				}
+			}
+			if format == "txt" && !strings.Contains(lines[0], key+" ") {
+				t.Errorf("key %s cut in %q", key, lines[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+// Package gelf sends records written through the logger to Graylog as GELF messages over UDP or TCP,
+// for hosts where file tailing agents cannot run.
+//
+//	sink, err := gelf.New(gelf.Config{Address: "graylog:12201", Fields: map[string]any{"service": "api"}})
+//	if err != nil {
+//		return err
+//	}
+//	logger.AddSink("graylog", sink)
+//	defer sink.Close()
+//
+// Messages are sent from a goroutine of the sink, so a slow or unreachable server never blocks logging.
+// Messages beyond the queue size are dropped and counted.
+package gelf
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+// Defaults of Config
+const (
+	DefaultChunkSize = 1420 // fits the MTU of most networks
+	DefaultQueueSize = 1024
+)
+
+// GELF framing limits
+const (
+	chunkHeaderLen = 12  // magic bytes, message id, sequence number and count
+	maxChunks      = 128 // chunks per message accepted by Graylog
+	dialTimeout    = 5 * time.Second
+)
+
+// chunkMagic starts every chunk of a chunked UDP message
+var chunkMagic = [2]byte{0x1e, 0x0f}
+
+// Syslog severities of GELF levels
+const (
+	severityError   = 3
+	severityWarning = 4
+	severityInfo    = 6
+	severityDebug   = 7
+)
+
+// Config configures a GELF sink.
+type Config struct {
+	Address   string         // host:port of the GELF input
+	Network   string         // "udp" (default) or "tcp"
+	Host      string         // source host of the messages, the hostname if empty
+	Fields    map[string]any // static additional fields sent with every message, e.g. "service"
+	ChunkSize int            // maximum UDP datagram size, larger messages are chunked, DefaultChunkSize if 0
+	QueueSize int            // messages queued for sending, DefaultQueueSize if 0
+}
+
+// Sink sends every written record as a GELF message. It implements logger.Sink.
+type Sink struct {
+	network   string
+	address   string
+	host      string
+	fields    map[string]any
+	chunkSize int
+
+	queue   chan []byte
+	done    chan struct{}
+	closeMu sync.Mutex
+	closed  bool
+	dropped atomic.Uint64
+
+	conn net.Conn // owned by the send goroutine
+}
+
+// New creates a sink sending to the configured address and starts its send goroutine.
+// A TCP server that is not reachable yet is dialed again for the following messages.
+func New(cfg Config) (*Sink, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("gelf: address must not be empty")
+	}
+	network := cfg.Network
+	if network == "" {
+		network = "udp"
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("gelf: invalid network: %s", network)
+	}
+	chunkSize := cfg.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize <= chunkHeaderLen {
+		return nil, fmt.Errorf("gelf: invalid chunk size: %d", chunkSize)
+	}
+	queueSize := cfg.QueueSize
+	if queueSize == 0 {
+		queueSize = DefaultQueueSize
+	}
+	if queueSize < 0 {
+		return nil, fmt.Errorf("gelf: invalid queue size: %d", queueSize)
+	}
+	host := cfg.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	fields := make(map[string]any, len(cfg.Fields))
+	for k, v := range cfg.Fields {
+		fields[fieldName(k)] = fieldValue(v)
+	}
+
+	s := &Sink{
+		network:   network,
+		address:   cfg.Address,
+		host:      host,
+		fields:    fields,
+		chunkSize: chunkSize,
+		queue:     make(chan []byte, queueSize),
+		done:      make(chan struct{}),
+	}
+	// A UDP address that does not resolve is a configuration error, report it now
+	if network == "udp" {
+		conn, err := net.DialTimeout(network, cfg.Address, dialTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("gelf: failed to dial %s: %w", cfg.Address, err)
+		}
+		s.conn = conn
+	}
+	go s.send()
+	return s, nil
+}
+
+// Write encodes the entry as a GELF message and queues it for sending.
+// It returns an error if the queue is full or the sink is closed, the message is dropped.
+func (s *Sink) Write(entry *logger.Entry) error {
+	msg, err := s.encode(entry)
+	if err != nil {
+		s.dropped.Add(1)
+		return err
+	}
+
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+	if s.closed {
+		s.dropped.Add(1)
+		return errors.New("gelf: sink closed")
+	}
+	select {
+	case s.queue <- msg:
+		return nil
+	default:
+		s.dropped.Add(1)
+		return errors.New("gelf: queue full")
+	}
+}
+
+// Dropped returns the number of messages dropped because the queue was full, the sink was closed
+// or sending failed.
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close sends the queued messages and closes the connection. Remove the sink from the logger first,
+// later records are dropped.
+func (s *Sink) Close() error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.closeMu.Unlock()
+
+	<-s.done
+	return nil
+}
+
+// encode builds the GELF message of an entry: the message, a syslog level, the timestamp in seconds,
+// and the trace, the record fields and the static fields as additional fields
+func (s *Sink) encode(entry *logger.Entry) ([]byte, error) {
+	msg := make(map[string]any, len(s.fields)+len(entry.Values)/2+6)
+	for k, v := range s.fields {
+		msg[k] = v
+	}
+	for _, f := range entry.Fields() {
+		addField(msg, "", f)
+	}
+	if entry.Trace != "" {
+		msg["_trace"] = entry.Trace
+	}
+	msg["_log_level"] = logger.LevelString(entry.Level)
+
+	short := entry.Message()
+	if short == "" && len(entry.Values) == 0 {
+		// Raw records carry the line only
+		short = strings.TrimSpace(string(entry.Line))
+	}
+	if short == "" {
+		// Graylog rejects messages without a short message
+		short = "-"
+	}
+	msg["version"] = "1.1"
+	msg["host"] = s.host
+	msg["short_message"] = short
+	msg["timestamp"] = math.Round(float64(entry.Time.UnixMicro())/1e3) / 1e3
+	msg["level"] = severity(entry.Level)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("gelf: failed to encode message: %w", err)
+	}
+	return data, nil
+}
+
+// send writes queued messages until the queue is closed
+func (s *Sink) send() {
+	defer close(s.done)
+	defer func() {
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	}()
+
+	for msg := range s.queue {
+		var err error
+		if s.network == "udp" {
+			err = s.writeUDP(msg)
+		} else {
+			err = s.writeTCP(msg)
+		}
+		if err != nil {
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// writeUDP sends a message as one datagram, or in chunks if it exceeds the chunk size
+func (s *Sink) writeUDP(msg []byte) error {
+	if len(msg) <= s.chunkSize {
+		_, err := s.conn.Write(msg)
+		return err
+	}
+
+	payload := s.chunkSize - chunkHeaderLen
+	count := (len(msg) + payload - 1) / payload
+	if count > maxChunks {
+		return fmt.Errorf("gelf: message of %d bytes exceeds %d chunks", len(msg), maxChunks)
+	}
+	chunk := make([]byte, 0, s.chunkSize)
+	chunk = append(chunk, chunkMagic[:]...)
+	chunk = append(chunk, make([]byte, 8)...)
+	if _, err := rand.Read(chunk[2:10]); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		end := min((i+1)*payload, len(msg))
+		chunk = append(chunk[:10], byte(i), byte(count))
+		chunk = append(chunk, msg[i*payload:end]...)
+		if _, err := s.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTCP sends a null byte delimited message, dialing again after a failure
+func (s *Sink) writeTCP(msg []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, dialTimeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if _, err := s.conn.Write(append(msg, 0)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// severity maps a level to a syslog severity, levels between the standard ones take the lower one
+func severity(level int64) int {
+	switch {
+	case level >= logger.LevelError:
+		return severityError
+	case level >= logger.LevelWarn:
+		return severityWarning
+	case level >= logger.LevelInfo:
+		return severityInfo
+	default:
+		return severityDebug
+	}
+}
+
+// addField sets the additional field of a record field, flattening the fields of a group into fields of
+// their own with dotted names, e.g. "_http.method", as GELF has no nested values
+func addField(msg map[string]any, prefix string, f logger.Field) {
+	if group, ok := f.Value().([]logger.Field); ok {
+		for _, member := range group {
+			addField(msg, prefix+f.Key+".", member)
+		}
+		return
+	}
+	msg[fieldName(prefix+f.Key)] = fieldValue(f.Value())
+}
+
+// fieldName returns the additional field name of a key: prefixed with an underscore, with characters
+// outside letters, digits, '_', '.' and '-' replaced by '_'. The reserved "_id" becomes "_id_".
+func fieldName(key string) string {
+	name := "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, strings.TrimPrefix(key, "_"))
+	if name == "_id" {
+		return "_id_"
+	}
+	return name
+}
+
+// fieldValue returns the value of an additional field, GELF only allows strings and numbers
+func fieldValue(v any) any {
+	switch val := v.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return val
+	case float32:
+		return fieldValue(float64(val))
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return fmt.Sprint(val)
+		}
+		return val
+	case error:
+		return val.Error()
+	case fmt.Stringer:
+		return val.String()
+	default:
+		return fmt.Sprintf("%+v", val)
+	}
+}
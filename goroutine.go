@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+)
+
+// includeGoroutine writes the id of the logging goroutine to every record
+var includeGoroutine bool
+
+// workerKey is the context key of the worker label attached with ContextWithWorker
+type workerKey struct{}
+
+// goroutinePrefix starts the first line of a goroutine stack trace, "goroutine 42 [running]:"
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID returns the id of the calling goroutine parsed from its stack trace header, 0 if it can't be
+// parsed. The runtime doesn't expose the id otherwise, the header is formatted without walking the stack.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// stampGoroutine records the id of the logging goroutine with IncludeGoroutine. It must be called on the
+// goroutine of the logging call.
+func stampGoroutine(record *logRecord) {
+	if includeGoroutine {
+		record.Goroutine = goroutineID()
+	}
+}
+
+// recordWorker returns the worker label attached to the context of the record, empty if none
+func recordWorker(r *logRecord) string {
+	if r.LogCtx == nil {
+		return ""
+	}
+	worker, _ := r.LogCtx.Value(workerKey{}).(string)
+	return worker
+}
+
+// contextWithWorker attaches a worker label to the context, an empty label removes an inherited one
+func contextWithWorker(ctx context.Context, worker string) context.Context {
+	return context.WithValue(ctx, workerKey{}, worker)
+}
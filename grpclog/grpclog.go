@@ -0,0 +1,188 @@
+// Package grpclog provides gRPC interceptors logging every RPC through the logger with its method, status code,
+// duration and peer, and a grpclog.LoggerV2 adapter writing the internal logs of gRPC through the logger.
+//
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(grpclog.UnaryServerInterceptor(grpclog.Config{})),
+//		grpc.StreamInterceptor(grpclog.StreamServerInterceptor(grpclog.Config{})),
+//	)
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithUnaryInterceptor(grpclog.UnaryClientInterceptor(grpclog.Config{})),
+//		grpc.WithStreamInterceptor(grpclog.StreamClientInterceptor(grpclog.Config{})),
+//	)
+//
+// RPCs ending with a server side code, Unknown, DeadlineExceeded, Unimplemented, Internal, Unavailable or DataLoss,
+// are logged at error level, other failed RPCs at warn level and successful ones at info level. Streams are logged
+// once they end.
+//
+// The package is a module of its own, so the logger does not depend on the gRPC module.
+package grpclog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/LixenWraith/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMessage is the message of RPC records if Config.Message is empty
+const DefaultMessage = "gRPC call"
+
+// Config configures the interceptors.
+type Config struct {
+	Message     string   // message of RPC records, DefaultMessage if empty
+	SkipMethods []string // full methods not logged, e.g. /grpc.health.v1.Health/Check
+}
+
+// UnaryServerInterceptor returns a server interceptor logging unary RPCs.
+func UnaryServerInterceptor(cfg Config) grpc.UnaryServerInterceptor {
+	cfg = cfg.withDefaults()
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		cfg.logRPC(ctx, "server", info.FullMethod, false, peerAddr(ctx), time.Since(start), err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor logging streaming RPCs when the handler returns.
+func StreamServerInterceptor(cfg Config) grpc.StreamServerInterceptor {
+	cfg = cfg.withDefaults()
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		ctx := ss.Context()
+		cfg.logRPC(ctx, "server", info.FullMethod, true, peerAddr(ctx), time.Since(start), err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a client interceptor logging unary RPCs.
+func UnaryClientInterceptor(cfg Config) grpc.UnaryClientInterceptor {
+	cfg = cfg.withDefaults()
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption) error {
+		var p peer.Peer
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+		cfg.logRPC(ctx, "client", method, false, addrOf(&p, cc.Target()), time.Since(start), err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a client interceptor logging streaming RPCs once the stream ends: when
+// receiving returns an error or io.EOF, when the single response of a client streaming RPC is received,
+// or when the stream could not be created.
+func StreamClientInterceptor(cfg Config) grpc.StreamClientInterceptor {
+	cfg = cfg.withDefaults()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		p := new(peer.Peer)
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, append(opts, grpc.Peer(p))...)
+		if err != nil {
+			cfg.logRPC(ctx, "client", method, true, cc.Target(), time.Since(start), err)
+			return nil, err
+		}
+		end := func(err error) {
+			cfg.logRPC(ctx, "client", method, true, addrOf(p, cc.Target()), time.Since(start), err)
+		}
+		return &clientStream{ClientStream: cs, singleResponse: !desc.ServerStreams, end: end}, nil
+	}
+}
+
+// clientStream logs the RPC of a client stream once receiving ends it. Without server streaming, the RPC ends
+// with its single response, received by CloseAndRecv without reading io.EOF.
+type clientStream struct {
+	grpc.ClientStream
+	singleResponse bool
+	once           sync.Once
+	end            func(err error)
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		if s.singleResponse {
+			s.once.Do(func() { s.end(nil) })
+		}
+	case errors.Is(err, io.EOF):
+		s.once.Do(func() { s.end(nil) })
+	default:
+		s.once.Do(func() { s.end(err) })
+	}
+	return err
+}
+
+// withDefaults returns the config with defaults for unset fields
+func (cfg Config) withDefaults() Config {
+	if cfg.Message == "" {
+		cfg.Message = DefaultMessage
+	}
+	return cfg
+}
+
+// logRPC writes the record of an RPC at the level of its status code
+func (cfg Config) logRPC(ctx context.Context, kind, method string, stream bool, addr string, d time.Duration,
+	err error) {
+	if slices.Contains(cfg.SkipMethods, method) {
+		return
+	}
+	code := status.Code(err)
+	fields := []logger.Field{
+		logger.Str("kind", kind),
+		logger.Str("method", method),
+		logger.Str("code", code.String()),
+		logger.Any("duration", d),
+		logger.Str("peer", addr),
+	}
+	if stream {
+		fields = append(fields, logger.Bool("stream", true))
+	}
+	if err != nil {
+		fields = append(fields, logger.Str("error", status.Convert(err).Message()))
+	}
+
+	switch {
+	case code == codes.OK:
+		logger.InfoFields(ctx, cfg.Message, fields...)
+	case serverError(code):
+		logger.ErrorFields(ctx, cfg.Message, fields...)
+	default:
+		logger.WarnFields(ctx, cfg.Message, fields...)
+	}
+}
+
+// serverError reports whether a code signals a failure of the server rather than of the request
+func serverError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable,
+		codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// peerAddr returns the address of the client of a server RPC, empty if unknown
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// addrOf returns the address of the server of a client RPC, the target of the connection if not known
+func addrOf(p *peer.Peer, target string) string {
+	if p.Addr != nil {
+		return p.Addr.String()
+	}
+	return target
+}
@@ -0,0 +1,107 @@
+package grpclog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/LixenWraith/logger"
+	grpclogv2 "google.golang.org/grpc/grpclog"
+)
+
+// LoggerV2 implements grpclog.LoggerV2, writing the internal logs of gRPC through the logger. gRPC logs
+// connection state changes at info level, logger.SetLevelFor("grpc", logger.LevelWarn) leaves them out.
+// Install it before creating servers or connections:
+//
+//	grpclog.SetLoggerV2(loggrpc.NewLoggerV2(0))
+type LoggerV2 struct {
+	verbosity int
+}
+
+var _ grpclogv2.LoggerV2 = (*LoggerV2)(nil)
+
+// NewLoggerV2 returns a LoggerV2 reporting verbosity levels up to verbosity as enabled to gRPC, 0 for the
+// default logs of gRPC only.
+func NewLoggerV2(verbosity int) *LoggerV2 {
+	return &LoggerV2{verbosity: verbosity}
+}
+
+// Records of the internal logs of gRPC have the module "grpc", so logger.SetLevelFor("grpc", level) filters
+// them apart from the application, and a "system" field of "grpc"
+var (
+	grpcCtx     = logger.ContextWithModule(context.Background(), "grpc")
+	systemField = logger.Str("system", "grpc")
+)
+
+// Info logs args formatted like fmt.Sprint at info level.
+func (l *LoggerV2) Info(args ...any) {
+	logger.InfoFields(grpcCtx, fmt.Sprint(args...), systemField)
+}
+
+// Infoln logs args formatted like fmt.Sprintln at info level.
+func (l *LoggerV2) Infoln(args ...any) {
+	logger.InfoFields(grpcCtx, sprintln(args), systemField)
+}
+
+// Infof logs a message formatted like fmt.Sprintf at info level.
+func (l *LoggerV2) Infof(format string, args ...any) {
+	logger.InfoFields(grpcCtx, fmt.Sprintf(format, args...), systemField)
+}
+
+// Warning logs args formatted like fmt.Sprint at warn level.
+func (l *LoggerV2) Warning(args ...any) {
+	logger.WarnFields(grpcCtx, fmt.Sprint(args...), systemField)
+}
+
+// Warningln logs args formatted like fmt.Sprintln at warn level.
+func (l *LoggerV2) Warningln(args ...any) {
+	logger.WarnFields(grpcCtx, sprintln(args), systemField)
+}
+
+// Warningf logs a message formatted like fmt.Sprintf at warn level.
+func (l *LoggerV2) Warningf(format string, args ...any) {
+	logger.WarnFields(grpcCtx, fmt.Sprintf(format, args...), systemField)
+}
+
+// Error logs args formatted like fmt.Sprint at error level.
+func (l *LoggerV2) Error(args ...any) {
+	logger.ErrorFields(grpcCtx, fmt.Sprint(args...), systemField)
+}
+
+// Errorln logs args formatted like fmt.Sprintln at error level.
+func (l *LoggerV2) Errorln(args ...any) {
+	logger.ErrorFields(grpcCtx, sprintln(args), systemField)
+}
+
+// Errorf logs a message formatted like fmt.Sprintf at error level.
+func (l *LoggerV2) Errorf(format string, args ...any) {
+	logger.ErrorFields(grpcCtx, fmt.Sprintf(format, args...), systemField)
+}
+
+// Fatal logs at error level, then exits with status 1 through logger.Exit, writing the queued records first.
+func (l *LoggerV2) Fatal(args ...any) {
+	l.Error(args...)
+	logger.Exit(1)
+}
+
+// Fatalln logs at error level and exits like Fatal.
+func (l *LoggerV2) Fatalln(args ...any) {
+	l.Errorln(args...)
+	logger.Exit(1)
+}
+
+// Fatalf logs at error level and exits like Fatal.
+func (l *LoggerV2) Fatalf(format string, args ...any) {
+	l.Errorf(format, args...)
+	logger.Exit(1)
+}
+
+// V reports whether the verbosity level is enabled.
+func (l *LoggerV2) V(level int) bool {
+	return level <= l.verbosity
+}
+
+// sprintln formats args like fmt.Sprintln, without the trailing newline
+func sprintln(args []any) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
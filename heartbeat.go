@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"context"
+	"time"
+)
+
+// heartbeatEvent identifies heartbeat records in the "event" field
+const heartbeatEvent = "heartbeat"
+
+// Heartbeat state, startedAt is the time of the first Init since the last shutdown
+var (
+	heartbeatInterval time.Duration
+	startedAt         time.Time
+)
+
+// writeHeartbeat writes a heartbeat record with the running counters, regardless of the level:
+//
+//	event=heartbeat uptime_ms=U records_written=R bytes_written=B dropped=D disk_usage_bytes=S disk_free_bytes=F
+//
+// Disk figures are -1 if the directory cannot be read.
+// It must only be called from the processor goroutine.
+func writeHeartbeat(s *serializer) {
+	now := clockNow()
+	usage, err := getLogDirSize(directory)
+	if err != nil {
+		usage = -1
+	}
+	free, err := getDiskFreeSpace(directory)
+	if err != nil {
+		free = -1
+	}
+
+	record := logRecord{
+		LogCtx:    context.Background(),
+		Flags:     flags,
+		TimeStamp: now,
+		Level:     LevelInfo,
+		HasMsg:    true,
+		Msg:       "Logger heartbeat",
+	}
+	record.Fields = []Field{
+		Str("event", heartbeatEvent),
+		Int64("uptime_ms", now.Sub(startedAt).Milliseconds()),
+		Uint64("records_written", recordsWritten.Load()),
+		Uint64("bytes_written", bytesWritten.Load()),
+		Uint64("dropped", droppedLogs.Load()),
+		Int64("disk_usage_bytes", usage),
+		Int64("disk_free_bytes", free),
+	}
+	writeRecord(s, &record)
+}
@@ -0,0 +1,163 @@
+// Package httplog provides net/http middleware logging every request through the logger with its method, path,
+// status, latency, response bytes and remote address, and logging panics of handlers.
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/orders", handleOrders)
+//	http.ListenAndServe(":8080", httplog.Middleware(mux))
+//
+// Requests answered with a 5xx status are logged at error level, 4xx at warn level and others at info level.
+// A panicking handler is logged with its stack by logger.CapturePanic and answered with 500 if no response was
+// written yet. http.ErrAbortHandler panics are logged as aborted requests and continue to the server.
+package httplog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+// DefaultMessage is the message of request records if Config.Message is empty
+const DefaultMessage = "HTTP request"
+
+// Config configures the middleware.
+type Config struct {
+	Message         string   // message of request records, DefaultMessage if empty
+	SkipPaths       []string // paths of requests not logged, e.g. /healthz, panics are logged regardless
+	RequestIDHeader string   // request header attached as request_id with logger.ContextWithFields, none if empty
+}
+
+// Middleware logs the requests served by next with the default config.
+func Middleware(next http.Handler) http.Handler {
+	return New(Config{})(next)
+}
+
+// New returns middleware logging the requests served by the wrapped handler according to cfg.
+func New(cfg Config) func(http.Handler) http.Handler {
+	if cfg.Message == "" {
+		cfg.Message = DefaultMessage
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serve(cfg, next, w, r)
+		})
+	}
+}
+
+// serve runs the handler with a response writer recording status and size, then logs the request
+func serve(cfg Config, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx := r.Context()
+	if cfg.RequestIDHeader != "" {
+		if id := r.Header.Get(cfg.RequestIDHeader); id != "" {
+			ctx = logger.ContextWithFields(ctx, "request_id", id)
+			r = r.WithContext(ctx)
+		}
+	}
+
+	rw := &responseWriter{ResponseWriter: w}
+	err := logger.CapturePanic(ctx, func() { next.ServeHTTP(rw, r) })
+	aborted := errors.Is(err, http.ErrAbortHandler)
+	if err != nil && !aborted && rw.status == 0 {
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+
+	if err != nil || !slices.Contains(cfg.SkipPaths, r.URL.Path) {
+		logRequest(ctx, cfg.Message, r, rw, time.Since(start), err, aborted)
+	}
+	if aborted {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// logRequest writes the request record at the level of its status
+func logRequest(ctx context.Context, msg string, r *http.Request, rw *responseWriter, latency time.Duration, err error,
+	aborted bool) {
+	status := rw.status
+	if status == 0 {
+		// Handlers writing nothing are answered with 200 by the server
+		status = http.StatusOK
+	}
+	fields := []logger.Field{
+		logger.Str("method", r.Method),
+		logger.Str("path", r.URL.Path),
+		logger.Int("status", status),
+		logger.Any("latency", latency),
+		logger.Int64("bytes", rw.bytes),
+		logger.Str("remote_addr", r.RemoteAddr),
+	}
+	switch {
+	case aborted:
+		fields = append(fields, logger.Bool("aborted", true))
+	case err != nil:
+		fields = append(fields, logger.Str("error", err.Error()))
+	}
+
+	switch {
+	case status >= 500 || (err != nil && !aborted):
+		logger.ErrorFields(ctx, msg, fields...)
+	case status >= 400 || aborted:
+		logger.WarnFields(ctx, msg, fields...)
+	default:
+		logger.InfoFields(ctx, msg, fields...)
+	}
+}
+
+// responseWriter records the status and the number of body bytes written through it
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// Informational responses are followed by the final status
+	if w.status == 0 && (status < 100 || status > 199) {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush forwards to the wrapped writer if it supports flushing, for streaming handlers
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack forwards to the wrapped writer if it supports hijacking, for WebSocket and other upgraded connections.
+// A hijacked request is logged with status 101 Switching Protocols unless a status was written before.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("httplog: %T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
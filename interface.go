@@ -1,6 +1,11 @@
 package logger
 
-import "context"
+import (
+	"context"
+	"io"
+	stdlog "log"
+	"net/http"
+)
 
 // Log level constants match slog levels for consistency with applications that use it.
 // These values are used to determine which logs to write based on minimum level configuration.
@@ -11,9 +16,22 @@ const (
 	LevelError int64 = 8  // matches slog.LevelError
 )
 
+// ParseLevel converts a level name such as "info", "LevelWarn" or "INFO+2", or a numeric value, to a level.
+// Names are matched case-insensitively and include the names returned by LevelString.
+func ParseLevel(s string) (int64, error) {
+	return parseLevel(s)
+}
+
+// LevelString returns the name of a level as written to the log, e.g. "WARN".
+// Levels between the standard ones are named with an offset from the next lower one, e.g. "INFO+2".
+func LevelString(level int64) string {
+	return levelToString(level)
+}
+
 // Init initializes the logger with the provided configuration and context.
-func Init(ctx context.Context, cfg ...*LoggerConfig) error {
-	return configLogger(ctx, cfg...)
+// It accepts a *LoggerConfig, functional options such as WithLevel, or both, applied in order.
+func Init(ctx context.Context, opts ...Option) error {
+	return configLogger(ctx, opts...)
 }
 
 // Debug logs a message at debug level with the given context and additional arguments.
@@ -40,8 +58,101 @@ func Error(logCtx context.Context, args ...any) {
 	log(logCtx, flags, LevelError, traceDepth, args...)
 }
 
+// DebugFields logs a message with typed fields at debug level.
+// Up to 8 primitive fields are logged without heap allocation.
+func DebugFields(logCtx context.Context, msg string, fields ...Field) {
+	logFields(logCtx, flags, LevelDebug, traceDepth, msg, fields...)
+}
+
+// InfoFields logs a message with typed fields at info level.
+// Up to 8 primitive fields are logged without heap allocation.
+func InfoFields(logCtx context.Context, msg string, fields ...Field) {
+	logFields(logCtx, flags, LevelInfo, traceDepth, msg, fields...)
+}
+
+// WarnFields logs a message with typed fields at warning level.
+// Up to 8 primitive fields are logged without heap allocation.
+func WarnFields(logCtx context.Context, msg string, fields ...Field) {
+	logFields(logCtx, flags, LevelWarn, traceDepth, msg, fields...)
+}
+
+// ErrorFields logs a message with typed fields at error level.
+// Up to 8 primitive fields are logged without heap allocation.
+func ErrorFields(logCtx context.Context, msg string, fields ...Field) {
+	logFields(logCtx, flags, LevelError, traceDepth, msg, fields...)
+}
+
+// Debugf logs a message formatted with fmt.Sprintf at debug level.
+// The message is only formatted if the record is not dropped by the level.
+func Debugf(logCtx context.Context, format string, args ...any) {
+	logf(logCtx, flags, LevelDebug, traceDepth, format, args...)
+}
+
+// Infof logs a message formatted with fmt.Sprintf at info level.
+// The message is only formatted if the record is not dropped by the level.
+func Infof(logCtx context.Context, format string, args ...any) {
+	logf(logCtx, flags, LevelInfo, traceDepth, format, args...)
+}
+
+// Warnf logs a message formatted with fmt.Sprintf at warning level.
+// The message is only formatted if the record is not dropped by the level.
+func Warnf(logCtx context.Context, format string, args ...any) {
+	logf(logCtx, flags, LevelWarn, traceDepth, format, args...)
+}
+
+// Errorf logs a message formatted with fmt.Sprintf at error level.
+// The message is only formatted if the record is not dropped by the level.
+func Errorf(logCtx context.Context, format string, args ...any) {
+	logf(logCtx, flags, LevelError, traceDepth, format, args...)
+}
+
+// WriteRaw writes a pre-serialized line at the level as is, through the same buffering, rotation and disk
+// management as other records. A newline is appended if missing. The line is copied, it should be a single
+// line in the configured format, e.g. a JSON object for the json format.
+func WriteRaw(level int64, line []byte) {
+	logRaw(level, line)
+}
+
+// Writer returns an io.Writer logging each write as a record at the level, without the trailing newline.
+// It is meant for libraries that write whole lines to a writer, such as request logging middleware.
+func Writer(level int64) io.Writer {
+	return newWriter(level)
+}
+
+// StdLogger returns a standard library *log.Logger writing through Writer at the level,
+// e.g. for http.Server.ErrorLog or grpclog.NewLoggerV2.
+func StdLogger(level int64) *stdlog.Logger {
+	return newStdLogger(level)
+}
+
+// Pressure returns the occupancy of the record buffer from 0 (empty) to 1 (full), so producers can shed load
+// before records are dropped. It returns 0 if the logger is not initialized.
+func Pressure() float64 {
+	return pressure()
+}
+
+// SubscribePressure returns a channel notified whenever the buffer occupancy crosses one of the thresholds,
+// given between 0 and 1, in either direction, and a function ending the subscription. Notifications are
+// coalesced while the channel is not read, the current value is read with Pressure.
+func SubscribePressure(thresholds ...float64) (<-chan struct{}, func(), error) {
+	return subscribePressure(thresholds...)
+}
+
+// Exit shuts the logger down, writing and syncing the queued records for up to 5 seconds, then exits the
+// program with code. As with os.Exit, deferred functions are not run.
+func Exit(code int) {
+	exitLogger(code)
+}
+
+// SetExitFunc replaces os.Exit as the function ending the process in Exit and quick.Fatal, e.g. to observe
+// the code in tests or to run cleanup of a wrapper first. nil restores os.Exit.
+func SetExitFunc(fn func(code int)) {
+	setExitFunc(fn)
+}
+
 // Shutdown gracefully shuts down the logger, ensuring all buffered messages are written
-// and files are properly closed. It respects context cancellation for timeout control.
+// and files are properly closed. It respects context cancellation for timeout control:
+// records still queued when the context is done are abandoned and reported by a *ShutdownError.
 func Shutdown(ctx ...context.Context) error {
 	shutdownCtx := context.Background()
 	if len(ctx) > 0 {
@@ -70,11 +181,197 @@ func ErrorTrace(logCtx context.Context, depth int, args ...any) {
 	log(logCtx, flags, LevelError, int64(depth), args...)
 }
 
+// Flush blocks until all records logged before the call are written and synced to disk, and cleanup or
+// retention work already due is complete, or the context is done. Unlike Shutdown, the logger keeps running.
+func Flush(ctx context.Context) error {
+	return flushLogger(ctx)
+}
+
+// Rotate closes the active log file and continues logging into a new file, once all records logged
+// before the call are written to the closed file, or the context is done.
+func Rotate(ctx context.Context) error {
+	return rotateLogger(ctx)
+}
+
+// Reopen closes the active log file and continues logging into a new file, recreating the
+// log directory if needed. It is intended for SIGHUP handlers and external rotation tools.
+func Reopen() error {
+	return requestReopen()
+}
+
+// VerifyFile verifies the hash chain of a log file written with AuditChain enabled.
+// It returns an error describing the first line where the chain is broken, or the missing footer of a closed file.
+func VerifyFile(path string) error {
+	return verifyAuditFile(path)
+}
+
+// VerifyRecord checks the checksum of a line written with RecordChecksum enabled and returns the record
+// without the checksum and newline. Lines torn by a crash fail the check and can be skipped.
+func VerifyRecord(line []byte) ([]byte, error) {
+	return verifyRecord(line)
+}
+
+// SetPreInitBuffer holds up to size records logged before the first Init in memory and writes them once
+// Init completes, filtered by the configured level and formatted with the configured flags.
+// Records beyond the size are reported as dropped. It has no effect after Init, 0 disables buffering.
+func SetPreInitBuffer(size int) {
+	setPreInitBuffer(size)
+}
+
+// SetLevel changes the minimum level of records without a module override. Unlike reconfiguring the level,
+// it does not restart the processor or start a new file. It ends a debug boost started by SIGUSR1.
+func SetLevel(level int64) {
+	setLevel(level)
+}
+
+// GetLevel returns the minimum level of records without a module override, LevelDebug during a debug boost.
+func GetLevel() int64 {
+	return currentLevel()
+}
+
+// SetLevelFor overrides the minimum level for records of a module, and of its sub-modules without
+// an override of their own. The module is the import path of the calling package, e.g.
+// "github.com/acme/app/db", or the name attached to the context with ContextWithModule.
+func SetLevelFor(module string, level int64) {
+	setModuleLevel(module, level, false)
+}
+
+// ClearLevelFor removes the level override of a module.
+func ClearLevelFor(module string) {
+	setModuleLevel(module, 0, true)
+}
+
+// ContextWithModule attaches a module name to the context, selecting the level override of the module
+// instead of the calling package.
+func ContextWithModule(ctx context.Context, module string) context.Context {
+	return context.WithValue(ctx, moduleKey{}, module)
+}
+
+// ContextWithWorker attaches a worker label to the context, written to records logged with the context as
+// "worker", so interleaved records of concurrent workers can be grouped without a field in every call.
+func ContextWithWorker(ctx context.Context, worker string) context.Context {
+	return contextWithWorker(ctx, worker)
+}
+
+// ContextWithFields returns a context carrying fields added to every record logged with it, after the fields of
+// the call. Args pair up as key and value like the variadic API, a Field is a pair on its own. Fields are added to
+// those of a parent context, replacing a parent field of the same key, e.g. a middleware attaching request_id once.
+func ContextWithFields(ctx context.Context, args ...any) context.Context {
+	return contextWithFields(ctx, args...)
+}
+
+// RecoverAndLog recovers a panic and logs its value and stack at error level, then waits until the record
+// is synced to disk. With repanic, the panic continues after logging. It must be deferred directly:
+//
+//	defer logger.RecoverAndLog(ctx, false)
+func RecoverAndLog(ctx context.Context, repanic bool) {
+	if r := recover(); r != nil {
+		logPanic(ctx, r)
+		if repanic {
+			panic(r)
+		}
+	}
+}
+
+// CapturePanic runs fn and, if it panics, logs the panic like RecoverAndLog and returns it as an error.
+func CapturePanic(ctx context.Context, fn func()) error {
+	return capturePanic(ctx, fn)
+}
+
+// ContextWithTransient marks records logged with the context as transient. With TransientRetention set,
+// they are written to separate files deleted after the retention, e.g. for a verbose debugging session.
+func ContextWithTransient(ctx context.Context) context.Context {
+	return contextWithTransient(ctx)
+}
+
+// RegisterStage makes a custom stage available under a name for use in LoggerConfig.Pipeline.
+// Built-in stage names are reserved.
+func RegisterStage(name string, stage Stage) error {
+	return registerStage(name, stage)
+}
+
+// DecodeFile decodes a log file written through a pipeline of the given stages, in pipeline order.
+func DecodeFile(path string, stages ...Stage) ([]byte, error) {
+	return decodeFile(path, stages...)
+}
+
+// Recent returns up to n of the latest records as serialized lines, all kept with n <= 0, oldest first.
+// It requires RecentSize in the configuration and returns nothing otherwise.
+func Recent(n int) []string {
+	return recent(n)
+}
+
+// Query reads records back from log files and returns those matching opts, oldest first. Without Files it reads
+// the log files of the running logger, records still buffered are only included after Flush.
+func Query(opts QueryOptions) ([]QueryEntry, error) {
+	return query(opts)
+}
+
+// ParseLine parses a line of a txt, json or ecs log file as Query does, reporting false for empty lines
+// and invalid JSON. Text time stamps are parsed with the configured TimestampFormat, or RFC 3339.
+func ParseLine(line string) (QueryEntry, bool) {
+	return parseLine(line)
+}
+
+// MsgpackToJSON converts the records of a msgpack format file to JSON, one record per line. Files written
+// through a pipeline are decoded with DecodeFile first. On error, the records converted so far are returned.
+func MsgpackToJSON(data []byte) ([]byte, error) {
+	return msgpackToJSON(data)
+}
+
+// ServeHTTP serves the latest records on GET, one per line, limited by the optional "n" parameter.
+// It can be mounted on any mux with http.HandlerFunc(logger.ServeHTTP) and is served as /recent by the admin listener.
+func ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveRecent(w, r)
+}
+
 // Config initializes the logger with the provided configuration.
 func Config(cfg *LoggerConfig) error {
 	return configLogger(context.Background(), cfg)
 }
 
+// IsInitialized reports whether the logger is initialized and running.
+func IsInitialized() bool {
+	return isInitialized.Load()
+}
+
+// Paused reports whether logging is paused because disk space limits are exceeded and cleanup couldn't free
+// enough space. Records logged meanwhile are dropped with cause disk_full.
+func Paused() bool {
+	return diskCheckFailed.Load()
+}
+
+// Resume checks the disk space now instead of after DiskCheckInterval and resumes logging paused by a full disk
+// if the limits are met again. It returns ErrDiskFull if logging stays paused, nil if it was not paused.
+func Resume() error {
+	return resumeLogging()
+}
+
+// Diagnostics returns the channel of the logger's internal events: rotations, deletions by retention and disk
+// space cleanup, failed writes and drop reports. Events are sent once it was called, without blocking the logger,
+// and discarded while 64 events wait for the consumer. All calls return the same channel.
+func Diagnostics() <-chan DiagEvent {
+	return diagnostics()
+}
+
+// Stats returns the records and bytes written, drops and files created since Init, or from Init to Shutdown after
+// Shutdown, e.g. for a batch job reporting its logging at the end of a run.
+func Stats() RunStats {
+	return runStats()
+}
+
+// IsDisabled reports whether logging calls are dropped because the logger was shut down or its automatic
+// initialization failed. Reset clears the state.
+func IsDisabled() bool {
+	return loggerDisabled.Load()
+}
+
+// LastInitError returns the error of the last failed Init, Config or automatic initialization, nil if the
+// last one succeeded or none was attempted.
+func LastInitError() error {
+	return lastInitError()
+}
+
 // EnsureInitialized checks if the logger is initialized, and initializes if not.
 // returns true if it was already initialized or initialization attempt was successful.
 // returns false if logger cannot be initialized.
@@ -82,10 +379,48 @@ func EnsureInitialized() bool {
 	return ensureInitialized()
 }
 
-// LogWithFlags allows custom flag control for logging with specified flags, level and trace depth
+// SetClock sets the clock providing the time of records and the tickers of flushes, heartbeats and retention
+// checks, nil restores the system clock. Tickers are created when the logger starts, set the clock before Init.
+func SetClock(c Clock) {
+	setClock(c)
+}
+
+// SetErrorHandler sets a function receiving errors the logger cannot recover from on its own, such as writes
+// still failing after recreating the log file, nil removes it. It is called from the processor goroutine and
+// must return quickly, without logging through a blocking overflow policy.
+func SetErrorHandler(h func(err error)) {
+	setErrorHandler(h)
+}
+
+// SetArchiver sets an archiver uploading log files before cleanup or retention deletes them.
+// Files are deleted only after the upload succeeded, nil deletes files directly again.
+func SetArchiver(a Archiver) {
+	setArchiver(a)
+}
+
+// AddSink registers a sink receiving every record written to the log file under a unique name.
+func AddSink(name string, sink Sink) error {
+	return addSink(name, sink)
+}
+
+// RemoveSink unregisters the named sink, reporting whether it was registered.
+func RemoveSink(name string) bool {
+	return removeSink(name)
+}
+
+// Reset clears the disabled state left by a failed automatic initialization or a shutdown,
+// so the next quick logging call initializes the logger again. Failed automatic initializations
+// are also retried every 5 seconds without Reset.
+func Reset() {
+	resetLogger()
+}
+
+// LogWithFlags logs the args like Info at the level, with the record flags given instead of the configured ones,
+// e.g. 0 for a record without time stamp and level or FlagDefault|FlagShowSequence. A depth of -1 uses the
+// configured TraceDepth and 0 disables the trace. The level is still checked against the minimum level.
 func LogWithFlags(ctx context.Context, flags int64, level int64, depth int64, args ...any) {
 	if depth == -1 {
 		depth = traceDepth
 	}
 	log(ctx, flags, level, depth, args...)
-}
\ No newline at end of file
+}
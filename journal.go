@@ -0,0 +1,393 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// Journal file layout: a header of the magic, the last committed record id and the offset of the next
+// uncommitted frame, followed by a ring of frames. A frame is a 4-byte length, an 8-byte record id and a
+// 4-byte CRC-32C over the id and the line, followed by the serialized line. A zero length marks the wrap
+// to the first frame.
+const (
+	journalMagic       = "LGJ1"
+	journalHeader      = 64
+	journalFrameHeader = 16
+)
+
+// journal is a memory-mapped file holding the serialized records queued but not yet written to the log
+// file. The mapping is shared with the file, so the records survive the process being killed.
+type journal struct {
+	file *os.File // locked exclusively while open
+	base string   // directory and name the journal belongs to
+	data []byte   // mapped file, nil once closed
+
+	// Appending state, guarded by journalMu. undoOff and undoID restore the state before the record
+	// being queued if it is not queued in memory.
+	writeOff int
+	nextID   uint64
+	undoOff  int
+	undoID   uint64
+
+	// Commit state, advanced by the processor once records are written
+	commitMu    sync.Mutex
+	committedID uint64
+	commitOff   int
+}
+
+// Journal state. journalMu serializes appending to the journal with queueing, so records are queued in
+// the order of their ids.
+var (
+	journalMu     sync.Mutex
+	activeJournal *journal
+	journalSizeKB int64
+
+	// journalDone is the last journaled record processed, committed by the processor after the write
+	// of the records before it. Only accessed by the processor goroutine.
+	journalDone struct {
+		j   *journal
+		id  uint64
+		end int
+	}
+)
+
+// openJournal opens the journal of the running config, keeping an open journal of the same directory, name
+// and size. Each logger of a directory and name journals to a slot file of its own, <name>.journal or
+// <name>.<slot>.journal, locked while it runs. Records left unwritten by ended processes are written to a
+// recovery file first, its name and record count are returned. With reconfig, an open journal is replaced
+// without recovery, its records are still queued.
+func openJournal(sizeKB int64, reconfig bool) (recovered string, count int, err error) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	base := filepath.Join(directory, name)
+	size := int(sizeKB * 1024)
+	if j := activeJournal; j != nil && j.base == base && len(j.data) == journalHeader+size {
+		journalSizeKB = sizeKB
+		return "", 0, nil
+	}
+	recoverOrphans := activeJournal == nil && !reconfig
+	if activeJournal != nil {
+		closeJournalLocked(true)
+	}
+	journalSizeKB = sizeKB
+
+	var f *os.File
+	own := 0
+	if sizeKB > 0 {
+		if f, own, err = claimSlotFile("journal"); err != nil {
+			return "", 0, fmt.Errorf("failed to open journal: %w", err)
+		}
+	}
+	if recoverOrphans {
+		// Journals of ended processes, including the one claimed, are recovered and removed
+		orphans := orphanedSlotFiles("journal", own)
+		if f != nil {
+			orphans = append(orphans, f)
+		}
+		recovered, count, err = recoverJournal(orphans)
+		for _, o := range orphans {
+			if o != f {
+				removeSlotFile(o)
+			}
+		}
+		if err != nil {
+			if f != nil {
+				f.Close()
+			}
+			return "", 0, err
+		}
+	}
+	if f == nil {
+		return recovered, count, nil
+	}
+
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return recovered, count, fmt.Errorf("failed to size journal: %w", err)
+	}
+	if err := f.Truncate(int64(journalHeader + size)); err != nil {
+		f.Close()
+		return recovered, count, fmt.Errorf("failed to size journal: %w", err)
+	}
+	data, err := mapFile(f, journalHeader+size)
+	if err != nil {
+		f.Close()
+		return recovered, count, fmt.Errorf("failed to map journal: %w", err)
+	}
+	copy(data, journalMagic)
+	binary.BigEndian.PutUint64(data[16:], journalHeader)
+
+	activeJournal = &journal{
+		file:      f,
+		base:      base,
+		data:      data,
+		writeOff:  journalHeader,
+		nextID:    1,
+		commitOff: journalHeader,
+	}
+	return recovered, count, nil
+}
+
+// closeJournal closes the journal on shutdown. The file is removed unless records in it were not written,
+// the next process recovers them.
+func closeJournal() {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	closeJournalLocked(false)
+}
+
+// closeJournalLocked unmaps and closes the journal, journalMu must be held. With remove, the file is removed
+// regardless of its records.
+func closeJournalLocked(remove bool) {
+	j := activeJournal
+	if j == nil {
+		return
+	}
+	activeJournal = nil
+
+	j.commitMu.Lock()
+	remove = remove || j.committedID == j.nextID-1
+	unmapFile(j.data)
+	j.data = nil
+	j.commitMu.Unlock()
+
+	if remove {
+		removeSlotFile(j.file)
+	} else {
+		j.file.Close()
+	}
+}
+
+// journalRecord appends a record, or the records of a batch, to the journal and marks the record with the
+// id and end offset of its last frame. It returns with journalMu held if the journal is enabled, the caller
+// queues the record and releases it with releaseJournal. A record that doesn't fit into the free space of
+// the journal is queued without journaling.
+func journalRecord(record *logRecord) bool {
+	if journalSizeKB <= 0 {
+		return false
+	}
+	journalMu.Lock()
+	j := activeJournal
+	if j == nil {
+		journalMu.Unlock()
+		return false
+	}
+
+	s := spillSerializers.Get().(*serializer)
+	defer spillSerializers.Put(s)
+
+	j.undoOff, j.undoID = j.writeOff, j.nextID
+	ok := true
+	if record.Batch == nil {
+		ok = j.append(s.serialize(record))
+	}
+	for i := 0; ok && i < len(record.Batch); i++ {
+		ok = j.append(s.serialize(&record.Batch[i]))
+	}
+	if !ok {
+		// Queued without journaling, frames of a batch appended so far are given up
+		j.writeOff, j.nextID = j.undoOff, j.undoID
+		return true
+	}
+	record.journal, record.journalID, record.journalEnd = j, j.nextID-1, j.writeOff
+	return true
+}
+
+// releaseJournal ends journaling a record after queueing it and releases journalMu. A record that was not
+// queued in memory is removed from the journal again, it was spilled or dropped.
+func releaseJournal(record *logRecord, queued bool) {
+	if j := record.journal; !queued && j != nil {
+		j.writeOff, j.nextID = j.undoOff, j.undoID
+	}
+	journalMu.Unlock()
+}
+
+// append writes a frame of line at the write offset, wrapping to the first frame if needed.
+// It reports false if the frame doesn't fit into the space of uncommitted frames. journalMu must be held.
+func (j *journal) append(line []byte) bool {
+	n := journalFrameHeader + len(line)
+	end := len(j.data)
+	if n > end-journalHeader {
+		return false
+	}
+
+	j.commitMu.Lock()
+	empty, commitOff := j.committedID == j.nextID-1, j.commitOff
+	j.commitMu.Unlock()
+
+	off := j.writeOff
+	switch {
+	case off+n <= end && (empty || off >= commitOff || off+n < commitOff):
+	case off+n > end && (empty || off >= commitOff && journalHeader+n < commitOff):
+		if off+4 <= end {
+			binary.BigEndian.PutUint32(j.data[off:], 0)
+		}
+		off = journalHeader
+	default:
+		return false
+	}
+
+	frame := j.data[off : off+n]
+	binary.BigEndian.PutUint32(frame, uint32(len(line)))
+	binary.BigEndian.PutUint64(frame[4:], j.nextID)
+	copy(frame[journalFrameHeader:], line)
+	binary.BigEndian.PutUint32(frame[12:], journalChecksum(frame[4:12], line))
+
+	j.writeOff = off + n
+	j.nextID++
+	return true
+}
+
+// journalChecksum returns the CRC-32C of a frame id and line
+func journalChecksum(id, line []byte) uint32 {
+	return crc32.Update(crc32.Checksum(id, crc32c), crc32c, line)
+}
+
+// markJournaled notes a processed record, its journal frames are committed with the next write.
+// It must only be called from the processor goroutine.
+func markJournaled(record *logRecord) {
+	if record.journal != nil && (record.journal != journalDone.j || record.journalID > journalDone.id) {
+		journalDone.j, journalDone.id, journalDone.end = record.journal, record.journalID, record.journalEnd
+	}
+}
+
+// commitJournal commits the records processed so far, called once the records before them are written.
+// It must only be called from the processor goroutine.
+func commitJournal() {
+	j := journalDone.j
+	if j == nil {
+		return
+	}
+	j.commitMu.Lock()
+	if j.data != nil && journalDone.id > j.committedID {
+		j.committedID, j.commitOff = journalDone.id, journalDone.end
+		binary.BigEndian.PutUint64(j.data[8:], j.committedID)
+		binary.BigEndian.PutUint64(j.data[16:], uint64(j.commitOff))
+	}
+	j.commitMu.Unlock()
+	journalDone.j = nil
+}
+
+// journalRecoveredTag follows the logger name in the names of recovery files
+const journalRecoveredTag = "recovered"
+
+// recoverJournal writes the uncommitted records of journals left by ended processes to a recovery file
+// named <name>_recovered_<timestamp>, in the configured format and pipeline. It returns the name of the file
+// and the number of records, none if the journals are empty or not journals.
+func recoverJournal(files []*os.File) (string, int, error) {
+	var lines [][]byte
+	for _, f := range files {
+		data, err := io.ReadAll(io.NewSectionReader(f, 0, math.MaxInt64))
+		if err != nil || len(data) < journalHeader || string(data[:4]) != journalMagic {
+			continue
+		}
+		lines = append(lines, readJournal(data)...)
+	}
+	if len(lines) == 0 {
+		return "", 0, nil
+	}
+
+	filename, err := generateLogFileName(name+"_"+journalRecoveredTag, clockNow())
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to recover journal: %w", err)
+	}
+	f, err := openLogFile(filepath.Join(directory, filename))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to recover journal: %w", err)
+	}
+	defer f.Close()
+
+	var out []byte
+	if len(pipeline) > 0 {
+		out = pipelineHeader()
+	}
+	s := newSerializer()
+	for _, line := range lines {
+		s.buf = append(s.buf[:0], line...)
+		data := s.buf
+		if recordChecksum {
+			data = s.appendChecksum()
+		}
+		if format == FormatMsgpack {
+			data = s.pack(data)
+		}
+		if len(pipeline) > 0 {
+			if data, err = s.encode(data); err != nil {
+				return "", 0, fmt.Errorf("failed to recover journal: %w", err)
+			}
+		}
+		out = append(out, data...)
+	}
+	if _, err := f.Write(out); err != nil {
+		return "", 0, fmt.Errorf("failed to recover journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return "", 0, fmt.Errorf("failed to recover journal: %w", err)
+	}
+	return filename, len(lines), nil
+}
+
+// readJournal returns the lines of the frames following the last committed record, in id order. Reading
+// stops at the first frame that is torn, stale or not the next id.
+func readJournal(data []byte) [][]byte {
+	id := binary.BigEndian.Uint64(data[8:]) + 1
+	off := int(binary.BigEndian.Uint64(data[16:]))
+	if off < journalHeader || off > len(data) {
+		return nil
+	}
+
+	var lines [][]byte
+	for scanned, wrapped := 0, false; scanned < len(data); {
+		if off+journalFrameHeader > len(data) || binary.BigEndian.Uint32(data[off:]) == 0 {
+			if wrapped {
+				break
+			}
+			scanned += len(data) - off
+			off, wrapped = journalHeader, true
+			continue
+		}
+		frame := data[off:]
+		size := int(binary.BigEndian.Uint32(frame))
+		if journalFrameHeader+size > len(frame) || binary.BigEndian.Uint64(frame[4:]) != id {
+			break
+		}
+		line := frame[journalFrameHeader : journalFrameHeader+size]
+		if binary.BigEndian.Uint32(frame[12:]) != journalChecksum(frame[4:12], line) {
+			break
+		}
+		lines = append(lines, slices.Clip(line))
+		off += journalFrameHeader + size
+		scanned += journalFrameHeader + size
+		id++
+	}
+	return lines
+}
+
+// writeRecoveryMarker writes a record to the new log file naming the recovery file of a previous process.
+// It must be called with mu held and the processor stopped.
+func writeRecoveryMarker(file string, count int) {
+	record := logRecord{
+		LogCtx:    context.Background(),
+		Flags:     flags,
+		TimeStamp: clockNow(),
+		Level:     max(LevelWarn, fileMinLevel),
+		HasMsg:    true,
+		Msg:       "Recovered unwritten records of the previous process from the journal",
+	}
+	record.Fields = []Field{
+		Str("event", "journal_recovery"),
+		Int("records", count),
+		Str("recovery_file", file),
+	}
+	writeRecord(newSerializer(), &record)
+	flushWrites()
+}
@@ -0,0 +1,320 @@
+// Package kafka streams records written through the logger to an Apache Kafka topic. It has no client of
+// its own: messages are handed to a Producer, an adapter over the Kafka client of the application, so the
+// logger does not depend on one.
+//
+//	sink, err := kafka.New(kafka.Config{
+//		Producer:     producer,
+//		Topic:        "logs.billing",
+//		Key:          kafka.KeyField("tenant_id"),
+//		FallbackPath: "/var/log/billing/kafka_fallback.log",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	logger.AddSink("kafka", sink)
+//	defer sink.Close()
+//
+// Messages are batched and produced from a goroutine of the sink. Batches still failing after the retries
+// are appended to the fallback file, if configured, for replay once the brokers are back.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+// Defaults of Config
+const (
+	DefaultBatchSize = 500
+	DefaultLinger    = 100 * time.Millisecond
+	DefaultQueueSize = 10000
+	DefaultRetries   = 3
+	DefaultBackoff   = 500 * time.Millisecond
+	DefaultTimeout   = 10 * time.Second
+)
+
+// Acks is the acknowledgement required from the brokers before a produce succeeds.
+type Acks int
+
+// Acknowledgement levels, the zero value waits for all in-sync replicas
+const (
+	AcksAll    Acks = iota // all in-sync replicas, acks=-1
+	AcksLeader             // the partition leader only, acks=1
+	AcksNone               // no acknowledgement, acks=0
+)
+
+// String returns the Kafka acks setting of the level, "all", "1" or "0".
+func (a Acks) String() string {
+	switch a {
+	case AcksLeader:
+		return "1"
+	case AcksNone:
+		return "0"
+	}
+	return "all"
+}
+
+// Message is a record to produce.
+type Message struct {
+	Topic string
+	Key   []byte // partitioning key, nil for the default partitioner of the producer
+	Value []byte // serialized record in the configured format, without the trailing newline
+	Time  time.Time
+}
+
+// Producer produces messages with a Kafka client. Produce returns once the messages are acknowledged
+// as required by acks or the context is done, and must not retain the slice. It is called from a single goroutine.
+type Producer interface {
+	Produce(ctx context.Context, acks Acks, messages []Message) error
+}
+
+// ProducerFunc adapts a function to the Producer interface.
+type ProducerFunc func(ctx context.Context, acks Acks, messages []Message) error
+
+// Produce calls f(ctx, acks, messages).
+func (f ProducerFunc) Produce(ctx context.Context, acks Acks, messages []Message) error {
+	return f(ctx, acks, messages)
+}
+
+// KeyField returns a Key function keying messages by the value of a record field, so the records of
+// e.g. a tenant share a partition and keep their order. Records without the field have no key.
+func KeyField(name string) func(entry *logger.Entry) []byte {
+	return func(entry *logger.Entry) []byte {
+		for _, f := range entry.Fields() {
+			if f.Key == name {
+				return fmt.Append(nil, f.Value())
+			}
+		}
+		return nil
+	}
+}
+
+// Config configures a Kafka sink.
+type Config struct {
+	Producer     Producer
+	Topic        string
+	Key          func(entry *logger.Entry) []byte // partitioning key of a record, no key if nil
+	Acks         Acks
+	BatchSize    int           // messages per produce, DefaultBatchSize if 0
+	Linger       time.Duration // maximum time a message waits for its batch to fill, DefaultLinger if 0
+	QueueSize    int           // messages queued for producing, DefaultQueueSize if 0
+	Retries      int           // retries of a failed batch, DefaultRetries if 0, none if negative
+	Backoff      time.Duration // delay before the first retry, doubled for each next one, DefaultBackoff if 0
+	Timeout      time.Duration // timeout of each produce, DefaultTimeout if 0
+	FallbackPath string        // file the values of failed batches are appended to, one per line
+}
+
+// Sink produces every written record to a Kafka topic. It implements logger.Sink.
+type Sink struct {
+	cfg Config
+
+	queue   chan Message
+	done    chan struct{}
+	closeMu sync.Mutex
+	closed  bool
+
+	dropped    atomic.Uint64
+	fallbacked atomic.Uint64
+	lastErr    atomic.Value // stores errorValue
+}
+
+// New creates a sink producing to the configured topic and starts its produce goroutine.
+func New(cfg Config) (*Sink, error) {
+	if cfg.Producer == nil {
+		return nil, errors.New("kafka: producer must not be nil")
+	}
+	if cfg.Topic == "" {
+		return nil, errors.New("kafka: topic must not be empty")
+	}
+	if cfg.Acks < AcksAll || cfg.Acks > AcksNone {
+		return nil, fmt.Errorf("kafka: invalid acks: %d", cfg.Acks)
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.Linger == 0 {
+		cfg.Linger = DefaultLinger
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = DefaultRetries
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.BatchSize < 0 || cfg.Linger < 0 || cfg.QueueSize < 0 || cfg.Backoff < 0 || cfg.Timeout < 0 {
+		return nil, errors.New("kafka: invalid batch, queue or retry settings")
+	}
+
+	s := &Sink{
+		cfg:   cfg,
+		queue: make(chan Message, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write queues the entry as a message. It returns an error if the queue is full or the sink is closed,
+// the message is dropped and only kept in the log file.
+func (s *Sink) Write(entry *logger.Entry) error {
+	line := entry.Line
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	msg := Message{
+		Topic: s.cfg.Topic,
+		Value: append([]byte(nil), line...),
+		Time:  entry.Time,
+	}
+	if s.cfg.Key != nil {
+		msg.Key = s.cfg.Key(entry)
+	}
+
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+	if s.closed {
+		s.dropped.Add(1)
+		return errors.New("kafka: sink closed")
+	}
+	select {
+	case s.queue <- msg:
+		return nil
+	default:
+		s.dropped.Add(1)
+		return errors.New("kafka: queue full")
+	}
+}
+
+// Dropped returns the number of messages neither produced nor written to the fallback file.
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Fallback returns the number of messages written to the fallback file.
+func (s *Sink) Fallback() uint64 {
+	return s.fallbacked.Load()
+}
+
+// Err returns the error of the last failed produce, nil if the last produce succeeded.
+func (s *Sink) Err() error {
+	v, _ := s.lastErr.Load().(errorValue)
+	return v.error
+}
+
+// Close produces the queued messages and stops the sink. Remove the sink from the logger first,
+// later records are dropped.
+func (s *Sink) Close() error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.closeMu.Unlock()
+
+	<-s.done
+	return nil
+}
+
+// errorValue wraps errors stored in lastErr, as atomic.Value requires a consistent type
+type errorValue struct {
+	error
+}
+
+// run collects batches of up to BatchSize messages, waiting at most Linger for a batch to fill
+func (s *Sink) run() {
+	defer close(s.done)
+
+	batch := make([]Message, 0, s.cfg.BatchSize)
+	linger := time.NewTimer(s.cfg.Linger)
+	linger.Stop()
+	defer linger.Stop()
+
+	for {
+		select {
+		case msg, ok := <-s.queue:
+			if !ok {
+				if len(batch) > 0 {
+					s.produce(batch)
+				}
+				return
+			}
+			if len(batch) == 0 {
+				linger.Reset(s.cfg.Linger)
+			}
+			batch = append(batch, msg)
+			if len(batch) < s.cfg.BatchSize {
+				continue
+			}
+			if !linger.Stop() {
+				select {
+				case <-linger.C:
+				default:
+				}
+			}
+		case <-linger.C:
+		}
+		s.produce(batch)
+		batch = batch[:0]
+	}
+}
+
+// produce sends a batch, retrying with backoff, and writes it to the fallback file if all attempts fail
+func (s *Sink) produce(batch []Message) {
+	backoff := s.cfg.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+		err = s.cfg.Producer.Produce(ctx, s.cfg.Acks, batch)
+		cancel()
+		s.lastErr.Store(errorValue{err})
+		if err == nil || attempt >= s.cfg.Retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if err == nil {
+		return
+	}
+	if err := s.writeFallback(batch); err != nil {
+		s.dropped.Add(uint64(len(batch)))
+		return
+	}
+	s.fallbacked.Add(uint64(len(batch)))
+}
+
+// writeFallback appends the values of a batch to the fallback file
+func (s *Sink) writeFallback(batch []Message) error {
+	if s.cfg.FallbackPath == "" {
+		return errors.New("kafka: no fallback file")
+	}
+	f, err := os.OpenFile(s.cfg.FallbackPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("kafka: failed to open fallback file: %w", err)
+	}
+	var buf []byte
+	for _, msg := range batch {
+		buf = append(buf, msg.Value...)
+		buf = append(buf, '\n')
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return fmt.Errorf("kafka: failed to write fallback file: %w", err)
+	}
+	return f.Close()
+}
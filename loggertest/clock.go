@@ -0,0 +1,96 @@
+package loggertest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+// Clock is a logger.Clock whose time only moves with Advance, so tests of flushes, heartbeats and
+// retention run without sleeping. Set it with logger.SetClock before Init:
+//
+//	clock := loggertest.NewClock(time.Now())
+//	logger.SetClock(clock)
+//	t.Cleanup(func() { logger.SetClock(nil) })
+//	// Init, log, then
+//	clock.Advance(2 * time.Hour) // retention checks now see files as two hours older
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers map[*ticker]struct{}
+}
+
+// NewClock returns a clock set to start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start, tickers: make(map[*ticker]struct{})}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Ticker returns a ticker firing every d of clock time.
+func (c *Clock) Ticker(d time.Duration) logger.Ticker {
+	if d <= 0 {
+		panic("loggertest: non-positive ticker interval")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &ticker{clock: c, interval: d, next: c.now.Add(d), c: make(chan time.Time), stop: make(chan struct{})}
+	c.tickers[t] = struct{}{}
+	return t
+}
+
+// Advance moves the clock forward by d and delivers one tick to every ticker due meanwhile, like a
+// time.Ticker dropping ticks for a slow receiver. It returns once the logger received the ticks, so
+// a following logger.Flush is processed after them.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var due []*ticker
+	for t := range c.tickers {
+		if now.Before(t.next) {
+			continue
+		}
+		for !now.Before(t.next) {
+			t.next = t.next.Add(t.interval)
+		}
+		due = append(due, t)
+	}
+	c.mu.Unlock()
+
+	for _, t := range due {
+		select {
+		case t.c <- now:
+		case <-t.stop:
+		}
+	}
+}
+
+// ticker is a Ticker of a Clock
+type ticker struct {
+	clock    *Clock
+	interval time.Duration
+	next     time.Time // guarded by the clock mutex
+	c        chan time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func (t *ticker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *ticker) Stop() {
+	t.stopOnce.Do(func() {
+		t.clock.mu.Lock()
+		delete(t.clock.tickers, t)
+		t.clock.mu.Unlock()
+		close(t.stop)
+	})
+}
@@ -0,0 +1,206 @@
+// Package loggertest captures records written through the logger in memory, so tests of code
+// that logs can assert on levels and fields instead of parsing files off disk.
+//
+//	func TestHandler(t *testing.T) {
+//		rec := loggertest.Capture(t)
+//		handle(ctx)
+//		rec.AssertLogged(t, logger.LevelInfo, "request served", "status", 200)
+//	}
+//
+// The logger is process global, so records logged by parallel tests are captured as well.
+package loggertest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+// flushTimeout bounds waiting for queued records before reading captured entries
+const flushTimeout = 2 * time.Second
+
+// captureSeq makes sink names unique across captures
+var captureSeq atomic.Uint64
+
+// Entry is a captured log record.
+type Entry struct {
+	Time   time.Time
+	Level  int64
+	Trace  string
+	Values []any  // positional values as logged
+	Line   string // serialized record in the configured format
+}
+
+// Message returns the first value as a string, the message by convention.
+func (e Entry) Message() string {
+	if len(e.Values) == 0 {
+		return ""
+	}
+	return fmt.Sprint(e.Values[0])
+}
+
+// Field returns the value following the given key among the values after the message.
+func (e Entry) Field(key string) (any, bool) {
+	for i := 1; i+1 < len(e.Values); i += 2 {
+		if k, ok := e.Values[i].(string); ok && k == key {
+			return e.Values[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// Recorder holds the entries captured since Capture.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Capture starts capturing written records until the test ends. If the logger is not initialized,
+// it is initialized at debug level with files written to a temporary test directory and shut down
+// when the test ends.
+func Capture(t testing.TB) *Recorder {
+	t.Helper()
+
+	if !logger.IsInitialized() {
+		ctx := context.Background()
+		if err := logger.Init(ctx, &logger.LoggerConfig{Level: logger.LevelDebug, Directory: t.TempDir()}); err != nil {
+			t.Fatalf("loggertest: failed to initialize logger: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = logger.Shutdown(ctx)
+		})
+	}
+
+	r := &Recorder{}
+	name := fmt.Sprintf("loggertest/%d/%s", captureSeq.Add(1), t.Name())
+	if err := logger.AddSink(name, logger.SinkFunc(r.write)); err != nil {
+		t.Fatalf("loggertest: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.RemoveSink(name)
+	})
+	return r
+}
+
+// write is the sink receiving records from the logger
+func (r *Recorder) write(e *logger.Entry) error {
+	entry := Entry{
+		Time:   e.Time,
+		Level:  e.Level,
+		Trace:  e.Trace,
+		Values: append([]any(nil), e.Values...),
+		Line:   string(e.Line),
+	}
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	return nil
+}
+
+// Entries waits for records logged before the call to be written and returns all captured entries.
+func (r *Recorder) Entries() []Entry {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	_ = logger.Flush(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Len returns the number of captured entries.
+func (r *Recorder) Len() int {
+	return len(r.Entries())
+}
+
+// Level returns the captured entries with the given level.
+func (r *Recorder) Level(level int64) []Entry {
+	var matched []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// Reset discards the captured entries.
+func (r *Recorder) Reset() {
+	r.Entries() // wait for pending records so they are discarded as well
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+// Find returns the first entry with the given level and message whose fields contain the
+// given key/value pairs.
+func (r *Recorder) Find(level int64, msg string, keyValues ...any) (Entry, bool) {
+	for _, e := range r.Entries() {
+		if e.Level == level && e.Message() == msg && hasFields(e, keyValues) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// AssertLogged fails the test if no entry matches the level, message and key/value pairs.
+func (r *Recorder) AssertLogged(t testing.TB, level int64, msg string, keyValues ...any) {
+	t.Helper()
+	if _, ok := r.Find(level, msg, keyValues...); !ok {
+		t.Errorf("loggertest: no %s entry %q with fields %v, captured:\n%s",
+			logger.LevelString(level), msg, keyValues, r.dump())
+	}
+}
+
+// AssertNotLogged fails the test if an entry matches the level, message and key/value pairs.
+func (r *Recorder) AssertNotLogged(t testing.TB, level int64, msg string, keyValues ...any) {
+	t.Helper()
+	if e, ok := r.Find(level, msg, keyValues...); ok {
+		t.Errorf("loggertest: unexpected %s entry: %s", logger.LevelString(level), e.Line)
+	}
+}
+
+// AssertCount fails the test if the number of entries with the given level differs.
+func (r *Recorder) AssertCount(t testing.TB, level int64, count int) {
+	t.Helper()
+	if n := len(r.Level(level)); n != count {
+		t.Errorf("loggertest: %d %s entries, expected %d", n, logger.LevelString(level), count)
+	}
+}
+
+// hasFields checks the entry for each key/value pair, comparing values by their string form
+// so that e.g. int and int64 values match.
+func hasFields(e Entry, keyValues []any) bool {
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		key, ok := keyValues[i].(string)
+		if !ok {
+			return false
+		}
+		value, found := e.Field(key)
+		if !found {
+			return false
+		}
+		if !reflect.DeepEqual(value, keyValues[i+1]) && fmt.Sprint(value) != fmt.Sprint(keyValues[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// dump formats the captured entries for failure messages
+func (r *Recorder) dump() string {
+	var sb strings.Builder
+	for _, e := range r.Entries() {
+		sb.WriteString("  ")
+		sb.WriteString(strings.TrimRight(e.Line, "\n"))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
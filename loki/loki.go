@@ -0,0 +1,365 @@
+// Package loki pushes records written through the logger to Grafana Loki over its HTTP push API,
+// for deployments without a log shipping agent.
+//
+//	sink, err := loki.New(loki.Config{
+//		URL:        "http://loki:3100/loki/api/v1/push",
+//		Labels:     map[string]string{"service": "api", "env": "prod"},
+//		LevelLabel: "level",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	logger.AddSink("loki", sink)
+//	defer sink.Close()
+//
+// Records are batched and pushed from a goroutine of the sink. While Loki is unreachable, records are
+// buffered in memory and pushed again with exponential backoff, the oldest are dropped once the buffer is full.
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+// Defaults of Config
+const (
+	DefaultBatchSize  = 1000
+	DefaultBatchWait  = time.Second
+	DefaultBufferSize = 100000
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = time.Minute
+	DefaultTimeout    = 10 * time.Second
+)
+
+// Config configures a Loki sink.
+type Config struct {
+	URL        string            // push endpoint, e.g. http://loki:3100/loki/api/v1/push
+	Labels     map[string]string // static stream labels, e.g. service and env
+	LevelLabel string            // label carrying the level name, e.g. "level", no level label if empty
+	Headers    map[string]string // extra request headers, e.g. X-Scope-OrgID or Authorization
+	BatchSize  int               // records per push, DefaultBatchSize if 0
+	BatchWait  time.Duration     // maximum time a record waits for its batch to fill, DefaultBatchWait if 0
+	BufferSize int               // records kept while pushes fail, DefaultBufferSize if 0
+	MinBackoff time.Duration     // delay after the first failed push, doubled up to MaxBackoff, DefaultMinBackoff if 0
+	MaxBackoff time.Duration     // DefaultMaxBackoff if 0
+	Client     *http.Client      // client with DefaultTimeout if nil
+}
+
+// record is a buffered log line
+type record struct {
+	time  time.Time
+	level int64
+	line  string
+}
+
+// Sink pushes every written record to Loki. It implements logger.Sink.
+type Sink struct {
+	cfg Config
+
+	mu      sync.Mutex
+	pending []record // oldest first, the head is pushed next
+	evicted uint64   // records dropped from the head of pending for new ones
+	closed  bool
+
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	dropped atomic.Uint64
+	lastErr atomic.Value // stores errorValue
+}
+
+// New creates a sink pushing to the configured URL and starts its push goroutine.
+func New(cfg Config) (*Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("loki: url must not be empty")
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchWait == 0 {
+		cfg.BatchWait = DefaultBatchWait
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.BatchSize < 0 || cfg.BufferSize < cfg.BatchSize || cfg.BatchWait < 0 ||
+		cfg.MinBackoff < 0 || cfg.MaxBackoff < cfg.MinBackoff {
+		return nil, errors.New("loki: invalid batch, buffer or backoff settings")
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if _, ok := cfg.Labels[cfg.LevelLabel]; ok && cfg.LevelLabel != "" {
+		return nil, fmt.Errorf("loki: level label %s is also a static label", cfg.LevelLabel)
+	}
+
+	s := &Sink{
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write buffers the entry for the next push, dropping the oldest buffered record if the buffer is full.
+func (s *Sink) Write(entry *logger.Entry) error {
+	line := entry.Line
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	r := record{time: entry.Time, level: entry.Level, line: string(line)}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.dropped.Add(1)
+		return errors.New("loki: sink closed")
+	}
+	var err error
+	if len(s.pending) >= s.cfg.BufferSize {
+		s.pending = s.pending[1:]
+		s.evicted++
+		s.dropped.Add(1)
+		err = errors.New("loki: buffer full, dropped oldest record")
+	}
+	s.pending = append(s.pending, r)
+	full := len(s.pending) >= s.cfg.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// Dropped returns the number of records dropped because the buffer was full, the sink was closed
+// or Loki rejected them.
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Err returns the error of the last failed push, nil if the last push succeeded.
+func (s *Sink) Err() error {
+	v, _ := s.lastErr.Load().(errorValue)
+	return v.error
+}
+
+// Close pushes the buffered records once more and stops the sink. Remove the sink from the logger first,
+// later records are dropped. It returns the error of the final push, the records are dropped.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+
+	for {
+		batch, mark := s.batch()
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.push(context.Background(), batch)
+		if err != nil && isRetryable(err) {
+			s.mu.Lock()
+			s.dropped.Add(uint64(len(s.pending)))
+			s.pending = nil
+			s.mu.Unlock()
+			return err
+		}
+		s.commit(len(batch), mark, err)
+	}
+}
+
+// run pushes a batch when it is full or BatchWait after the previous push, backing off while pushes fail
+func (s *Sink) run() {
+	defer close(s.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
+	backoff := time.Duration(0)
+	timer := time.NewTimer(s.cfg.BatchWait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+			if backoff > 0 {
+				// Retried when the backoff timer fires
+				continue
+			}
+		case <-timer.C:
+		}
+
+		wait := s.cfg.BatchWait
+		if batch, mark := s.batch(); len(batch) > 0 {
+			err := s.push(ctx, batch)
+			switch {
+			case err == nil || !isRetryable(err):
+				s.commit(len(batch), mark, err)
+				backoff = 0
+			case ctx.Err() != nil:
+				return
+			default:
+				backoff = min(max(2*backoff, s.cfg.MinBackoff), s.cfg.MaxBackoff)
+				wait = backoff
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// batch returns up to BatchSize of the oldest buffered records, they stay buffered until committed.
+// The mark is passed to commit to account for records evicted meanwhile.
+func (s *Sink) batch() ([]record, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(len(s.pending), s.cfg.BatchSize)
+	return append([]record(nil), s.pending[:n]...), s.evicted
+}
+
+// commit removes a pushed batch of n records from the buffer, counting them as dropped if Loki rejected them
+func (s *Sink) commit(n int, mark uint64, err error) {
+	s.mu.Lock()
+	n = max(n-int(s.evicted-mark), 0)
+	s.pending = s.pending[n:]
+	s.mu.Unlock()
+	if err != nil {
+		s.dropped.Add(uint64(n))
+	}
+}
+
+// pushError is a failed push, retryable unless Loki rejected the request itself
+type pushError struct {
+	status int
+	err    error
+}
+
+func (e *pushError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pushError) Unwrap() error {
+	return e.err
+}
+
+// isRetryable reports whether a push may succeed later: network errors, rate limits and server errors
+func isRetryable(err error) bool {
+	var pe *pushError
+	if !errors.As(err, &pe) || pe.status == 0 {
+		return true
+	}
+	return pe.status == http.StatusTooManyRequests || pe.status >= 500
+}
+
+// push sends a batch as one request, with a stream per level if LevelLabel is set
+func (s *Sink) push(ctx context.Context, batch []record) (err error) {
+	defer func() { s.lastErr.Store(errorValue{err}) }()
+
+	body, err := s.encode(batch)
+	if err != nil {
+		return &pushError{status: http.StatusBadRequest, err: err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return &pushError{status: http.StatusBadRequest, err: fmt.Errorf("loki: failed to create request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return &pushError{err: fmt.Errorf("loki: push failed: %w", err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &pushError{status: resp.StatusCode, err: fmt.Errorf("loki: push failed: %s: %s", resp.Status, bytes.TrimSpace(msg))}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// errorValue wraps errors stored in lastErr, as atomic.Value requires a consistent type
+type errorValue struct {
+	error
+}
+
+// stream is a Loki stream of the push request
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encode builds the push request body of a batch
+func (s *Sink) encode(batch []record) ([]byte, error) {
+	var streams []*stream
+	byLevel := make(map[int64]*stream)
+	for _, r := range batch {
+		level := r.level
+		if s.cfg.LevelLabel == "" {
+			level = 0
+		}
+		st, ok := byLevel[level]
+		if !ok {
+			labels := make(map[string]string, len(s.cfg.Labels)+1)
+			for k, v := range s.cfg.Labels {
+				labels[k] = v
+			}
+			if s.cfg.LevelLabel != "" {
+				labels[s.cfg.LevelLabel] = logger.LevelString(level)
+			}
+			st = &stream{Stream: labels}
+			byLevel[level] = st
+			streams = append(streams, st)
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(r.time.UnixNano(), 10), r.line})
+	}
+	data, err := json.Marshal(struct {
+		Streams []*stream `json:"streams"`
+	}{streams})
+	if err != nil {
+		return nil, fmt.Errorf("loki: failed to encode batch: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"context"
+	"time"
+)
+
+// Maintenance goroutine state. It is started and stopped together with the processor.
+var (
+	maintainDone chan struct{} // closed when the maintenance goroutine exits
+
+	// maintainBarrier carries a channel closed by the maintenance goroutine once the work
+	// started before it received the request is complete
+	maintainBarrier = make(chan chan struct{})
+)
+
+// maintainLogs runs the directory maintenance in its own goroutine with its own tickers, so the processor
+// never stalls on directory scans or file deletions during bursts: disk space scans and cleanup, retention,
+// retention tiers and transient purges. It closes ready once its tickers exist, so clock ticks after the start
+// are not missed. It exits once ctx is done, then closes done.
+func maintainLogs(ctx context.Context, ready, done chan struct{}) {
+	defer close(done)
+
+	// Disk space is scanned at most once per diskCheckInterval, the ticker bounds the delay after it expires
+	diskTicker := newTicker(flushTimer)
+	defer diskTicker.Stop()
+
+	var retentionChan <-chan time.Time // nil channel
+	if (retentionPeriod > 0 || len(retentionTiers) > 0) && retentionCheck > 0 {
+		retentionTicker := newTicker(retentionCheck)
+		defer retentionTicker.Stop()
+		retentionChan = retentionTicker.C() // assign channel only if ticker exists
+		updateEarliestFileTime()
+	}
+
+	var transientChan <-chan time.Time
+	if transientRetention > 0 {
+		transientTicker := newTicker(transientCheckInterval())
+		defer transientTicker.Stop()
+		transientChan = transientTicker.C()
+	}
+
+	close(ready)
+
+	refreshDiskSpace()
+	for {
+		select {
+		case <-diskTicker.C():
+			refreshDiskSpace()
+		case <-diskCheckRequest:
+			refreshDiskSpace()
+		case <-transientChan:
+			purgeTransientLogs()
+		case <-retentionChan:
+			checkRetention()
+		case idle := <-maintainBarrier:
+			// A scan requested before the barrier is due work, it is done first
+			select {
+			case <-diskCheckRequest:
+				refreshDiskSpace()
+			default:
+			}
+			close(idle)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkRetention deletes the files older than the retention period and applies the retention tiers.
+// It must only be called from the maintenance goroutine.
+func checkRetention() {
+	// Only process if retention is enabled
+	if retentionPeriod > 0 {
+		// Files rotated since a scan that found none are only seen by scanning again
+		if earliest, ok := earliestFileTime.Load().(time.Time); !ok || earliest.IsZero() {
+			updateEarliestFileTime()
+		}
+		// Safe type assertion and non-zero check
+		if earliest, ok := earliestFileTime.Load().(time.Time); ok {
+			// Only process if we have a valid timestamp
+			if !earliest.IsZero() && clockNow().Sub(earliest) > retentionPeriod {
+				ctx := context.Background()
+				if err := cleanExpiredLogs(ctx, earliest); err == nil {
+					// Only update if cleanup succeeded
+					updateEarliestFileTime()
+				}
+			}
+		}
+	}
+	applyRetentionTiers()
+}
+
+// waitMaintenance waits until the maintenance work running or due when it is called is complete,
+// or ctx is done. It returns immediately if the maintenance goroutine is not running.
+func waitMaintenance(ctx context.Context) error {
+	mu.RLock()
+	running := processCtx
+	mu.RUnlock()
+	if running == nil {
+		return nil
+	}
+
+	idle := make(chan struct{})
+	select {
+	case maintainBarrier <- idle:
+	case <-running.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strconv"
+)
+
+// SchemaVersion is the version of the serialized record layout written as schema_version with IncludeSchema.
+// It is incremented when a release changes the members or encoding of records of an existing format.
+const SchemaVersion = 1
+
+// modulePath is the module of the logger, used to look up its version in the build info
+const modulePath = "github.com/LixenWraith/logger"
+
+// Record metadata state, metaFields are written to every record after the level
+var (
+	includeHost   bool
+	includePID    bool
+	includeSchema bool
+	staticFields  map[string]string
+	metaFields    []Field
+)
+
+// reservedKeys are the keys written by the json format itself, not usable as static fields
+var reservedKeys = []string{"time", "level", "seq", "goroutine", "worker", "truncated", "original_bytes", "trace", "deadline_remaining", "msg", "fields", "hash",
+	auditFinalKey, auditRecordsKey}
+
+// moduleVersion returns the version of the logger module built into the binary, "devel" when it is the main
+// module or built without module information
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if info.Main.Path == modulePath {
+		if v := info.Main.Version; v != "" && v != "(devel)" {
+			return v
+		}
+		return "devel"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "devel"
+}
+
+// configureMetadata sets the metadata written to every record: the host name, the process id, the schema
+// and logger versions, and the static fields sorted by key.
+func configureMetadata(host, pid, schema bool, static map[string]string) error {
+	var fields []Field
+	if host {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get host name: %w", err)
+		}
+		fields = append(fields, Str("host", hostname))
+	}
+	if pid {
+		fields = append(fields, Int("pid", os.Getpid()))
+	}
+	if schema {
+		fields = append(fields, Int("schema_version", SchemaVersion), Str("logger_version", moduleVersion()))
+	}
+
+	if err := validateStaticFields(host, pid, schema, static); err != nil {
+		return err
+	}
+	keys := slices.Sorted(maps.Keys(static))
+	for _, key := range keys {
+		fields = append(fields, Str(key, static[key]))
+	}
+
+	includeHost, includePID, includeSchema, staticFields = host, pid, schema, static
+	metaFields = fields
+	return nil
+}
+
+// validateStaticFields rejects static field keys that are empty, reserved or conflict with included metadata
+func validateStaticFields(host, pid, schema bool, static map[string]string) error {
+	for key := range static {
+		if key == "" || slices.Contains(reservedKeys, key) {
+			return fmt.Errorf("invalid static field key: %s", strconv.Quote(key))
+		}
+		if (host && key == "host") || (pid && key == "pid") ||
+			(schema && (key == "schema_version" || key == "logger_version")) {
+			return fmt.Errorf("static field %s conflicts with the included %s", key, key)
+		}
+	}
+	return nil
+}
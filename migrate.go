@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// migrationEvent identifies format migration markers in the "event" field
+const migrationEvent = "format_migration"
+
+// retireCurrentFile closes the active file on reconfiguration. If the format changes, a migration marker
+// is written first, so parsers of the file see why it ends. It returns the name of the file and, if the
+// format changes, the running format.
+// It must be called with mu held and the processor stopped, before the new config is applied.
+func retireCurrentFile(ctx context.Context, nextFormat string) (file, previousFormat string) {
+	f := currentFile.Load().(*os.File)
+	if f == nil {
+		return "", ""
+	}
+	if nextFormat != "" && nextFormat != format {
+		previousFormat = format
+		marker := migrationRecord("Log format changed, continued in a new file", format, nextFormat)
+		writeRecord(newSerializer(), &marker)
+		// Writing may have rotated the file
+		f = currentFile.Load().(*os.File)
+	}
+	file = filepath.Base(f.Name())
+	if err := closeCurrentFile(ctx); err != nil {
+		reportError(err)
+	}
+	currentFile.Store((*os.File)(nil))
+	return file, previousFormat
+}
+
+// writeMigrationMarker writes the marker opening the first file in the new format, naming the previous file.
+// It must be called with mu held and the processor stopped, after the new config is applied.
+func writeMigrationMarker(from, to, previousFile string) {
+	marker := migrationRecord("Log format changed, continued from the previous file", from, to)
+	marker.Fields = append(marker.Fields, Str("previous_file", previousFile))
+	writeRecord(newSerializer(), &marker)
+	flushWrites()
+}
+
+// migrationRecord builds a format migration marker:
+//
+//	event=format_migration from_format=F to_format=T
+//
+// It is written regardless of FileMinLevel.
+func migrationRecord(msg, from, to string) logRecord {
+	record := logRecord{
+		LogCtx:    context.Background(),
+		Flags:     flags,
+		TimeStamp: clockNow(),
+		Level:     max(LevelInfo, fileMinLevel),
+		HasMsg:    true,
+		Msg:       msg,
+	}
+	record.Fields = []Field{
+		Str("event", migrationEvent),
+		Str("from_format", from),
+		Str("to_format", to),
+	}
+	return record
+}
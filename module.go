@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// loggerPackage is the import path of this package, its frames are skipped when resolving the caller
+const loggerPackage = "github.com/LixenWraith/logger"
+
+// maxModuleFrames bounds the frames inspected to find the first caller outside the logger
+const maxModuleFrames = 16
+
+// Module level overrides, replaced copy-on-write so logging reads them without locking
+var (
+	moduleMu     sync.Mutex
+	moduleLevels atomic.Pointer[map[string]int64]
+)
+
+// setLevel sets the minimum level of records without a module override, ending a running debug boost
+func setLevel(level int64) {
+	cancelDebugBoost()
+	logLevel.Store(level)
+}
+
+// currentLevel returns the minimum level of records without a module override, LevelDebug during a boost.
+// Before the first Init, it is the level set by setLevel or else LevelInfo.
+func currentLevel() int64 {
+	if level, ok := logLevel.Load().(int64); ok {
+		return level
+	}
+	return LevelInfo
+}
+
+// moduleKey is the context key of an explicit module name
+type moduleKey struct{}
+
+// setModuleLevel sets or, with clear, removes the level override of a module
+func setModuleLevel(module string, level int64, clear bool) {
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+
+	updated := make(map[string]int64)
+	if p := moduleLevels.Load(); p != nil {
+		for k, v := range *p {
+			updated[k] = v
+		}
+	}
+	module = strings.TrimSuffix(module, "/")
+	if clear {
+		delete(updated, module)
+	} else {
+		updated[module] = level
+	}
+	moduleLevels.Store(&updated)
+}
+
+// minLevel returns the minimum level for a record, taking module overrides into account.
+// The module is the one attached to the context, or the package of the calling function.
+func minLevel(logCtx context.Context) int64 {
+	p := moduleLevels.Load()
+	if p == nil || len(*p) == 0 {
+		return logLevel.Load().(int64)
+	}
+	if level, ok := moduleLevel(logCtx, *p); ok {
+		return level
+	}
+	return logLevel.Load().(int64)
+}
+
+// moduleLevel finds the override of the longest module matching the context module or caller package
+func moduleLevel(logCtx context.Context, levels map[string]int64) (int64, bool) {
+	var module string
+	if logCtx != nil {
+		module, _ = logCtx.Value(moduleKey{}).(string)
+	}
+	if module == "" {
+		module = callerPackage()
+	}
+
+	// Longest match first: the module itself, then its parent paths
+	for module != "" {
+		if level, ok := levels[module]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(module, '/')
+		if i < 0 {
+			break
+		}
+		module = module[:i]
+	}
+	return 0, false
+}
+
+// callerPackage returns the import path of the first calling function outside the logger and quick packages,
+// skipping the CallerSkip frames of wrapper packages beyond it
+func callerPackage() string {
+	var pcs [maxModuleFrames]uintptr
+	n := runtime.Callers(2, pcs[:]) // skip runtime.Callers and callerPackage
+	skip := callerSkip
+	for _, pc := range pcs[:n] {
+		// FuncForPC reports the innermost function of inlined calls
+		fn := runtime.FuncForPC(pc - 1)
+		if fn == nil {
+			continue
+		}
+		pkg := packageOf(fn.Name())
+		if pkg == loggerPackage || pkg == loggerPackage+"/quick" {
+			continue
+		}
+		if skip == 0 {
+			return pkg
+		}
+		skip--
+	}
+	return ""
+}
+
+// packageOf extracts the import path from a fully qualified function name,
+// e.g. "github.com/acme/app/db.(*Store).Get" yields "github.com/acme/app/db".
+func packageOf(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
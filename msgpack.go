@@ -0,0 +1,507 @@
+package logger
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// FormatMsgpack is the format of records as MessagePack maps with the members of the json format,
+// written back to back without separators
+const FormatMsgpack = "msgpack"
+
+// MessagePack type bytes
+const (
+	mpNil     = 0xc0
+	mpFalse   = 0xc2
+	mpTrue    = 0xc3
+	mpBin8    = 0xc4
+	mpBin16   = 0xc5
+	mpBin32   = 0xc6
+	mpFloat32 = 0xca
+	mpFloat64 = 0xcb
+	mpUint8   = 0xcc
+	mpUint16  = 0xcd
+	mpUint32  = 0xce
+	mpUint64  = 0xcf
+	mpInt8    = 0xd0
+	mpInt16   = 0xd1
+	mpInt32   = 0xd2
+	mpInt64   = 0xd3
+	mpStr8    = 0xd9
+	mpStr16   = 0xda
+	mpStr32   = 0xdb
+	mpArray16 = 0xdc
+	mpArray32 = 0xdd
+	mpMap16   = 0xde
+	mpMap32   = 0xdf
+)
+
+// msgpackSizes are the sizes of the value or length following the type byte of the non-fix types
+var msgpackSizes = map[byte]int{
+	mpBin8: 1, mpBin16: 2, mpBin32: 4, mpStr8: 1, mpStr16: 2, mpStr32: 4, mpArray16: 2, mpArray32: 4, mpMap16: 2, mpMap32: 4,
+	mpUint8: 1, mpUint16: 2, mpUint32: 4, mpUint64: 8, mpInt8: 1, mpInt16: 2, mpInt32: 4, mpInt64: 8, mpFloat32: 4, mpFloat64: 8,
+}
+
+// msgpackMaxDepth bounds the nesting of decoded values
+const msgpackMaxDepth = 64
+
+// pack returns the MessagePack encoding of the JSON record in line, in a buffer of the serializer.
+// Lines that are not a JSON object, such as raw lines of other formats, are packed as a map with the line as "msg".
+func (s *serializer) pack(line []byte) []byte {
+	if len(line) > 0 && line[0] == '{' {
+		if out, rest, err := packJSON(s.packed[:0], line); err == nil && len(skipSpace(rest)) == 0 {
+			s.packed = out
+			return s.packed
+		}
+	}
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	s.packed = append(s.packed[:0], 0x81)
+	s.packed = appendMsgpackStr(s.packed, "msg")
+	s.packed = appendMsgpackStr(s.packed, string(line))
+	return s.packed
+}
+
+// skipSpace returns src without leading JSON whitespace
+func skipSpace(src []byte) []byte {
+	for len(src) > 0 && (src[0] == ' ' || src[0] == '\n' || src[0] == '\r' || src[0] == '\t') {
+		src = src[1:]
+	}
+	return src
+}
+
+// packJSON appends the MessagePack encoding of the JSON value at the start of src to dst and returns
+// the rest of src. Unknown escape sequences, as written for control characters, keep the escaped byte.
+func packJSON(dst, src []byte) ([]byte, []byte, error) {
+	src = skipSpace(src)
+	if len(src) == 0 {
+		return dst, src, fmt.Errorf("unexpected end of JSON")
+	}
+	switch c := src[0]; {
+	case c == '{' || c == '[':
+		return packContainer(dst, src)
+	case c == '"':
+		return packString(dst, src)
+	case c == 't' && len(src) >= 4 && string(src[:4]) == "true":
+		return append(dst, mpTrue), src[4:], nil
+	case c == 'f' && len(src) >= 5 && string(src[:5]) == "false":
+		return append(dst, mpFalse), src[5:], nil
+	case c == 'n' && len(src) >= 4 && string(src[:4]) == "null":
+		return append(dst, mpNil), src[4:], nil
+	case c == '-' || c >= '0' && c <= '9':
+		return packNumber(dst, src)
+	}
+	return dst, src, fmt.Errorf("invalid JSON at %q", src[0])
+}
+
+// packContainer packs an object or array. The member count is only known at the end, a fixmap or fixarray
+// header is reserved and widened if needed.
+func packContainer(dst, src []byte) ([]byte, []byte, error) {
+	isMap := src[0] == '{'
+	end := byte(']')
+	if isMap {
+		end = '}'
+	}
+	header := len(dst)
+	dst = append(dst, 0)
+	src = skipSpace(src[1:])
+
+	var err error
+	n := 0
+	for len(src) > 0 && src[0] != end {
+		if n > 0 {
+			if src[0] != ',' {
+				return dst, src, fmt.Errorf("expected ',' in JSON")
+			}
+			src = skipSpace(src[1:])
+		}
+		if isMap {
+			if len(src) == 0 || src[0] != '"' {
+				return dst, src, fmt.Errorf("expected object key in JSON")
+			}
+			if dst, src, err = packString(dst, src); err != nil {
+				return dst, src, err
+			}
+			if src = skipSpace(src); len(src) == 0 || src[0] != ':' {
+				return dst, src, fmt.Errorf("expected ':' in JSON")
+			}
+			src = src[1:]
+		}
+		if dst, src, err = packJSON(dst, src); err != nil {
+			return dst, src, err
+		}
+		src = skipSpace(src)
+		n++
+	}
+	if len(src) == 0 {
+		return dst, src, fmt.Errorf("unexpected end of JSON")
+	}
+
+	fix, size16, size32 := byte(0x90), byte(mpArray16), byte(mpArray32)
+	if isMap {
+		fix, size16, size32 = 0x80, mpMap16, mpMap32
+	}
+	if n < 16 {
+		dst[header] = fix | byte(n)
+		return dst, src[1:], nil
+	}
+	var wide []byte
+	if n <= math.MaxUint16 {
+		wide = binary.BigEndian.AppendUint16([]byte{size16}, uint16(n))
+	} else {
+		wide = binary.BigEndian.AppendUint32([]byte{size32}, uint32(n))
+	}
+	dst = append(dst, wide[1:]...)
+	copy(dst[header+len(wide):], dst[header+1:len(dst)-len(wide)+1])
+	copy(dst[header:], wide)
+	return dst, src[1:], nil
+}
+
+// packString packs a JSON string, unescaped. The header is sized for the escaped length, which is never shorter.
+func packString(dst, src []byte) ([]byte, []byte, error) {
+	end := 1
+	escaped := false
+	for end < len(src) && src[end] != '"' {
+		if src[end] == '\\' {
+			escaped = true
+			end++
+		}
+		end++
+	}
+	if end >= len(src) {
+		return dst, src, fmt.Errorf("unterminated JSON string")
+	}
+	raw := src[1:end]
+	if !escaped {
+		dst = appendStrHeader(dst, len(raw))
+		return append(dst, raw...), src[end+1:], nil
+	}
+
+	header := len(dst)
+	dst = appendStrHeader(dst, len(raw))
+	start := len(dst)
+	dst = appendUnescaped(dst, raw)
+	setMsgpackLength(dst[header:start], len(dst)-start)
+	return dst, src[end+1:], nil
+}
+
+// appendUnescaped appends a JSON string body with its escape sequences resolved
+func appendUnescaped(dst, raw []byte) []byte {
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 == len(raw) {
+			dst = append(dst, raw[i])
+			continue
+		}
+		i++
+		switch raw[i] {
+		case 'b':
+			dst = append(dst, '\b')
+		case 'f':
+			dst = append(dst, '\f')
+		case 'n':
+			dst = append(dst, '\n')
+		case 'r':
+			dst = append(dst, '\r')
+		case 't':
+			dst = append(dst, '\t')
+		case 'u':
+			r, n := decodeEscapedRune(raw[i+1:])
+			if n == 0 {
+				dst = append(dst, 'u')
+				continue
+			}
+			dst = utf8.AppendRune(dst, r)
+			i += n
+		default:
+			dst = append(dst, raw[i])
+		}
+	}
+	return dst
+}
+
+// decodeEscapedRune decodes the hex digits of a \u escape, and the low surrogate following a high one,
+// returning the rune and the number of bytes consumed, 0 if invalid
+func decodeEscapedRune(b []byte) (rune, int) {
+	if len(b) < 4 {
+		return 0, 0
+	}
+	v, err := strconv.ParseUint(string(b[:4]), 16, 16)
+	if err != nil {
+		return 0, 0
+	}
+	r := rune(v)
+	if utf16.IsSurrogate(r) && len(b) >= 10 && b[4] == '\\' && b[5] == 'u' {
+		if low, err := strconv.ParseUint(string(b[6:10]), 16, 16); err == nil {
+			if pair := utf16.DecodeRune(r, rune(low)); pair != utf8.RuneError {
+				return pair, 10
+			}
+		}
+	}
+	return r, 4
+}
+
+// packNumber packs a JSON number, integers as the smallest integer type and other numbers as float64
+func packNumber(dst, src []byte) ([]byte, []byte, error) {
+	end, isFloat := 0, false
+	for end < len(src) {
+		c := src[end]
+		if c == '.' || c == 'e' || c == 'E' {
+			isFloat = true
+		} else if !(c >= '0' && c <= '9' || c == '-' || c == '+') {
+			break
+		}
+		end++
+	}
+	num := string(src[:end])
+	if !isFloat {
+		if v, err := strconv.ParseInt(num, 10, 64); err == nil {
+			return appendMsgpackInt(dst, v), src[end:], nil
+		}
+		if v, err := strconv.ParseUint(num, 10, 64); err == nil {
+			dst = append(dst, mpUint64)
+			return binary.BigEndian.AppendUint64(dst, v), src[end:], nil
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return dst, src, fmt.Errorf("invalid JSON number %q", num)
+	}
+	dst = append(dst, mpFloat64)
+	return binary.BigEndian.AppendUint64(dst, math.Float64bits(f)), src[end:], nil
+}
+
+// appendMsgpackInt appends an integer in its smallest MessagePack encoding
+func appendMsgpackInt(dst []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= 0x7f:
+		return append(dst, byte(v))
+	case v < 0 && v >= -32:
+		return append(dst, byte(v))
+	case v >= 0 && v <= math.MaxUint8:
+		return append(dst, mpUint8, byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, mpUint16), uint16(v))
+	case v >= 0 && v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, mpUint32), uint32(v))
+	case v >= 0:
+		return binary.BigEndian.AppendUint64(append(dst, mpUint64), uint64(v))
+	case v >= math.MinInt8:
+		return append(dst, mpInt8, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(dst, mpInt16), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(dst, mpInt32), uint32(v))
+	}
+	return binary.BigEndian.AppendUint64(append(dst, mpInt64), uint64(v))
+}
+
+// appendMsgpackStr appends a string
+func appendMsgpackStr(dst []byte, s string) []byte {
+	return append(appendStrHeader(dst, len(s)), s...)
+}
+
+// appendStrHeader appends the header of a string of n bytes
+func appendStrHeader(dst []byte, n int) []byte {
+	switch {
+	case n <= 31:
+		return append(dst, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		return append(dst, mpStr8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, mpStr16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(dst, mpStr32), uint32(n))
+}
+
+// setMsgpackLength rewrites the length of a string header for a length that fits the same header size
+func setMsgpackLength(header []byte, n int) {
+	switch len(header) {
+	case 1:
+		header[0] = 0xa0 | byte(n)
+	case 2:
+		header[1] = byte(n)
+	case 3:
+		binary.BigEndian.PutUint16(header[1:], uint16(n))
+	default:
+		binary.BigEndian.PutUint32(header[1:], uint32(n))
+	}
+}
+
+// msgpackToJSON converts a stream of MessagePack records to JSON, one record per line
+func msgpackToJSON(data []byte) ([]byte, error) {
+	var out []byte
+	for len(data) > 0 {
+		var err error
+		if out, data, err = appendMsgpackJSON(out, data, 0); err != nil {
+			return out, err
+		}
+		out = append(out, '\n')
+	}
+	return out, nil
+}
+
+// isMsgpack reports whether data starts with a MessagePack map, which no text record does
+func isMsgpack(data []byte) bool {
+	return len(data) > 0 && (data[0]&0xf0 == 0x80 || data[0] == mpMap16 || data[0] == mpMap32)
+}
+
+// appendMsgpackJSON appends the JSON form of the MessagePack value at the start of data and returns the rest.
+// Binary values are written as base64 strings, map keys that are not strings as their JSON text.
+func appendMsgpackJSON(dst, data []byte, depth int) ([]byte, []byte, error) {
+	if len(data) == 0 {
+		return dst, data, fmt.Errorf("truncated msgpack record")
+	}
+	if depth > msgpackMaxDepth {
+		return dst, data, fmt.Errorf("msgpack record nested too deeply")
+	}
+	c, data := data[0], data[1:]
+	switch {
+	case c <= 0x7f:
+		return strconv.AppendInt(dst, int64(c), 10), data, nil
+	case c >= 0xe0:
+		return strconv.AppendInt(dst, int64(int8(c)), 10), data, nil
+	case c&0xf0 == 0x80:
+		return appendMsgpackMap(dst, data, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return appendMsgpackArray(dst, data, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return appendMsgpackString(dst, data, int(c&0x1f))
+	}
+
+	size := msgpackSizes[c]
+	switch c {
+	case mpNil:
+		return append(dst, "null"...), data, nil
+	case mpFalse:
+		return append(dst, "false"...), data, nil
+	case mpTrue:
+		return append(dst, "true"...), data, nil
+	}
+	if size == 0 {
+		return dst, data, fmt.Errorf("unsupported msgpack type 0x%02x", c)
+	}
+	if len(data) < size {
+		return dst, data, fmt.Errorf("truncated msgpack record")
+	}
+	var v uint64
+	for _, b := range data[:size] {
+		v = v<<8 | uint64(b)
+	}
+	data = data[size:]
+
+	switch c {
+	case mpUint8, mpUint16, mpUint32, mpUint64:
+		return strconv.AppendUint(dst, v, 10), data, nil
+	case mpInt8:
+		return strconv.AppendInt(dst, int64(int8(v)), 10), data, nil
+	case mpInt16:
+		return strconv.AppendInt(dst, int64(int16(v)), 10), data, nil
+	case mpInt32:
+		return strconv.AppendInt(dst, int64(int32(v)), 10), data, nil
+	case mpInt64:
+		return strconv.AppendInt(dst, int64(v), 10), data, nil
+	case mpFloat32:
+		return appendJSONFloat(dst, float64(math.Float32frombits(uint32(v)))), data, nil
+	case mpFloat64:
+		return appendJSONFloat(dst, math.Float64frombits(v)), data, nil
+	case mpStr8, mpStr16, mpStr32:
+		return appendMsgpackString(dst, data, int(v))
+	case mpArray16, mpArray32:
+		return appendMsgpackArray(dst, data, int(v), depth)
+	case mpMap16, mpMap32:
+		return appendMsgpackMap(dst, data, int(v), depth)
+	}
+	// Binary
+	if uint64(len(data)) < v {
+		return dst, data, fmt.Errorf("truncated msgpack record")
+	}
+	dst = append(dst, '"')
+	dst = base64.StdEncoding.AppendEncode(dst, data[:v])
+	return append(dst, '"'), data[v:], nil
+}
+
+// appendMsgpackString appends a string of n bytes as a JSON string
+func appendMsgpackString(dst, data []byte, n int) ([]byte, []byte, error) {
+	if len(data) < n {
+		return dst, data, fmt.Errorf("truncated msgpack record")
+	}
+	return appendJSONQuoted(dst, string(data[:n])), data[n:], nil
+}
+
+// appendMsgpackArray appends an array of n values as a JSON array
+func appendMsgpackArray(dst, data []byte, n, depth int) ([]byte, []byte, error) {
+	dst = append(dst, '[')
+	var err error
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		if dst, data, err = appendMsgpackJSON(dst, data, depth+1); err != nil {
+			return dst, data, err
+		}
+	}
+	return append(dst, ']'), data, nil
+}
+
+// appendMsgpackMap appends a map of n pairs as a JSON object
+func appendMsgpackMap(dst, data []byte, n, depth int) ([]byte, []byte, error) {
+	dst = append(dst, '{')
+	var err error
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		if len(data) > 0 && (data[0]&0xe0 == 0xa0 || data[0] >= mpStr8 && data[0] <= mpStr32) {
+			dst, data, err = appendMsgpackJSON(dst, data, depth+1)
+		} else {
+			var key []byte
+			if key, data, err = appendMsgpackJSON(nil, data, depth+1); err == nil {
+				dst = appendJSONQuoted(dst, string(key))
+			}
+		}
+		if err != nil {
+			return dst, data, err
+		}
+		dst = append(dst, ':')
+		if dst, data, err = appendMsgpackJSON(dst, data, depth+1); err != nil {
+			return dst, data, err
+		}
+	}
+	return append(dst, '}'), data, nil
+}
+
+// appendJSONFloat appends a float as a JSON number, null for values JSON cannot represent
+func appendJSONFloat(dst []byte, f float64) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return append(dst, "null"...)
+	}
+	return strconv.AppendFloat(dst, f, 'g', -1, 64)
+}
+
+// appendJSONQuoted appends s as a JSON string, escaping quotes, backslashes and control characters
+func appendJSONQuoted(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < 0x20:
+			dst = fmt.Appendf(dst, "\\u%04x", c)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
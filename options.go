@@ -0,0 +1,360 @@
+package logger
+
+import "time"
+
+// Option configures the logger in Init. Options are applied in order on top of the defaults, or on top
+// of the running configuration when the logger is already initialized.
+//
+// Unlike the fields of a *LoggerConfig, which is an Option as well and only overrides with non-zero
+// values or keys listed in its Set, functional options set their value as given, including zero values
+// such as disabling timestamps.
+type Option interface {
+	apply(cfg *LoggerConfig)
+}
+
+// optionFunc adapts a function to the Option interface
+type optionFunc func(cfg *LoggerConfig)
+
+func (f optionFunc) apply(cfg *LoggerConfig) {
+	f(cfg)
+}
+
+// apply merges the non-zero and explicitly set values of the config, making *LoggerConfig usable as an Option.
+func (c *LoggerConfig) apply(cfg *LoggerConfig) {
+	if c == nil {
+		return
+	}
+	*cfg = *mergeConfigs(cfg, c)
+}
+
+// WithLevel sets the minimum level of logged records.
+func WithLevel(level int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.Level = level })
+}
+
+// WithName sets the base name of log files.
+func WithName(name string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.Name = name })
+}
+
+// WithDirectory sets the directory log files are written to.
+func WithDirectory(dir string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.Directory = dir })
+}
+
+// WithFormat sets the output format, "txt", "json", "ecs", "console" or "msgpack".
+func WithFormat(format string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.Format = format })
+}
+
+// WithJSONIndent enables or disables indented multi-line records of the json and ecs formats, for development.
+func WithJSONIndent(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.JSONIndent = enabled })
+}
+
+// WithMaxRecordBytes truncates records serialized to more than n bytes, 0 disables the limit.
+func WithMaxRecordBytes(n int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.MaxRecordBytes = n })
+}
+
+// WithFoldLines writes newlines in strings of txt and console records as indented continuation lines instead of
+// escapes, for reading stack traces and multi-line errors. Files are no longer one record per line.
+func WithFoldLines(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.FoldLines = enabled })
+}
+
+// WithExtension sets the log file extension, without dot. An empty extension uses the format.
+func WithExtension(ext string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.Extension = ext })
+}
+
+// WithShowTimestamp enables or disables record timestamps.
+func WithShowTimestamp(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.ShowTimestamp = enabled })
+}
+
+// WithShowLevel enables or disables record levels.
+func WithShowLevel(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.ShowLevel = enabled })
+}
+
+// WithShowSequence numbers records in the order of the logging calls.
+func WithShowSequence(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.ShowSequence = enabled })
+}
+
+// WithShowDeadline enables or disables the remaining time until the context deadline.
+func WithShowDeadline(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.ShowDeadline = enabled })
+}
+
+// WithBufferSize sets the channel buffer size in records.
+func WithBufferSize(size int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.BufferSize = size })
+}
+
+// WithMaxSizeMB sets the size of a log file triggering rotation, 0 disables rotation.
+func WithMaxSizeMB(mb int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.MaxSizeMB = mb })
+}
+
+// WithMaxTotalSizeMB sets the total size of the log directory triggering cleanup, 0 disables the limit.
+func WithMaxTotalSizeMB(mb int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.MaxTotalSizeMB = mb })
+}
+
+// WithMinDiskFreeMB sets the free disk space below which cleanup starts, 0 disables the limit.
+func WithMinDiskFreeMB(mb int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.MinDiskFreeMB = mb })
+}
+
+// WithMaxSizeBytes sets the size of a log file triggering rotation in bytes, overriding WithMaxSizeMB.
+func WithMaxSizeBytes(n int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.MaxSizeBytes = n })
+}
+
+// WithMaxTotalSizeBytes sets the total size of the log directory triggering cleanup in bytes, overriding WithMaxTotalSizeMB.
+func WithMaxTotalSizeBytes(n int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.MaxTotalSizeBytes = n })
+}
+
+// WithMinDiskFreeBytes sets the free disk space below which cleanup starts in bytes, overriding WithMinDiskFreeMB.
+func WithMinDiskFreeBytes(n int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.MinDiskFreeBytes = n })
+}
+
+// WithFlushTimer sets the interval of forced writes to disk, with millisecond resolution.
+func WithFlushTimer(d time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.FlushTimer = d.Milliseconds() })
+}
+
+// WithStructuredTrace writes the trace of json and ecs records as an array of frames with function, file and line.
+func WithStructuredTrace(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.StructuredTrace = enabled })
+}
+
+// WithTraceWithLines adds the file base name and line to every function of the trace.
+func WithTraceWithLines(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.TraceWithLines = enabled })
+}
+
+// WithCallerSkip skips n additional frames to reach the caller, for packages wrapping the logging functions.
+// A wrapper calling logger.Info from its own helper passes 1, so traces and module overrides resolve the
+// caller of the helper.
+func WithCallerSkip(n int) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.CallerSkip = int64(n) })
+}
+
+// WithTraceDepth sets the default function call trace depth, 0-10, 0 disables tracing.
+func WithTraceDepth(depth int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.TraceDepth = depth })
+}
+
+// WithRetention sets how long log files are kept and how often expired files are checked.
+// A zero period disables retention.
+func WithRetention(period, checkInterval time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) {
+		cfg.RetentionPeriod = period.Hours()
+		cfg.RetentionCheckInterval = checkInterval.Minutes()
+	})
+}
+
+// WithRetentionTiers sets retention tiers as "<hours>:<level>", e.g. "24:warn": files older than the hours
+// keep only the records of the level and above.
+func WithRetentionTiers(tiers ...string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.RetentionTiers = append([]string{}, tiers...) })
+}
+
+// WithRetentionExclude sets filename glob patterns never deleted by retention or disk cleanup.
+func WithRetentionExclude(patterns ...string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.RetentionExclude = append([]string{}, patterns...) })
+}
+
+// WithStatsFile enables or disables the lifetime stats file.
+func WithStatsFile(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.StatsFile = enabled })
+}
+
+// WithEncryptionKey sets the hex encoded AES key encrypting records on disk, empty disables encryption.
+func WithEncryptionKey(key string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.EncryptionKey = key })
+}
+
+// WithPipeline sets the stages applied to records before writing, in order.
+func WithPipeline(stages ...string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.Pipeline = append([]string{}, stages...) })
+}
+
+// WithLazyOpen enables or disables deferring file creation errors to the first write.
+func WithLazyOpen(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.LazyOpen = enabled })
+}
+
+// WithAuditChain enables or disables the audit hash chain.
+func WithAuditChain(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.AuditChain = enabled })
+}
+
+// WithFileHeaders enables or disables the header and footer records of every log file.
+func WithFileHeaders(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.FileHeaders = enabled })
+}
+
+// WithRecordChecksum enables or disables the CRC-32C checksum appended to every record.
+func WithRecordChecksum(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.RecordChecksum = enabled })
+}
+
+// WithMinRotateInterval sets the minimum time between size based rotations, with millisecond resolution.
+func WithMinRotateInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.MinRotateInterval = d.Milliseconds() })
+}
+
+// WithDiskCheckInterval sets how long the result of a disk space scan is reused, with millisecond resolution.
+// A rotation always makes the next record scan again, 0 scans on every record.
+func WithDiskCheckInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.DiskCheckInterval = d.Milliseconds() })
+}
+
+// WithConsole also writes records to a console target, ConsoleStdout or ConsoleStderr, empty disables it.
+func WithConsole(target string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.Console = target })
+}
+
+// WithFileMinLevel sets the minimum level written to the log file, in addition to the logger level.
+func WithFileMinLevel(level int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.FileMinLevel = level })
+}
+
+// WithConsoleMinLevel sets the minimum level written to the console, in addition to the logger level.
+func WithConsoleMinLevel(level int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.ConsoleMinLevel = level })
+}
+
+// WithSyncPolicy sets when records written to the log file are synced to disk: SyncInterval,
+// SyncEveryWrite or SyncEveryError.
+func WithSyncPolicy(policy string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.SyncPolicy = policy })
+}
+
+// WithQueueType selects the buffer between logging calls and the processor, QueueChannel or QueueRing.
+func WithQueueType(queueType string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.QueueType = queueType })
+}
+
+// WithOverflowPolicy sets the behavior when the buffer is full, and the maximum blocking time of
+// OverflowBlockWithTimeout with millisecond resolution.
+func WithOverflowPolicy(policy string, timeout time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) {
+		cfg.OverflowPolicy = policy
+		cfg.OverflowTimeout = timeout.Milliseconds()
+	})
+}
+
+// WithShedding drops records below the level, without waiting for room, once the buffer occupancy reaches the
+// threshold between 0 and 1, keeping the rest of the buffer for more severe records. A threshold of 0 disables it.
+func WithShedding(threshold float64, level int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) {
+		cfg.ShedThreshold = threshold
+		cfg.ShedLevel = level
+	})
+}
+
+// WithJournalSizeKB sets the size of the memory-mapped journal recovering queued records after a crash, 0 disables it.
+func WithJournalSizeKB(kb int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.JournalSizeKB = kb })
+}
+
+// WithSpillMaxMB sets the size of the on-disk queue for records overflowing the buffer, 0 drops them.
+func WithSpillMaxMB(mb int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.SpillMaxMB = mb })
+}
+
+// WithAdmin sets the admin listener address and its bearer token, an empty address disables it.
+func WithAdmin(address, token string) Option {
+	return optionFunc(func(cfg *LoggerConfig) {
+		cfg.AdminAddress = address
+		cfg.AdminToken = token
+	})
+}
+
+// WithRecentSize keeps the latest records in memory for Recent and ServeHTTP, 0 disables it.
+func WithRecentSize(records int64) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.RecentSize = records })
+}
+
+// WithSignals enables signal controls: SIGHUP reopens the file, SIGUSR1 raises the level to debug for
+// debugDuration and SIGUSR2 logs the running counters.
+func WithSignals(enabled bool, debugDuration time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) {
+		cfg.Signals = enabled
+		cfg.SignalDebugDuration = debugDuration.Milliseconds()
+	})
+}
+
+// WithCleanupScope selects the files subject to size limits and retention, CleanupScopeName or CleanupScopeDirectory.
+func WithCleanupScope(scope string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.CleanupScope = scope })
+}
+
+// WithTransientRetention keeps records logged with ContextWithTransient in their own files, deleted after
+// the retention. Zero writes them to the log file.
+func WithTransientRetention(retention time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.TransientRetention = retention.Minutes() })
+}
+
+// WithDedup collapses records repeating the level and message of a record written within the window
+// into a summary record with "repeat_count", 0 disables it.
+func WithDedup(window time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.DedupWindow = window.Milliseconds() })
+}
+
+// WithHeartbeat writes a heartbeat record with the running counters every interval, 0 disables it.
+func WithHeartbeat(interval time.Duration) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.HeartbeatInterval = interval.Milliseconds() })
+}
+
+// WithIncludeHost writes the host name to every record.
+func WithIncludeHost(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.IncludeHost = enabled })
+}
+
+// WithIncludeSchema writes the schema version and the logger version to every record.
+func WithIncludeSchema(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.IncludeSchema = enabled })
+}
+
+// WithIncludeGoroutine writes the id of the logging goroutine to every record.
+func WithIncludeGoroutine(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.IncludeGoroutine = enabled })
+}
+
+// WithIncludePID writes the process id to every record.
+func WithIncludePID(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.IncludePID = enabled })
+}
+
+// WithStaticFields writes the fields to every record, replacing the static fields set before.
+func WithStaticFields(fields map[string]string) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.StaticFields = fields })
+}
+
+// WithStructuredErrors writes error values in json formats as objects with their message, type,
+// wrapped causes and stack.
+func WithStructuredErrors(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.StructuredErrors = enabled })
+}
+
+// WithTimestamp sets the timestamp format, e.g. TimestampUnixMilli or a Go time layout, and the time zone
+// of timestamps such as "UTC", empty keeping local time.
+func WithTimestamp(format, zone string) Option {
+	return optionFunc(func(cfg *LoggerConfig) {
+		cfg.TimestampFormat = format
+		cfg.TimeZone = zone
+	})
+}
+
+// WithDurationNanos writes time.Duration values as integer nanoseconds instead of their String form, e.g. 150ms.
+func WithDurationNanos(enabled bool) Option {
+	return optionFunc(func(cfg *LoggerConfig) { cfg.DurationNanos = enabled })
+}
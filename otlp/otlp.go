@@ -0,0 +1,546 @@
+// Package otlp exports records written through the logger to an OpenTelemetry collector as OTLP log records
+// over HTTP, so the logger is an OpenTelemetry log source without a separate agent.
+//
+//	sink, err := otlp.New(otlp.Config{
+//		URL:      "http://otel-collector:4318/v1/logs",
+//		Resource: map[string]string{"service.name": "api", "deployment.environment": "prod"},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	logger.AddSink("otlp", sink)
+//	defer sink.Close()
+//
+// Records are batched into ExportLogsServiceRequest messages in the protobuf encoding and pushed from a goroutine
+// of the sink. While the collector is unreachable, records are buffered in memory and pushed again with exponential
+// backoff, the oldest are dropped once the buffer is full. gRPC is not supported, as it requires the gRPC module,
+// collectors receive OTLP over HTTP on port 4318 by default.
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LixenWraith/logger"
+)
+
+// Defaults of Config
+const (
+	DefaultBatchSize  = 1000
+	DefaultBatchWait  = time.Second
+	DefaultBufferSize = 100000
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = time.Minute
+	DefaultTimeout    = 10 * time.Second
+	DefaultScope      = "github.com/LixenWraith/logger"
+)
+
+// Config configures an OTLP sink.
+type Config struct {
+	URL        string            // OTLP/HTTP logs endpoint, e.g. http://otel-collector:4318/v1/logs
+	Resource   map[string]string // resource attributes, e.g. service.name, host.name is added if not set
+	Scope      string            // instrumentation scope name, DefaultScope if empty
+	Headers    map[string]string // extra request headers, e.g. Authorization
+	Gzip       bool              // compress request bodies
+	BatchSize  int               // records per push, DefaultBatchSize if 0
+	BatchWait  time.Duration     // maximum time a record waits for its batch to fill, DefaultBatchWait if 0
+	BufferSize int               // records kept while pushes fail, DefaultBufferSize if 0
+	MinBackoff time.Duration     // delay after the first failed push, doubled up to MaxBackoff, DefaultMinBackoff if 0
+	MaxBackoff time.Duration     // DefaultMaxBackoff if 0
+	Client     *http.Client      // client with DefaultTimeout if nil
+}
+
+// record is a buffered log record, encoded as an OTLP LogRecord
+type record []byte
+
+// Sink pushes every written record to the collector. It implements logger.Sink.
+type Sink struct {
+	cfg      Config
+	resource []byte // encoded Resource message
+
+	mu      sync.Mutex
+	pending []record // oldest first, the head is pushed next
+	evicted uint64   // records dropped from the head of pending for new ones
+	closed  bool
+
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	dropped atomic.Uint64
+	lastErr atomic.Value // stores errorValue
+}
+
+// New creates a sink pushing to the configured URL and starts its push goroutine.
+func New(cfg Config) (*Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("otlp: url must not be empty")
+	}
+	if cfg.Scope == "" {
+		cfg.Scope = DefaultScope
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchWait == 0 {
+		cfg.BatchWait = DefaultBatchWait
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.BatchSize < 0 || cfg.BufferSize < cfg.BatchSize || cfg.BatchWait < 0 ||
+		cfg.MinBackoff < 0 || cfg.MaxBackoff < cfg.MinBackoff {
+		return nil, errors.New("otlp: invalid batch, buffer or backoff settings")
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	attrs := make(map[string]string, len(cfg.Resource)+1)
+	for k, v := range cfg.Resource {
+		attrs[k] = v
+	}
+	if _, ok := attrs["host.name"]; !ok {
+		if host, err := os.Hostname(); err == nil {
+			attrs["host.name"] = host
+		}
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var resource []byte
+	for _, k := range keys {
+		resource = appendMessage(resource, 1, appendKeyValue(nil, k, attrs[k]))
+	}
+
+	s := &Sink{
+		cfg:      cfg,
+		resource: resource,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write buffers the entry for the next push, dropping the oldest buffered record if the buffer is full.
+func (s *Sink) Write(entry *logger.Entry) error {
+	r := encodeRecord(entry, time.Now())
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.dropped.Add(1)
+		return errors.New("otlp: sink closed")
+	}
+	var err error
+	if len(s.pending) >= s.cfg.BufferSize {
+		s.pending = s.pending[1:]
+		s.evicted++
+		s.dropped.Add(1)
+		err = errors.New("otlp: buffer full, dropped oldest record")
+	}
+	s.pending = append(s.pending, r)
+	full := len(s.pending) >= s.cfg.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// Dropped returns the number of records dropped because the buffer was full, the sink was closed
+// or the collector rejected them.
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Err returns the error of the last failed push, nil if the last push succeeded.
+func (s *Sink) Err() error {
+	v, _ := s.lastErr.Load().(errorValue)
+	return v.error
+}
+
+// Close pushes the buffered records once more and stops the sink. Remove the sink from the logger first,
+// later records are dropped. It returns the error of the final push, the records are dropped.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+
+	for {
+		batch, mark := s.batch()
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.push(context.Background(), batch)
+		if err != nil && isRetryable(err) {
+			s.mu.Lock()
+			s.dropped.Add(uint64(len(s.pending)))
+			s.pending = nil
+			s.mu.Unlock()
+			return err
+		}
+		s.commit(len(batch), mark, err)
+	}
+}
+
+// run pushes a batch when it is full or BatchWait after the previous push, backing off while pushes fail
+func (s *Sink) run() {
+	defer close(s.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
+	backoff := time.Duration(0)
+	timer := time.NewTimer(s.cfg.BatchWait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+			if backoff > 0 {
+				// Retried when the backoff timer fires
+				continue
+			}
+		case <-timer.C:
+		}
+
+		wait := s.cfg.BatchWait
+		if batch, mark := s.batch(); len(batch) > 0 {
+			err := s.push(ctx, batch)
+			switch {
+			case err == nil || !isRetryable(err):
+				s.commit(len(batch), mark, err)
+				backoff = 0
+			case ctx.Err() != nil:
+				return
+			default:
+				backoff = min(max(2*backoff, s.cfg.MinBackoff), s.cfg.MaxBackoff)
+				wait = backoff
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// batch returns up to BatchSize of the oldest buffered records, they stay buffered until committed.
+// The mark is passed to commit to account for records evicted meanwhile.
+func (s *Sink) batch() ([]record, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(len(s.pending), s.cfg.BatchSize)
+	return append([]record(nil), s.pending[:n]...), s.evicted
+}
+
+// commit removes a pushed batch of n records from the buffer, counting them as dropped if the collector rejected them
+func (s *Sink) commit(n int, mark uint64, err error) {
+	s.mu.Lock()
+	n = max(n-int(s.evicted-mark), 0)
+	s.pending = s.pending[n:]
+	s.mu.Unlock()
+	if err != nil {
+		s.dropped.Add(uint64(n))
+	}
+}
+
+// pushError is a failed push, retryable unless the collector rejected the request itself
+type pushError struct {
+	status int
+	err    error
+}
+
+func (e *pushError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pushError) Unwrap() error {
+	return e.err
+}
+
+// isRetryable reports whether a push may succeed later: network errors, rate limits and server errors
+func isRetryable(err error) bool {
+	var pe *pushError
+	if !errors.As(err, &pe) || pe.status == 0 {
+		return true
+	}
+	return pe.status == http.StatusTooManyRequests || pe.status >= 500
+}
+
+// push sends a batch as one ExportLogsServiceRequest
+func (s *Sink) push(ctx context.Context, batch []record) (err error) {
+	defer func() { s.lastErr.Store(errorValue{err}) }()
+
+	body := s.encode(batch)
+	if s.cfg.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(body)
+		_ = zw.Close()
+		body = buf.Bytes()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return &pushError{status: http.StatusBadRequest, err: fmt.Errorf("otlp: failed to create request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if s.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return &pushError{err: fmt.Errorf("otlp: push failed: %w", err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &pushError{status: resp.StatusCode, err: fmt.Errorf("otlp: push failed: %s: %s", resp.Status, bytes.TrimSpace(msg))}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// errorValue wraps errors stored in lastErr, as atomic.Value requires a consistent type
+type errorValue struct {
+	error
+}
+
+// encode builds the ExportLogsServiceRequest of a batch: one ResourceLogs with one ScopeLogs holding the records
+func (s *Sink) encode(batch []record) []byte {
+	scope := appendString(nil, 1, s.cfg.Scope)
+	scopeLogs := appendMessage(nil, 1, scope)
+	for _, r := range batch {
+		scopeLogs = appendMessage(scopeLogs, 2, r)
+	}
+	resourceLogs := appendMessage(nil, 1, s.resource)
+	resourceLogs = appendMessage(resourceLogs, 2, scopeLogs)
+	return appendMessage(nil, 1, resourceLogs)
+}
+
+// severityNumber maps a level to the OpenTelemetry severity number: the levels are 4 apart like the severity
+// ranges, Debug is DEBUG (5), Info is INFO (9), Warn is WARN (13) and Error is ERROR (17)
+func severityNumber(level int64) uint64 {
+	return uint64(min(max(level-logger.LevelInfo+9, 1), 24))
+}
+
+// encodeRecord encodes an entry as an OTLP LogRecord: the message as body, the fields and the trace as attributes
+func encodeRecord(entry *logger.Entry, observed time.Time) record {
+	var b []byte
+	if !entry.Time.IsZero() {
+		b = appendFixed64(b, 1, uint64(entry.Time.UnixNano()))
+	}
+	b = appendVarint(b, 2, severityNumber(entry.Level))
+	b = appendString(b, 3, logger.LevelString(entry.Level))
+
+	body := entry.Message()
+	if body == "" && len(entry.Values) == 0 {
+		// Raw records carry the line only
+		line := entry.Line
+		if n := len(line); n > 0 && line[n-1] == '\n' {
+			line = line[:n-1]
+		}
+		body = string(line)
+	}
+	b = appendMessage(b, 5, appendAnyValue(nil, body, 0))
+
+	for _, f := range entry.Fields() {
+		b = appendMessage(b, 6, appendKeyAny(nil, f.Key, f.Value(), 0))
+	}
+	if entry.Trace != "" {
+		b = appendMessage(b, 6, appendKeyValue(nil, "trace", entry.Trace))
+	}
+	b = appendFixed64(b, 11, uint64(observed.UnixNano()))
+	return b
+}
+
+// maxValueDepth bounds the nesting of encoded slices and maps
+const maxValueDepth = 8
+
+// appendKeyValue appends the fields of a KeyValue message with a string value
+func appendKeyValue(b []byte, key, value string) []byte {
+	return appendKeyAny(b, key, value, 0)
+}
+
+// appendKeyAny appends the fields of a KeyValue message
+func appendKeyAny(b []byte, key string, value any, depth int) []byte {
+	b = appendString(b, 1, key)
+	return appendMessage(b, 2, appendAnyValue(nil, value, depth))
+}
+
+// appendAnyValue appends the fields of an AnyValue message. Slices, string keyed maps and groups are arrays and
+// key/value lists, values of other types their string form.
+func appendAnyValue(b []byte, v any, depth int) []byte {
+	switch x := v.(type) {
+	case string:
+		return appendString(b, 1, x)
+	case bool:
+		n := uint64(0)
+		if x {
+			n = 1
+		}
+		return appendVarint(b, 2, n)
+	case int:
+		return appendVarint(b, 3, uint64(x))
+	case int8:
+		return appendVarint(b, 3, uint64(x))
+	case int16:
+		return appendVarint(b, 3, uint64(x))
+	case int32:
+		return appendVarint(b, 3, uint64(x))
+	case int64:
+		return appendVarint(b, 3, uint64(x))
+	case uint:
+		return appendUint(b, uint64(x))
+	case uint8:
+		return appendVarint(b, 3, uint64(x))
+	case uint16:
+		return appendVarint(b, 3, uint64(x))
+	case uint32:
+		return appendVarint(b, 3, uint64(x))
+	case uint64:
+		return appendUint(b, x)
+	case float32:
+		return appendFixed64(b, 4, math.Float64bits(float64(x)))
+	case float64:
+		return appendFixed64(b, 4, math.Float64bits(x))
+	case []byte:
+		return appendBytes(b, 7, x)
+	case time.Time:
+		return appendString(b, 1, x.Format(time.RFC3339Nano))
+	case error:
+		return appendString(b, 1, x.Error())
+	case fmt.Stringer:
+		return appendString(b, 1, x.String())
+	case nil:
+		// An AnyValue without a value is the empty value
+		return b
+	}
+	if depth < maxValueDepth {
+		switch x := v.(type) {
+		case []any:
+			var arr []byte
+			for _, e := range x {
+				arr = appendMessage(arr, 1, appendAnyValue(nil, e, depth+1))
+			}
+			return appendMessage(b, 5, arr)
+		case []string:
+			var arr []byte
+			for _, e := range x {
+				arr = appendMessage(arr, 1, appendString(nil, 1, e))
+			}
+			return appendMessage(b, 5, arr)
+		case []logger.Field:
+			// Groups keep the order of their fields
+			var kvs []byte
+			for _, f := range x {
+				kvs = appendMessage(kvs, 1, appendKeyAny(nil, f.Key, f.Value(), depth+1))
+			}
+			return appendMessage(b, 6, kvs)
+		case map[string]any:
+			keys := make([]string, 0, len(x))
+			for k := range x {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			var kvs []byte
+			for _, k := range keys {
+				kvs = appendMessage(kvs, 1, appendKeyAny(nil, k, x[k], depth+1))
+			}
+			return appendMessage(b, 6, kvs)
+		}
+	}
+	return appendString(b, 1, fmt.Sprint(v))
+}
+
+// appendUint appends an unsigned integer as int_value, or its string form beyond the int64 range
+func appendUint(b []byte, v uint64) []byte {
+	if v > math.MaxInt64 {
+		return appendString(b, 1, fmt.Sprint(v))
+	}
+	return appendVarint(b, 3, v)
+}
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// appendTag appends a field tag
+func appendTag(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wire))
+}
+
+// appendVarint appends a varint field, negative int64 values as their two's complement
+func appendVarint(b []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+// appendFixed64 appends a fixed64 or double field
+func appendFixed64(b []byte, field int, v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(appendTag(b, field, wireFixed64), v)
+}
+
+// appendBytes appends a length-delimited field
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendString appends a string field
+func appendString(b []byte, field int, v string) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendMessage appends an embedded message field
+func appendMessage(b []byte, field int, msg []byte) []byte {
+	return appendBytes(b, field, msg)
+}
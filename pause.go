@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// diskResumeEvent identifies the record written when logging resumes after a disk full pause
+const diskResumeEvent = "disk_resume"
+
+// Disk full pause state, pausedAt is the Unix nanosecond time the pause started and pauseDrops the count of
+// disk_full drops at that time. Only written by the maintenance goroutine.
+var (
+	pausedAt   atomic.Int64
+	pauseDrops atomic.Uint64
+)
+
+// diskFullDrops returns the records dropped for disk space over the process lifetime
+func diskFullDrops() uint64 {
+	return dropsByCause[bits.TrailingZeros32(uint32(causeDiskFull))].Load()
+}
+
+// setDiskPause stores the result of a disk space scan read by checkDiskSpace, pausing logging on an error and
+// resuming a paused logger otherwise. A pause is reported to the error handler, the end of a pause is logged.
+// It must only be called from the maintenance goroutine.
+func setDiskPause(err error) {
+	paused := diskCheckFailed.Load()
+	switch {
+	case err != nil && !paused:
+		pausedAt.Store(clockNow().UnixNano())
+		pauseDrops.Store(diskFullDrops())
+		diskCheckFailed.Store(true)
+		diskFullPauses.Add(1)
+		if errors.Is(err, ErrDiskFull) {
+			reportError(err)
+		}
+	case err == nil && paused:
+		diskCheckFailed.Store(false)
+		sendLogRecord(diskResumeRecord())
+	}
+}
+
+// diskResumeRecord builds the record logged when a disk full pause ends:
+//
+//	event=disk_resume dropped_count=N paused_at=RFC3339 pause_ms=P
+//
+// dropped_count counts the records dropped for disk space during the pause.
+func diskResumeRecord() logRecord {
+	now := clockNow()
+	start := time.Unix(0, pausedAt.Load())
+	record := logRecord{
+		LogCtx:    context.Background(),
+		Flags:     flags,
+		TimeStamp: now,
+		Level:     LevelError,
+		HasMsg:    true,
+		Msg:       "Logging resumed after disk full pause",
+	}
+	record.Fields = []Field{
+		Str("event", diskResumeEvent),
+		Uint64("dropped_count", diskFullDrops()-pauseDrops.Load()),
+		Str("paused_at", start.UTC().Format(time.RFC3339Nano)),
+		Int64("pause_ms", now.Sub(start).Milliseconds()),
+	}
+	return record
+}
+
+// resumeLogging scans the disk space now instead of after DiskCheckInterval, resuming logging paused by a
+// full disk if the limits are met again, e.g. after an operator freed space. It returns ErrDiskFull if
+// logging stays paused.
+func resumeLogging() error {
+	if !isInitialized.Load() {
+		return ErrNotInitialized
+	}
+	if !diskCheckFailed.Load() {
+		return nil
+	}
+	invalidateDiskCheck()
+	if err := waitMaintenance(context.Background()); err != nil {
+		return err
+	}
+	if diskCheckFailed.Load() {
+		return ErrDiskFull
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Pre-init buffer state. Records logged before the first successful Init are held in memory, up to
+// preInitSize records, and handed to the processor once Init completes.
+var (
+	preInitMu      sync.Mutex
+	preInitSize    int
+	preInitRecords []logRecord
+	preInitEnabled atomic.Bool // buffering is enabled and Init has not completed yet
+	preInitDone    atomic.Bool // the first Init completed, buffering is permanently off
+)
+
+// setPreInitBuffer enables buffering of up to size records before the first Init, 0 disables it
+func setPreInitBuffer(size int) {
+	preInitMu.Lock()
+	defer preInitMu.Unlock()
+
+	if preInitDone.Load() {
+		return
+	}
+	preInitSize = max(size, 0)
+	if len(preInitRecords) > preInitSize {
+		recordDrop(uint64(len(preInitRecords)-preInitSize), causeBufferFull)
+		preInitRecords = preInitRecords[:preInitSize]
+	}
+	preInitEnabled.Store(preInitSize > 0)
+}
+
+// bufferPreInit holds a record logged before Init, reporting false once Init completed.
+// Records beyond the buffer size are counted as dropped and reported after Init.
+func bufferPreInit(record logRecord) bool {
+	if !preInitEnabled.Load() {
+		return false
+	}
+
+	preInitMu.Lock()
+	defer preInitMu.Unlock()
+
+	if preInitDone.Load() {
+		return false
+	}
+
+	records := []logRecord{record}
+	if record.Batch != nil {
+		records = record.Batch
+	}
+	for _, r := range records {
+		if len(preInitRecords) < preInitSize {
+			preInitRecords = append(preInitRecords, r)
+		} else {
+			recordDrop(1, causeBufferFull)
+		}
+	}
+	return true
+}
+
+// takePreInit ends pre-init buffering and returns the buffered records admitted by the configured
+// level, formatted with the configured flags. It is called by initLogger with the processor running.
+func takePreInit() []logRecord {
+	preInitMu.Lock()
+	defer preInitMu.Unlock()
+
+	preInitDone.Store(true)
+	preInitEnabled.Store(false)
+
+	level := logLevel.Load().(int64)
+	var records []logRecord
+	for _, r := range preInitRecords {
+		if r.Level >= level {
+			r.Flags = flags
+			records = append(records, r)
+		}
+	}
+	preInitRecords = nil
+	return records
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// pressureWatch is a subscription notified when the channel occupancy crosses one of its thresholds.
+// band is the number of thresholds at or below the occupancy of the last check.
+type pressureWatch struct {
+	thresholds []float64
+	band       atomic.Int64
+	notify     chan struct{}
+}
+
+// Pressure subscriptions, copied on write so the logging path reads them without locking
+var (
+	pressureMu       sync.Mutex
+	pressureWatchers atomic.Pointer[[]*pressureWatch]
+)
+
+// pressure returns the occupancy of the record buffer from 0 (empty) to 1 (full), 0 if not initialized
+func pressure() float64 {
+	if !isInitialized.Load() {
+		return 0
+	}
+	return occupancy()
+}
+
+// occupancy returns the fill ratio of the active queue
+func occupancy() float64 {
+	n, c := queueLen(), queueCap()
+	if c == 0 {
+		return 0
+	}
+	return min(float64(n)/float64(c), 1)
+}
+
+// subscribePressure registers thresholds between 0 and 1 and returns a channel receiving a notification
+// whenever the occupancy crosses one of them, upwards or downwards, and a function ending the subscription.
+func subscribePressure(thresholds ...float64) (<-chan struct{}, func(), error) {
+	if len(thresholds) == 0 {
+		return nil, nil, fmt.Errorf("no pressure thresholds")
+	}
+	for _, t := range thresholds {
+		if t <= 0 || t > 1 {
+			return nil, nil, fmt.Errorf("invalid pressure threshold %v: must be in (0, 1]", t)
+		}
+	}
+	w := &pressureWatch{
+		thresholds: slices.Sorted(slices.Values(thresholds)),
+		notify:     make(chan struct{}, 1),
+	}
+	w.band.Store(int64(w.bandOf(pressure())))
+
+	pressureMu.Lock()
+	defer pressureMu.Unlock()
+	var watchers []*pressureWatch
+	if current := pressureWatchers.Load(); current != nil {
+		watchers = slices.Clone(*current)
+	}
+	watchers = append(watchers, w)
+	pressureWatchers.Store(&watchers)
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() { removePressureWatch(w) })
+	}
+	return w.notify, cancel, nil
+}
+
+// removePressureWatch ends a subscription
+func removePressureWatch(w *pressureWatch) {
+	pressureMu.Lock()
+	defer pressureMu.Unlock()
+	current := pressureWatchers.Load()
+	if current == nil {
+		return
+	}
+	watchers := slices.DeleteFunc(slices.Clone(*current), func(x *pressureWatch) bool { return x == w })
+	pressureWatchers.Store(&watchers)
+}
+
+// bandOf returns the number of thresholds at or below the occupancy
+func (w *pressureWatch) bandOf(p float64) int {
+	n := 0
+	for n < len(w.thresholds) && p >= w.thresholds[n] {
+		n++
+	}
+	return n
+}
+
+// checkPressure notifies the subscriptions whose band changed with the occupancy of the queue.
+// It is called after queueing a record and after the processor takes one, and costs a single load without subscriptions.
+func checkPressure() {
+	watchers := pressureWatchers.Load()
+	if watchers == nil || len(*watchers) == 0 {
+		return
+	}
+	p := occupancy()
+	for _, w := range *watchers {
+		band := int64(w.bandOf(p))
+		if old := w.band.Load(); old != band && w.band.CompareAndSwap(old, band) {
+			// A pending notification already tells the subscriber to read the pressure
+			select {
+			case w.notify <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -3,9 +3,11 @@ package logger
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -16,6 +18,13 @@ import (
 var (
 	processCtx    context.Context
 	processCancel context.CancelFunc
+	processDone   chan struct{} // closed when the processor exits
+
+	// processDrain bounds writing the queued records when the processor stops, the records left
+	// are abandoned. Set before the processor is stopped, like the drain counts read after it exited.
+	processDrain   context.Context
+	drainFlushed   uint64
+	drainAbandoned uint64
 
 	logChannel chan logRecord
 	bufferSize atomic.Int64
@@ -27,12 +36,39 @@ var (
 	traceDepth int64
 
 	flags int64
+
+	queueType string
+
+	// Overflow and shedding settings are read by producers, the threshold holds the bits of a float64
+	overflowPolicy  atomic.Value // stores string
+	overflowTimeout atomic.Int64 // nanoseconds
+	shedThreshold   atomic.Uint64
+	shedLevel       atomic.Int64
+
+	syncPolicy string
 )
 
+// Overflow policies applied when the channel buffer is full
 const (
-	// Record flags for controlling output structure
-	FlagShowTimestamp int64 = 0b01
-	FlagShowLevel     int64 = 0b10
+	OverflowDrop             = "drop"               // drop the record, or spill it if enabled
+	OverflowBlock            = "block"              // wait until the processor makes room
+	OverflowBlockWithTimeout = "block_with_timeout" // wait up to OverflowTimeout, then drop or spill
+)
+
+// Sync policies selecting when records written to the log file are synced to disk
+const (
+	SyncInterval   = "interval"    // every FlushTimer
+	SyncEveryWrite = "every_write" // after every record
+	SyncEveryError = "every_error" // after every error level record, and every FlushTimer
+)
+
+// Record flags controlling the output structure of a record, combined with | and passed to LogWithFlags.
+// The configured flags are derived from ShowTimestamp, ShowLevel, ShowDeadline and ShowSequence.
+const (
+	FlagShowTimestamp int64 = 0b001  // time stamp of the logging call
+	FlagShowLevel     int64 = 0b010  // level name
+	FlagShowDeadline  int64 = 0b100  // remaining time until the context deadline, if any
+	FlagShowSequence  int64 = 0b1000 // per-process sequence number in the order of the logging calls
 	FlagDefault             = FlagShowTimestamp | FlagShowLevel
 )
 
@@ -44,7 +80,33 @@ type logRecord struct {
 	TimeStamp time.Time
 	Level     int64
 	Trace     string
+	Frames    []traceFrame // trace frames with StructuredTrace, outer to inner
+	Seq       uint64       // sequence number with FlagShowSequence, 0 until numbered
+	Goroutine uint64       // id of the logging goroutine with IncludeGoroutine, 0 if not captured
 	Args      []any
+
+	// Typed message and fields set by the *Fields API, written after Args. Fields of logging calls are
+	// backed by a pooled array, so queuing a record does not allocate and copying it stays cheap.
+	HasMsg bool
+	Msg    string
+	Fields []Field
+
+	// flushDone marks a flush request instead of a log entry, it receives the sync result
+	// once all records queued before it are written. With rotate, the file is rotated instead of synced.
+	flushDone chan error
+	rotate    bool
+
+	// Batch holds records handed off together by a LocalBuffer instead of a single log entry
+	Batch []logRecord
+
+	// Raw is a newline terminated line queued by WriteRaw, written as is instead of serializing the record
+	Raw []byte
+
+	// Journal holding the record, or the records of a batch, until written: the id and end offset of the
+	// last frame, nil journal if not journaled
+	journal    *journal
+	journalID  uint64
+	journalEnd int
 }
 
 // init sets up a finalizer to handle non-graceful program termination.
@@ -64,191 +126,717 @@ func init() {
 	})
 }
 
+// admit checks whether a record at the given level should be logged, including disk space checks.
+func admit(logCtx context.Context, level int64) bool {
+	// Check if logger is initialized and if log should be processed based on level.
+	// Before the first Init, records may be held by the pre-init buffer.
+	if !isInitialized.Load() {
+		return preInitEnabled.Load()
+	}
+	if level < minLevel(logCtx) {
+		return false
+	}
+
+	// Check the result of the last disk space scan before attempting to log
+	if err := checkDiskSpace(); err != nil {
+		recordDrop(1, causeDiskFull)
+		return false
+	}
+
+	return true
+}
+
+// skipTrace is the number of frames skipped to reach the caller of the public logging functions:
+// 3 levels of logger calls + adjustment for runtime.Callers behavior.
+const skipTrace = 4
+
+// callerSkip is the number of additional frames skipped to reach the caller, set by wrapper packages
+// calling the logging functions on behalf of their callers
+var callerSkip int
+
 // log handles the actual logging operation including dropped log detection and disk space checks.
 // It buffers log records through a channel for asynchronous processing.
 func log(logCtx context.Context, flags int64, level int64, depth int64, args ...any) {
-	// Check if logger is initialized and if log should be processed based on level
-	if !isInitialized.Load() {
+	if !admit(logCtx, level) {
 		return
 	}
-	if level < logLevel.Load().(int64) {
-		return
+
+	// Get caller trace if set
+	var trace string
+	var frames []traceFrame
+	if depth > 0 {
+		trace, frames = getTrace(depth, skipTrace+callerSkip)
+	}
+
+	// Create log record from arguments
+	record := logRecord{
+		LogCtx:    logCtx,
+		Flags:     flags,
+		TimeStamp: clockNow(),
+		Level:     level,
+		Trace:     trace,
+		Frames:    frames,
+		Args:      withContextArgs(logCtx, args),
 	}
+	stampSequence(&record)
+	stampGoroutine(&record)
 
-	// Check disk space before attempting to log
-	if err := checkDiskSpace(logCtx); err != nil {
-		droppedLogs.Add(1)
+	// Process log record
+	sendLogRecord(record)
+}
+
+// logFields is the typed counterpart of log. The fields of the context follow the given fields.
+func logFields(logCtx context.Context, flags int64, level int64, depth int64, msg string, fields ...Field) {
+	if !admit(logCtx, level) {
 		return
 	}
 
-	// Process any dropped logs before handling new log
-	currentDrops := droppedLogs.Load()
-	logged := loggedDrops.Load()
-	if currentDrops > logged {
-		// Immediately update the logged drop counter to
-		// current dropped log counter to avoid conflict.
-		loggedDrops.Store(currentDrops)
-		dropRecord := logRecord{
-			LogCtx:    context.Background(),
-			Flags:     FlagDefault,
-			TimeStamp: time.Now(),
-			Level:     LevelError,
-			Args: []any{
-				"Logs were dropped",
-				"dropped_count", currentDrops - logged,
-				"total_dropped", currentDrops,
-			},
-		}
+	var trace string
+	var frames []traceFrame
+	if depth > 0 {
+		trace, frames = getTrace(depth, skipTrace+callerSkip)
+	}
 
-		sendLogRecord(dropRecord)
+	record := logRecord{
+		LogCtx:    logCtx,
+		Flags:     flags,
+		TimeStamp: clockNow(),
+		Level:     level,
+		Trace:     trace,
+		Frames:    frames,
+		HasMsg:    true,
+		Msg:       msg,
 	}
+	stampSequence(&record)
+	stampGoroutine(&record)
+	setFields(&record, withContextFields(logCtx, fields))
 
-	logArgs := args
-	// Get caller trace if set
-	const skipTrace = 4 // 3 levels of logger calls + adjustment for runtime.Callers behavior
+	sendLogRecord(record)
+}
+
+// setFields sets the fields of a record with a typed message. Up to maxInlineFields fields are copied into
+// a pooled array without allocation, more fields are appended to the record args as boxed values.
+func setFields(record *logRecord, fields []Field) {
+	if len(fields) > maxInlineFields {
+		// Fields don't fit the array, fall back to boxed args to keep them in order
+		args := make([]any, 0, 1+len(fields))
+		args = append(args, record.Msg)
+		for _, f := range fields {
+			args = append(args, f)
+		}
+		record.Args, record.HasMsg = args, false
+		return
+	}
+	if len(fields) > 0 {
+		record.Fields = append(getFields(), fields...)
+	}
+}
+
+// logf is the formatted counterpart of log. The message is formatted once, only for admitted records,
+// and written as the record message.
+func logf(logCtx context.Context, flags int64, level int64, depth int64, format string, args ...any) {
+	if !admit(logCtx, level) {
+		return
+	}
 
 	var trace string
+	var frames []traceFrame
 	if depth > 0 {
-		trace = getTrace(depth, skipTrace)
+		trace, frames = getTrace(depth, skipTrace+callerSkip)
 	}
 
-	// Create log record from arguments
 	record := logRecord{
 		LogCtx:    logCtx,
 		Flags:     flags,
-		TimeStamp: time.Now(),
+		TimeStamp: clockNow(),
 		Level:     level,
 		Trace:     trace,
-		Args:      logArgs,
+		Frames:    frames,
+		HasMsg:    true,
+		Msg:       fmt.Sprintf(format, args...),
 	}
+	stampSequence(&record)
+	stampGoroutine(&record)
+	setFields(&record, contextFields(logCtx))
 
-	// Process log record
 	sendLogRecord(record)
 }
 
+// logRaw queues a copy of a pre-serialized line, newline terminated, for writing without serialization
+func logRaw(level int64, line []byte) {
+	if len(line) == 0 || !admit(context.Background(), level) {
+		return
+	}
+
+	raw := make([]byte, len(line), len(line)+1)
+	copy(raw, line)
+	if raw[len(raw)-1] != '\n' {
+		raw = append(raw, '\n')
+	}
+	sendLogRecord(logRecord{
+		LogCtx:    context.Background(),
+		TimeStamp: clockNow(),
+		Level:     level,
+		Raw:       raw,
+	})
+}
+
 // sendLogRecord handles the safe sending of log records to the channel
 func sendLogRecord(record logRecord) {
+	// A dropped batch loses all of its records
+	drops := uint64(1)
+	if record.Batch != nil {
+		drops = uint64(len(record.Batch))
+	}
+
 	// mainly to handle shutdown when goroutines write to closed channel
 	defer func() {
 		if recover() != nil {
-			droppedLogs.Add(1)
+			recordDrop(drops, causeDisabled)
 		}
 	}()
 
+	if !isInitialized.Load() && bufferPreInit(record) {
+		return
+	}
+
 	if loggerDisabled.Load() {
-		droppedLogs.Add(1)
+		recordDrop(drops, causeDisabled)
 		return
 	}
 
+	// Journaled records are queued in the order of the journal
+	queued := false
+	if journalRecord(&record) {
+		defer func() { releaseJournal(&record, queued) }()
+	}
+	if queued = queueRecord(&record, drops); !queued {
+		// Spilled records are serialized and dropped ones discarded, their field array can be reused
+		releaseFields(&record)
+	}
+}
+
+// queueRecord queues a record in memory, waiting for room according to the overflow policy, or on disk if
+// spilling is enabled, and reports whether it was queued in memory. Records not queued at all are dropped.
+func queueRecord(record *logRecord, drops uint64) bool {
+	// Records below ShedLevel leave the rest of a saturated buffer to the more severe ones, without waiting for room
+	threshold := math.Float64frombits(shedThreshold.Load())
+	if threshold > 0 && record.Batch == nil && record.Level < shedLevel.Load() && occupancy() >= threshold {
+		if !spillRecord(record) {
+			recordDrop(drops, causeShed)
+		}
+		return false
+	}
+
+	if enqueue(*record) {
+		checkPressure()
+		return true
+	}
+	// Queue full, wait for room if the overflow policy allows, then queue on disk if spilling is enabled
+	if waitForRoom(*record) {
+		checkPressure()
+		return true
+	}
+	if !spillRecord(record) {
+		recordDrop(drops, causeBufferFull)
+	}
+	return false
+}
+
+// waitForRoom blocks sending a record to the full queue according to the overflow policy.
+// It gives up when the timeout of block_with_timeout expires, the record context is done or the
+// logger shuts down, and reports whether the record was sent. Waiting continues with the queue of a
+// reconfigured logger, under its overflow policy.
+func waitForRoom(record logRecord) bool {
+	var cancelled <-chan struct{}
+	if record.LogCtx != nil {
+		cancelled = record.LogCtx.Done()
+	}
+
+	var expired <-chan time.Time
+	for {
+		policy, _ := overflowPolicy.Load().(string)
+		if policy != OverflowBlock && policy != OverflowBlockWithTimeout {
+			return false
+		}
+		if policy == OverflowBlockWithTimeout && expired == nil {
+			timer := time.NewTimer(time.Duration(overflowTimeout.Load()))
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		// Reconfiguration stops the processor while holding mu, the queue is read once it runs again
+		mu.RLock()
+		ctx, channel, ring := processCtx, logChannel, logRing
+		mu.RUnlock()
+		if ctx == nil {
+			return false
+		}
+
+		sent, stopped := sendWaiting(record, channel, ring, ctx.Done(), cancelled, expired)
+		if !stopped || !isInitialized.Load() {
+			return sent
+		}
+	}
+}
+
+// sendWaiting sends a record to the queue, waiting until there is room, expired or cancelled fire, or the
+// processor stops. It reports whether the record was sent and whether waiting ended by the processor stopping.
+func sendWaiting(record logRecord, channel chan logRecord, ring *recordRing, stopped, cancelled <-chan struct{},
+	expired <-chan time.Time) (sent, wasStopped bool) {
+	if ring != nil {
+		for !ring.push(record) {
+			select {
+			case <-expired:
+				return false, false
+			case <-cancelled:
+				return false, false
+			case <-stopped:
+				return false, true
+			case <-time.After(ringRetryInterval):
+			}
+		}
+		return true, false
+	}
+
+	select {
+	case channel <- record:
+		return true, false
+	case <-expired:
+	case <-cancelled:
+	case <-stopped:
+		return false, true
+	}
+	return false, false
+}
+
+// flushLogger queues a flush request behind any pending records and waits until it is processed,
+// ensuring everything logged before the call is written and synced to disk, then waits for the
+// maintenance work already running or due.
+func flushLogger(ctx context.Context) error {
+	if err := requestProcessed(ctx, false); err != nil {
+		return err
+	}
+	return waitMaintenance(ctx)
+}
+
+// rotateLogger queues a rotation request behind any pending records and waits until it is processed,
+// so everything logged before the call is written to the previous file.
+func rotateLogger(ctx context.Context) error {
+	return requestProcessed(ctx, true)
+}
+
+// requestProcessed queues a flush or rotation request and waits for its result
+func requestProcessed(ctx context.Context, rotate bool) (err error) {
+	if !isInitialized.Load() {
+		return ErrNotInitialized
+	}
+
+	// Channel may be closed by a concurrent shutdown
+	defer func() {
+		if recover() != nil {
+			err = ErrShutdown
+		}
+	}()
+
+	done := make(chan error, 1)
+	request := logRecord{flushDone: done, rotate: rotate}
+	if ring := logRing; ring != nil {
+		// Requests share the ring with the records, so they are processed after the records logged before
+		if !ring.pushWait(request, ctx.Done()) {
+			return ctx.Err()
+		}
+	} else {
+		select {
+		case logChannel <- request:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
 	select {
-	case logChannel <- record:
-	default:
-		droppedLogs.Add(1)
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
 // processLogs is the main log processing loop running in a separate goroutine.
 // It handles the actual writing of logs and manages file rotation based on size.
-func processLogs() {
-	ticker := time.NewTicker(flushTimer)
+// It exits once ctx is done and the queued records are written, or records is closed, then closes done.
+func processLogs(ctx context.Context, records chan logRecord, ring *recordRing, done chan struct{}) {
+	defer close(done)
+
+	ticker := newTicker(flushTimer)
 	defer ticker.Stop()
 
-	var retentionTicker *time.Ticker
-	var retentionChan <-chan time.Time // nil channel
-	if retentionPeriod > 0 && retentionCheck > 0 {
-		retentionTicker = time.NewTicker(retentionCheck)
-		defer retentionTicker.Stop()
-		retentionChan = retentionTicker.C // assign channel only if ticker exists
-		updateEarliestFileTime()
+	var heartbeatChan <-chan time.Time
+	if heartbeatInterval > 0 {
+		heartbeatTicker := newTicker(heartbeatInterval)
+		defer heartbeatTicker.Stop()
+		heartbeatChan = heartbeatTicker.C()
+	}
+
+	// Serializer and encryption buffers are reused across records
+	s := newSerializer()
+
+	var ringWake <-chan struct{} // nil channel without a ring
+	if ring != nil {
+		ringWake = ring.wake
 	}
 
 	for {
 		select {
 		// Process each log record
-		case record, ok := <-logChannel:
+		case record, ok := <-records:
 			if !ok {
-				if currentFile := currentFile.Load().(*os.File); currentFile != nil {
-					currentFile.Sync()
-				}
+				expireDedup(s, time.Time{})
+				syncFile()
 				return
 			}
+			processRecord(s, &record, records)
+			processAvailable(s, records)
+			flushWrites()
+			checkPressure()
+		case <-ringWake:
+			processRing(s, ring, records)
+			flushWrites()
+			checkPressure()
+		case <-ticker.C():
+			if spillQueued.Load() {
+				drainSpill(s, spillDrainBatch)
+			}
+			expireDedup(s, clockNow())
+			if currentFile := currentFile.Load().(*os.File); currentFile != nil {
+				syncFile()
+				reconcileSize(currentFile)
+				// Start a new file if the active one was deleted or moved externally
+				if activeFileMissing(currentFile) {
+					reopenLogFile(context.Background())
+				}
+			} else if !nextOpenAttempt.IsZero() && !clockNow().Before(nextOpenAttempt) {
+				// Retry creating a deferred file once its backoff expired
+				openDeferredFile(context.Background())
+			}
+		case done := <-reopenChan:
+			done <- reopenLogFile(context.Background())
+		case <-heartbeatChan:
+			writeHeartbeat(s)
+			flushWrites()
+		case <-ctx.Done():
+			// Records queued before the stop are written with the running config
+			drainFlushed, drainAbandoned = drainQueue(s, records, processDrain)
+			expireDedup(s, time.Time{})
+			syncFile()
+			return
+		}
+	}
+}
 
-			// Create log entry and write
-			s := newSerializer()
-			data := s.serialize(record.Flags, record.TimeStamp, record.Level, record.Trace, record.Args)
+// processRecord handles a record received from the queue: flush requests, batches and single records.
+// It must only be called from the processor goroutine.
+func processRecord(s *serializer, record *logRecord, records chan logRecord) {
+	// Flush or rotation request, all preceding records are written
+	if record.flushDone != nil {
+		if spillQueued.Load() {
+			drainSpill(s, 0)
+		}
+		expireDedup(s, time.Time{})
+		var err error
+		if record.rotate {
+			err = reopenLogFile(context.Background())
+		} else {
+			err = syncFile()
+		}
+		record.flushDone <- err
+		return
+	}
 
-			// Check file size and rotate if needed
-			currentFileSize := currentSize.Load()
-			estimatedSize := currentFileSize + int64(len(data))
+	// Batches handed off by local buffers are written record by record
+	if record.Batch != nil {
+		for i := range record.Batch {
+			if !dedupRecord(s, &record.Batch[i]) {
+				writeRecord(s, &record.Batch[i])
+				releaseFields(&record.Batch[i])
+			}
+		}
+	} else if !dedupRecord(s, record) {
+		writeRecord(s, record)
+		releaseFields(record)
+	}
+	markJournaled(record)
 
-			if maxSizeMB > 0 && estimatedSize > maxSizeMB*1024*1024 {
-				if err := rotateLogFile(record.LogCtx); err != nil {
-					continue
-				}
+	// Catch up on spilled records once the queue is empty
+	if spillQueued.Load() && len(records) == 0 && (logRing == nil || logRing.len() == 0) {
+		drainSpill(s, spillDrainBatch)
+	}
+}
+
+// drainQueue processes the records currently queued without waiting for more, until the deadline
+// context is done. Records still queued then are abandoned and counted as dropped.
+// It returns the numbers of records processed and abandoned.
+// It must only be called from the processor goroutine.
+func drainQueue(s *serializer, records chan logRecord, deadline context.Context) (flushed, abandoned uint64) {
+	for {
+		if deadline.Err() != nil {
+			abandoned = abandonQueue(records)
+			return flushed, abandoned
+		}
+		if logRing != nil {
+			record, ok := logRing.pop()
+			if !ok {
+				return flushed, abandoned
+			}
+			flushed += recordCount(&record)
+			processRecord(s, &record, records)
+			continue
+		}
+		select {
+		case record, ok := <-records:
+			if !ok {
+				return flushed, abandoned
 			}
+			flushed += recordCount(&record)
+			processRecord(s, &record, records)
+		default:
+			return flushed, abandoned
+		}
+	}
+}
 
-			if _, err := currentFile.Load().(*os.File).Write(data); err != nil {
+// abandonQueue drops the records currently queued, failing pending flush requests, and returns their number
+func abandonQueue(records chan logRecord) uint64 {
+	var n uint64
+	for {
+		record, ok := logRecord{}, false
+		if logRing != nil {
+			record, ok = logRing.pop()
+		}
+		if ok {
+			if record.flushDone != nil {
+				record.flushDone <- ErrShutdown
 				continue
 			}
-
-			// Sync after each write during shutdown
-			if !isInitialized.Load() {
-				currentFile.Load().(*os.File).Sync()
+			n += recordCount(&record)
+			continue
+		}
+		select {
+		case record, ok := <-records:
+			if !ok {
+				return n
 			}
-
-			if fi, err := os.Stat(currentFile.Load().(*os.File).Name()); err == nil {
-				currentSize.Store(fi.Size())
+			if record.flushDone != nil {
+				record.flushDone <- ErrShutdown
+				continue
 			}
-		case <-ticker.C:
-			if currentFile := currentFile.Load().(*os.File); currentFile != nil {
-				currentFile.Sync()
+			n += recordCount(&record)
+		default:
+			if n > 0 {
+				recordDrop(n, causeDisabled)
 			}
-		case <-retentionChan:
-			// Only process if retention is enabled
-			if retentionPeriod > 0 {
-				// Safe type assertion and non-zero check
-				if earliest, ok := earliestFileTime.Load().(time.Time); ok {
-					// Only process if we have a valid timestamp
-					if !earliest.IsZero() && time.Since(earliest) > retentionPeriod {
-						ctx := context.Background()
-						if err := cleanExpiredLogs(ctx, earliest); err == nil {
-							// Only update if cleanup succeeded
-							updateEarliestFileTime()
-						}
-					}
-				}
+			return n
+		}
+	}
+}
+
+// recordCount returns the number of log entries carried by a queued record, 0 for flush requests
+func recordCount(record *logRecord) uint64 {
+	switch {
+	case record.flushDone != nil:
+		return 0
+	case record.Batch != nil:
+		return uint64(len(record.Batch))
+	}
+	return 1
+}
+
+// moveQueued moves the records left in a replaced channel or ring to the active queue without waiting.
+// Records that don't fit are dropped.
+func moveQueued(from chan logRecord, fromRing *recordRing) {
+	for {
+		record, ok := logRecord{}, false
+		if fromRing != nil {
+			record, ok = fromRing.pop()
+		}
+		if !ok {
+			select {
+			case record, ok = <-from:
+			default:
 			}
-		case <-processCtx.Done():
-			if currentFile := currentFile.Load().(*os.File); currentFile != nil {
-				currentFile.Sync()
+		}
+		if !ok {
+			return
+		}
+		if !enqueue(record) {
+			drops := uint64(1)
+			if record.Batch != nil {
+				drops = uint64(len(record.Batch))
 			}
+			recordDrop(drops, causeBufferFull)
+		}
+	}
+}
+
+// startProcessor starts a processor goroutine for the queue and the maintenance goroutine, mu must be held
+func startProcessor(ctx context.Context) {
+	processCtx, processCancel = context.WithCancel(ctx)
+	processDone = make(chan struct{})
+	maintainDone = make(chan struct{})
+	go processLogs(processCtx, logChannel, logRing, processDone)
+	ready := make(chan struct{})
+	go maintainLogs(processCtx, ready, maintainDone)
+	<-ready
+}
+
+// stopProcessor stops the processor after it wrote the records queued so far, or until the drain context
+// is done, and waits for it to exit. It returns the numbers of records written and abandoned, mu must be held.
+func stopProcessor(drain context.Context) (flushed, abandoned uint64) {
+	if processCancel == nil {
+		return 0, 0
+	}
+	processDrain, drainFlushed, drainAbandoned = drain, 0, 0
+	processCancel()
+	<-processDone
+	<-maintainDone
+	return drainFlushed, drainAbandoned
+}
+
+// writeRecord serializes a single record and writes it to the current file, rotating if needed.
+// It must only be called from the processor goroutine.
+func writeRecord(s *serializer, record *logRecord) {
+	writeGapMarker(s)
+	stampSequence(record)
+	s.serialize(record)
+	writeSerialized(s, record)
+}
+
+// writeSerialized writes the record serialized in s.buf, applying the audit chain and pipeline.
+// It must only be called from the processor goroutine.
+func writeSerialized(s *serializer, record *logRecord) {
+	recordRecent(s.buf)
+	if consoleWriter != nil && record.Level >= consoleMinLevel {
+		writeConsole(s.consoleLine(record))
+	}
+	if record.Level < fileMinLevel {
+		dispatchSinks(record, s.buf)
+		return
+	}
+	if isTransient(record) {
+		writeTransient(s, record)
+		return
+	}
+
+	// Create the file now if it could not be created at initialization, before the audit chain
+	// of the new file starts
+	if currentFile.Load().(*os.File) == nil {
+		if err := openDeferredFile(context.Background()); err != nil {
+			recordDrop(1, causeFileUnavailable)
 			return
 		}
 	}
+
+	// The chain is saved, so a record moving to a new file by rotation is not counted in the footer of the
+	// old one and is chained again from its final hash
+	var chain auditState
+	if auditChain {
+		s.unchained = append(s.unchained[:0], s.buf...)
+		chain = saveAuditState()
+	}
+	line, data, err := s.finishRecord()
+	if err != nil {
+		if auditChain {
+			chain.restore()
+		}
+		recordDrop(1, causeWriteError)
+		return
+	}
+
+	// Check file size and rotate if needed, the lines pending in the coalesced write count towards the file
+	currentFileSize := currentSize.Load() + int64(len(pendingWrite))
+	estimatedSize := currentFileSize + int64(len(data))
+
+	if maxSize > 0 && estimatedSize > maxSize && rotationAllowed(currentFileSize) {
+		if auditChain {
+			chain.restore()
+		}
+		if err := rotateLogFile(record.LogCtx); err != nil {
+			recordDrop(1, causeWriteError)
+			return
+		}
+		if auditChain {
+			chain = saveAuditState()
+			s.buf = append(s.buf[:0], s.unchained...)
+			if line, data, err = s.finishRecord(); err != nil {
+				chain.restore()
+				recordDrop(1, causeWriteError)
+				return
+			}
+		}
+	}
+
+	// Sinks receive the record when it is queued for the coalesced write. A failed write is chained again from
+	// the state before the first pending line.
+	if auditChain && pendingRecords == 0 {
+		pendingChain = chain
+	}
+	queueWrite(data)
+
+	// Sync after each write as required by the sync policy, and during shutdown
+	if !isInitialized.Load() || syncPolicy == SyncEveryWrite || (syncPolicy == SyncEveryError && record.Level >= LevelError) {
+		syncFile()
+	}
+
+	dispatchSinks(record, line)
+}
+
+// finishRecord chains, checksums, packs and encodes the record serialized in s.buf as configured. It returns
+// the line passed to sinks and the data written to the file.
+func (s *serializer) finishRecord() (line, data []byte, err error) {
+	data = s.buf
+	if auditChain {
+		data = s.appendAuditHash()
+	}
+	if recordChecksum {
+		data = s.appendChecksum()
+	}
+	line = data
+	if format == FormatMsgpack {
+		data = s.pack(data)
+	}
+	if len(pipeline) > 0 {
+		if data, err = s.encode(data); err != nil {
+			return nil, nil, err
+		}
+	}
+	return line, data, nil
 }
 
 // getTrace returns a function call trace as a string, formatted as "outer -> inner -> deepest".
 // It skips the specified number of frames and captures up to depth levels of function calls.
 // Returns empty string if depth is 0, or "(unknown)" if no frames are captured.
 // Function names are simplified to base names, with special handling for anonymous functions.
-func getTrace(depth int64, skip int) string {
+// With TraceWithLines, each function is followed by the file base name and line, "main.run(main.go:42)".
+// With StructuredTrace, the captured frames are also returned in the same order.
+func getTrace(depth int64, skip int) (string, []traceFrame) {
 	if depth == 0 {
-		return ""
+		return "", nil
 	}
 
 	// Capture up to depth+skip frames to account for internal calls
 	pc := make([]uintptr, int(depth)+skip)
 	n := runtime.Callers(skip, pc)
 	if n == 0 {
-		return "(unknown)"
+		return "(unknown)", nil
 	}
 
-	frames := runtime.CallersFrames(pc[:n])
+	callers := runtime.CallersFrames(pc[:n])
 	var trace []string
+	var frames []traceFrame
 	count := 0
 
 	for {
-		frame, more := frames.Next()
+		frame, more := callers.Next()
 		if !more || count >= int(depth) {
 			break
 		}
@@ -270,12 +858,18 @@ func getTrace(depth int64, skip int) string {
 				funcName = fmt.Sprintf("(anonymous %s)", funcName)
 			}
 		}
+		if traceWithLines {
+			funcName = fmt.Sprintf("%s(%s:%d)", funcName, filepath.Base(frame.File), frame.Line)
+		}
 		trace = append(trace, funcName)
+		if structuredTrace {
+			frames = append(frames, traceFrame{function: frame.Function, file: frame.File, line: frame.Line})
+		}
 		count++
 	}
 
 	if len(trace) == 0 {
-		return "(unknown)"
+		return "(unknown)", nil
 	}
 
 	// Reverse the trace array before joining for outer -> inner order
@@ -283,13 +877,41 @@ func getTrace(depth int64, skip int) string {
 		j := len(trace) - i - 1
 		trace[i], trace[j] = trace[j], trace[i]
 	}
-	return strings.Join(trace, " -> ")
+	slices.Reverse(frames)
+	return strings.Join(trace, " -> "), frames
+}
+
+// initRetryInterval is the minimum delay between automatic initialization attempts after a failure
+const initRetryInterval = 5 * time.Second
+
+// initFailedAt holds the unix nano time of the last failed automatic initialization, zero if none.
+// It distinguishes a failed auto-initialization, which is retried, from a shutdown, which is not.
+var initFailedAt atomic.Int64
+
+// initError holds the error of the last failed initialization, nil once an initialization succeeded
+var initError atomic.Pointer[error]
+
+// setInitError records the result of an initialization
+func setInitError(err error) {
+	if err == nil {
+		initError.Store(nil)
+		return
+	}
+	initError.Store(&err)
+}
+
+// lastInitError returns the error of the last failed initialization, nil if the last one succeeded
+func lastInitError() error {
+	if err := initError.Load(); err != nil {
+		return *err
+	}
+	return nil
 }
 
 // EnsureInitialized checks if logger is initialized and initializes with defaults if needed
 func ensureInitialized() bool {
-	// If previous initialization failed, drop logs silently
-	if loggerDisabled.Load() {
+	// If previous initialization failed, drop logs silently until the retry interval passed
+	if loggerDisabled.Load() && !initRetryDue() {
 		return false
 	}
 
@@ -303,15 +925,40 @@ func ensureInitialized() bool {
 	defer initMu.Unlock()
 
 	// Double check both conditions after lock
-	if loggerDisabled.Load() || isInitialized.Load() {
-		return isInitialized.Load()
+	if isInitialized.Load() {
+		return true
+	}
+	if loggerDisabled.Load() && !initRetryDue() {
+		return false
 	}
 
-	if err := Init(context.Background()); err != nil {
-		// Mark initialization as failed and silently drop future logs
+	// Lazy open keeps quick usable if the directory is not writable yet
+	if err := Init(context.Background(), &LoggerConfig{LazyOpen: true}); err != nil {
+		// Mark initialization as failed and drop logs until the next retry, the error is kept for
+		// LastInitError and passed to the error handler
+		initFailedAt.Store(time.Now().UnixNano())
 		loggerDisabled.Store(true)
+		reportError(fmt.Errorf("automatic initialization failed: %w", err))
 		return false
 	}
 
 	return true
-}
\ No newline at end of file
+}
+
+// initRetryDue reports whether a failed automatic initialization may be retried
+func initRetryDue() bool {
+	failedAt := initFailedAt.Load()
+	return failedAt != 0 && time.Now().UnixNano()-failedAt >= int64(initRetryInterval)
+}
+
+// resetLogger clears the disabled state left by a failed initialization or a shutdown,
+// so the next logging call through EnsureInitialized initializes the logger again.
+func resetLogger() {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	initFailedAt.Store(0)
+	if !isInitialized.Load() {
+		loggerDisabled.Store(false)
+	}
+}
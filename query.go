@@ -0,0 +1,417 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// queryMaxLine bounds the length of a line read by Query, longer lines are skipped
+const queryMaxLine = 1024 * 1024
+
+// QueryOptions selects the records returned by Query. Zero values don't filter.
+type QueryOptions struct {
+	Files    []string          // files to read, the log files of the running logger by default, oldest first
+	Since    time.Time         // records logged at or after the time
+	Until    time.Time         // records logged before the time
+	MinLevel string            // records at or above the level, e.g. "warn", see ParseLevel
+	Contains string            // records whose line contains the substring
+	Fields   map[string]string // records with each field, the value compared in its text form
+	Limit    int               // the latest matching records only
+}
+
+// QueryEntry is a record read back from a log file
+type QueryEntry struct {
+	Time     time.Time      // zero if the record has no time stamp
+	Level    int64          // valid with HasLevel
+	HasLevel bool           // the record has a level
+	Message  string         // the message, the first value of text records
+	Fields   map[string]any // key/value members, JSON values as decoded by encoding/json, text values as strings
+	Line     string         // the line as written, without the newline
+	File     string         // path of the file
+}
+
+// query reads the files selected by opts and returns the matching records, oldest first
+func query(opts QueryOptions) ([]QueryEntry, error) {
+	minLevel, filterLevel := int64(0), opts.MinLevel != ""
+	if filterLevel {
+		var err error
+		if minLevel, err = parseLevel(opts.MinLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	files := opts.Files
+	if files == nil {
+		var err error
+		if files, err = queryFiles(); err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []QueryEntry
+	for _, file := range files {
+		// Files last written before Since hold no matching records
+		if !opts.Since.IsZero() {
+			if info, err := os.Stat(file); err == nil && info.ModTime().Before(opts.Since) {
+				continue
+			}
+		}
+		err := scanEntries(file, func(e *QueryEntry) {
+			if !opts.Since.IsZero() && !e.Time.IsZero() && e.Time.Before(opts.Since) ||
+				!opts.Until.IsZero() && !e.Time.IsZero() && !e.Time.Before(opts.Until) ||
+				filterLevel && (!e.HasLevel || e.Level < minLevel) ||
+				!matchFields(e, opts.Fields) {
+				return
+			}
+			if opts.Limit > 0 && len(entries) == opts.Limit {
+				entries = slices.Delete(entries, 0, 1)
+			}
+			entries = append(entries, *e)
+		}, opts.Contains)
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// queryFiles returns the log files of the running logger, oldest first
+func queryFiles() ([]string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	dir, running, piped := directory, isInitialized.Load(), len(pipeline) > 0
+	if !running {
+		return nil, fmt.Errorf("%w, query files must be given", ErrNotInitialized)
+	}
+	if piped {
+		return nil, fmt.Errorf("query of files written through a pipeline not supported")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type logFile struct {
+		path    string
+		modTime time.Time
+	}
+	var logs []logFile
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != "."+extension || !isOwnLogFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, logFile{filepath.Join(dir, entry.Name()), info.ModTime()})
+	}
+	slices.SortStableFunc(logs, func(a, b logFile) int { return a.modTime.Compare(b.modTime) })
+
+	files := make([]string, len(logs))
+	for i, l := range logs {
+		files[i] = l.path
+	}
+	return files, nil
+}
+
+// scanEntries parses the lines of a file containing the substring and passes them to fn, the records of
+// a msgpack file as their json lines.
+// JSON lines that fail to parse and lines longer than queryMaxLine are skipped.
+func scanEntries(path string, fn func(*QueryEntry), contains string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Files of the msgpack format are converted to json lines, up to a record still being written
+	var r io.Reader = bufio.NewReader(f)
+	if first, err := r.(*bufio.Reader).Peek(1); err == nil && isMsgpack(first) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		lines, _ := msgpackToJSON(data)
+		r = bytes.NewReader(lines)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), queryMaxLine)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || contains != "" && !strings.Contains(line, contains) {
+			continue
+		}
+		entry, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		entry.File = path
+		fn(&entry)
+	}
+	if err := scanner.Err(); err != nil && err != bufio.ErrTooLong {
+		return err
+	}
+	return nil
+}
+
+// parseLine parses a line of the txt, json or ecs format, reporting false for empty lines, invalid JSON and
+// records failing their checksum. The checksum of a record written with RecordChecksum is not a field.
+func parseLine(line string) (QueryEntry, bool) {
+	entry := QueryEntry{Line: line}
+	if line == "" {
+		return entry, false
+	}
+	record := line
+	if _, _, ok := splitChecksum([]byte(line)); ok {
+		body, err := verifyRecord([]byte(line))
+		if err != nil {
+			return entry, false
+		}
+		record = string(body)
+	}
+	if record[0] == '{' {
+		return entry, parseJSONEntry(&entry, record)
+	}
+	parseTextEntry(&entry, record)
+	return entry, true
+}
+
+// matchFields reports whether the entry has every field with the value in its text form
+func matchFields(e *QueryEntry, fields map[string]string) bool {
+	for key, want := range fields {
+		v, ok := e.Fields[key]
+		if !ok {
+			return false
+		}
+		if s, isString := v.(string); isString && s != want || !isString && fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// parseJSONEntry parses a record of the json or ecs format. Members of the json "fields" object and nested
+// ECS objects are flattened into Fields, nested keys joined with dots.
+func parseJSONEntry(e *QueryEntry, line string) bool {
+	var members map[string]any
+	if json.Unmarshal([]byte(line), &members) != nil {
+		return false
+	}
+	e.Fields = make(map[string]any, len(members))
+	for key, v := range members {
+		switch key {
+		case "time", "@timestamp":
+			e.Time = parseEntryTime(v)
+		case "level", "log.level":
+			if name, ok := v.(string); ok {
+				if level, err := parseLevel(name); err == nil {
+					e.Level, e.HasLevel = level, true
+				}
+			}
+		case "msg", "message":
+			e.Message, _ = v.(string)
+		case "fields":
+			if obj, ok := v.(map[string]any); ok {
+				maps.Copy(e.Fields, obj)
+				continue
+			}
+			e.Fields[key] = v
+		default:
+			flattenMember(e.Fields, key, v)
+		}
+	}
+	return true
+}
+
+// flattenMember adds a member to fields, nested objects as members with dotted keys
+func flattenMember(fields map[string]any, key string, v any) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		fields[key] = v
+		return
+	}
+	for k, nested := range obj {
+		flattenMember(fields, key+"."+k, nested)
+	}
+}
+
+// parseEntryTime parses a JSON time member, a string in the configured layout or RFC 3339, or an epoch number
+func parseEntryTime(v any) time.Time {
+	switch t := v.(type) {
+	case string:
+		return parseTextTime(t)
+	case float64:
+		return epochTime(int64(t))
+	}
+	return time.Time{}
+}
+
+// parseTextTime parses a time stamp in the configured layout or RFC 3339
+func parseTextTime(s string) time.Time {
+	for _, layout := range []string{timestampLayout, time.RFC3339Nano} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// epochTime converts an epoch time stamp in the configured unit, or guessed from its magnitude
+func epochTime(n int64) time.Time {
+	unit := timestampUnit
+	if unit == 0 {
+		switch {
+		case n > 1e17:
+			unit = time.Nanosecond
+		case n > 1e14:
+			unit = time.Microsecond
+		case n > 1e11:
+			unit = time.Millisecond
+		default:
+			unit = time.Second
+		}
+	}
+	return time.Unix(0, n*int64(unit))
+}
+
+// parseTextEntry parses a record of the txt format: the time stamp and level if present, the key=value
+// metadata, the trace, then the message followed by key and value pairs
+func parseTextEntry(e *QueryEntry, line string) {
+	tokens := splitTextTokens(line)
+	e.Fields = make(map[string]any)
+
+	// The time stamp spans as many tokens as the layout has spaces
+	if n := strings.Count(timestampLayout, " ") + 1; timestampUnit == 0 && len(tokens) >= n {
+		if t := parseTextTime(strings.Join(tokens[:n], " ")); !t.IsZero() {
+			e.Time, tokens = t, tokens[n:]
+		}
+	} else if len(tokens) > 0 {
+		if n, err := strconv.ParseInt(tokens[0], 10, 64); err == nil {
+			e.Time, tokens = epochTime(n), tokens[1:]
+		}
+	}
+	if len(tokens) > 0 && isLevelName(tokens[0]) {
+		if level, err := parseLevel(tokens[0]); err == nil {
+			e.Level, e.HasLevel, tokens = level, true, tokens[1:]
+		}
+	}
+
+	// Sequence number, metadata and deadline
+	for len(tokens) > 0 {
+		key, value, ok := strings.Cut(tokens[0], "=")
+		if !ok || !isTextKey(key) {
+			break
+		}
+		e.Fields[key] = unquoteText(value)
+		tokens = tokens[1:]
+	}
+
+	// A trace of more than one function is joined with " -> "
+	if len(tokens) > 2 && tokens[1] == "->" {
+		trace := tokens[0]
+		for tokens = tokens[1:]; len(tokens) > 1 && tokens[0] == "->"; tokens = tokens[2:] {
+			trace += " -> " + tokens[1]
+		}
+		e.Fields["trace"] = trace
+	}
+
+	if len(tokens) > 0 {
+		e.Message, tokens = unquoteText(tokens[0]), tokens[1:]
+	}
+	for ; len(tokens) > 1; tokens = tokens[2:] {
+		e.Fields[unquoteText(tokens[0])] = unquoteText(tokens[1])
+	}
+	if len(tokens) == 1 {
+		e.Fields[badKey] = unquoteText(tokens[0])
+	}
+}
+
+// splitTextTokens splits a text line at spaces outside of quoted strings
+func splitTextTokens(line string) []string {
+	var tokens []string
+	start, quoted := 0, false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ' ':
+			if !quoted {
+				if i > start {
+					tokens = append(tokens, line[start:i])
+				}
+				start = i + 1
+			}
+		}
+	}
+	if start < len(line) {
+		tokens = append(tokens, line[start:])
+	}
+	return tokens
+}
+
+// isTextKey reports whether a token prefix is a field key, not a value containing '='
+func isTextKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if c <= ' ' || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// unquoteText removes the quotes and escapes of a quoted text value
+func unquoteText(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil && utf8.ValidRune(rune(r)) {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -3,6 +3,8 @@ package quick
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/LixenWraith/logger"
 )
@@ -43,6 +45,85 @@ func Error(args ...any) {
 	logger.Error(context.Background(), args...)
 }
 
+// Debugf logs a message formatted with fmt.Sprintf at debug level, like log.Printf.
+// Message is dropped if logger's level is higher than debug.
+func Debugf(format string, args ...any) {
+	if !logger.EnsureInitialized() {
+		return
+	}
+	logger.Debugf(context.Background(), format, args...)
+}
+
+// Infof logs a message formatted with fmt.Sprintf at info level, like log.Printf.
+// Message is dropped if logger's level is higher than info.
+func Infof(format string, args ...any) {
+	if !logger.EnsureInitialized() {
+		return
+	}
+	logger.Infof(context.Background(), format, args...)
+}
+
+// Warnf logs a message formatted with fmt.Sprintf at warning level, like log.Printf.
+// Message is dropped if logger's level is higher than warn.
+func Warnf(format string, args ...any) {
+	if !logger.EnsureInitialized() {
+		return
+	}
+	logger.Warnf(context.Background(), format, args...)
+}
+
+// Errorf logs a message formatted with fmt.Sprintf at error level, like log.Printf.
+// Message is dropped if logger's level is higher than error.
+func Errorf(format string, args ...any) {
+	if !logger.EnsureInitialized() {
+		return
+	}
+	logger.Errorf(context.Background(), format, args...)
+}
+
+// fatalFlushTimeout bounds the synchronous flush of Panic
+const fatalFlushTimeout = 2 * time.Second
+
+// Fatal logs an error message, flushes and shuts down the logger, then exits with status 1.
+// The message is synchronously written to disk before exiting.
+func Fatal(args ...any) {
+	if logger.EnsureInitialized() {
+		logger.Error(context.Background(), args...)
+	}
+	logger.Exit(1)
+}
+
+// Panic logs an error message, flushes it to disk and panics with the message.
+// The logger keeps running, so the panic can be recovered.
+func Panic(args ...any) {
+	if logger.EnsureInitialized() {
+		logger.Error(context.Background(), args...)
+		ctx, cancel := context.WithTimeout(context.Background(), fatalFlushTimeout)
+		_ = logger.Flush(ctx)
+		cancel()
+	}
+	panic(panicMessage(args))
+}
+
+// Fatalf is Fatal with a message formatted with fmt.Sprintf, like log.Fatalf.
+func Fatalf(format string, args ...any) {
+	Fatal(fmt.Sprintf(format, args...))
+}
+
+// Panicf is Panic with a message formatted with fmt.Sprintf, like log.Panicf.
+func Panicf(format string, args ...any) {
+	Panic(fmt.Sprintf(format, args...))
+}
+
+// panicMessage joins the arguments into the panic value
+func panicMessage(args []any) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = fmt.Sprint(arg)
+	}
+	return strings.Join(parts, " ")
+}
+
 // DebugTrace is Debug log with trace.
 func DebugTrace(depth int, args ...any) {
 	if !logger.EnsureInitialized() {
@@ -103,7 +184,7 @@ func Message(args ...any) {
 // e.g. quick.Config("level=debug")
 func Config(args ...string) error {
 	if !logger.EnsureInitialized() {
-		return fmt.Errorf("logger initialization failed")
+		return fmt.Errorf("logger initialization failed: %w", Err())
 	}
 
 	if len(args) == 0 {
@@ -118,8 +199,33 @@ func Config(args ...string) error {
 	return logger.Config(cfg)
 }
 
+// Err returns the reason quick logging calls are dropped: the error of the failed automatic initialization,
+// logger.ErrShutdown after Shutdown, or nil if the logger is running or initializes with the next call.
+func Err() error {
+	if !logger.IsDisabled() {
+		return nil
+	}
+	if err := logger.LastInitError(); err != nil {
+		return err
+	}
+	return logger.ErrShutdown
+}
+
+// Retry clears the disabled state and attempts the automatic initialization again without waiting for the
+// retry interval, e.g. after the permissions of the log directory were fixed. It returns Err after the attempt.
+func Retry() error {
+	logger.Reset()
+	logger.EnsureInitialized()
+	return Err()
+}
+
 // Shutdown performs a graceful shutdown of the logger with default default timeout
 func Shutdown() {
 	ctx := context.Background()
 	_ = logger.Shutdown(ctx)
-}
\ No newline at end of file
+}
+
+// Exit writes the queued records, shuts down the logger and exits the program with code, see logger.Exit.
+func Exit(code int) {
+	logger.Exit(code)
+}
@@ -22,6 +22,8 @@ func config(args ...string) (*logger.LoggerConfig, error) {
 		if err := setValue(cfg, key, value); err != nil {
 			return nil, fmt.Errorf("config error: %s", err)
 		}
+		// Explicit values apply even when zero, e.g. "show_level=false"
+		cfg.MarkSet(strings.ToLower(key))
 	}
 	return cfg, nil
 }
@@ -50,7 +52,7 @@ func setValue(cfg *logger.LoggerConfig, key, value string) error {
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		if tag := field.Tag.Get("toml"); tag == key {
+		if tag := field.Tag.Get("toml"); tag == key && tag != "-" {
 			f := v.Field(i)
 			if !f.IsValid() {
 				return fmt.Errorf("unknown config key: %s", key)
@@ -58,9 +60,9 @@ func setValue(cfg *logger.LoggerConfig, key, value string) error {
 
 			switch f.Kind() {
 			case reflect.Int64:
-				if strings.EqualFold(key, "level") {
+				if key == "level" || key == "shed_level" || strings.HasSuffix(key, "_min_level") {
 					// Special handling for level
-					level, err := parseLevel(value)
+					level, err := logger.ParseLevel(value)
 					if err != nil {
 						return err
 					}
@@ -97,6 +99,37 @@ func setValue(cfg *logger.LoggerConfig, key, value string) error {
 				}
 				f.SetBool(val)
 
+			case reflect.Slice:
+				if f.Type().Elem().Kind() != reflect.String {
+					return fmt.Errorf("unsupported config type for %s", key)
+				}
+				// Comma-separated list, empty value clears the list
+				items := []string{}
+				for _, item := range strings.Split(value, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						items = append(items, item)
+					}
+				}
+				f.Set(reflect.ValueOf(items))
+
+			case reflect.Map:
+				if f.Type().Key().Kind() != reflect.String || f.Type().Elem().Kind() != reflect.String {
+					return fmt.Errorf("unsupported config type for %s", key)
+				}
+				// Comma-separated key:value pairs, empty value clears the map
+				items := map[string]string{}
+				for _, item := range strings.Split(value, ",") {
+					if item = strings.TrimSpace(item); item == "" {
+						continue
+					}
+					k, val, ok := strings.Cut(item, ":")
+					if !ok {
+						return fmt.Errorf("invalid key:value pair for %s: %s", key, item)
+					}
+					items[strings.TrimSpace(k)] = strings.TrimSpace(val)
+				}
+				f.Set(reflect.ValueOf(items))
+
 			default:
 				return fmt.Errorf("unsupported config type for %s", key)
 			}
@@ -106,21 +139,3 @@ func setValue(cfg *logger.LoggerConfig, key, value string) error {
 	}
 	return fmt.Errorf("unknown config key: %s", key)
 }
-
-// parseLevel converts level string to corresponding int64 constant.
-// Accepts both format variants: "debug"/"leveldebug", "info"/"levelinfo" etc.
-// Returns error if level string is invalid.
-func parseLevel(level string) (int64, error) {
-	switch strings.ToLower(level) {
-	case "debug", "leveldebug":
-		return logger.LevelDebug, nil
-	case "info", "levelinfo":
-		return logger.LevelInfo, nil
-	case "warn", "levelwarn":
-		return logger.LevelWarn, nil
-	case "error", "levelerror":
-		return logger.LevelError, nil
-	default:
-		return 0, fmt.Errorf("invalid level: %s", level)
-	}
-}
\ No newline at end of file
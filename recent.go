@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Recent records ring, written by the processor and read by Recent and ServeHTTP.
+// Slots keep their backing arrays so a full ring records lines without allocating.
+var (
+	recentMu    sync.Mutex
+	recentLines [][]byte
+	recentNext  int  // slot of the next record
+	recentFull  bool // all slots hold a record
+	recentSize  atomic.Int64
+)
+
+// setRecentSize resizes the ring to size records, 0 disables it. The latest records are kept.
+func setRecentSize(size int) {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+
+	recentSize.Store(int64(max(size, 0)))
+	if size == len(recentLines) {
+		return
+	}
+	kept := recentLocked(size)
+	recentLines = nil
+	recentNext, recentFull = 0, false
+	if size <= 0 {
+		return
+	}
+	recentLines = make([][]byte, size)
+	for _, line := range kept {
+		recentLines[recentNext] = line
+		recentNext++
+	}
+	if recentNext == size {
+		recentNext, recentFull = 0, true
+	}
+}
+
+// recordRecent copies a serialized record into the ring.
+// It must only be called from the processor goroutine.
+func recordRecent(line []byte) {
+	if recentSize.Load() == 0 {
+		return
+	}
+
+	recentMu.Lock()
+	defer recentMu.Unlock()
+
+	if len(recentLines) == 0 {
+		return
+	}
+	recentLines[recentNext] = append(recentLines[recentNext][:0], line...)
+	recentNext++
+	if recentNext == len(recentLines) {
+		recentNext, recentFull = 0, true
+	}
+}
+
+// recentLocked returns copies of up to n of the latest lines, all with n <= 0, oldest first.
+// recentMu must be held.
+func recentLocked(n int) [][]byte {
+	count := recentNext
+	if recentFull {
+		count = len(recentLines)
+	}
+	if n > 0 && n < count {
+		count = n
+	}
+
+	lines := make([][]byte, 0, count)
+	start := recentNext - count
+	if start < 0 {
+		start += len(recentLines)
+	}
+	for i := 0; i < count; i++ {
+		line := recentLines[(start+i)%len(recentLines)]
+		lines = append(lines, append([]byte(nil), line...))
+	}
+	return lines
+}
+
+// recent returns up to n of the latest records as serialized lines, oldest first
+func recent(n int) []string {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+
+	lines := recentLocked(n)
+	records := make([]string, len(lines))
+	for i, line := range lines {
+		records[i] = strings.TrimSuffix(string(line), "\n")
+	}
+	return records
+}
+
+// serveRecent writes the latest records, limited by the optional "n" parameter, one per line
+func serveRecent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	n := 0
+	if v := r.FormValue("n"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n < 0 {
+			http.Error(w, "invalid record count: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if isJSONFormat(format) {
+		contentType = "application/x-ndjson"
+	}
+	w.Header().Set("Content-Type", contentType)
+	for _, line := range recent(n) {
+		_, _ = w.Write([]byte(line + "\n"))
+	}
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// panicFlushTimeout bounds the synchronous flush after logging a recovered panic
+const panicFlushTimeout = 2 * time.Second
+
+// logPanic logs a recovered panic value with the stack of the panicking goroutine at error level and
+// waits until it is synced to disk. It returns the panic as an error, wrapping panic values that are errors.
+func logPanic(ctx context.Context, value any) error {
+	var err error
+	if e, ok := value.(error); ok {
+		err = fmt.Errorf("panic: %w", e)
+	} else {
+		err = fmt.Errorf("panic: %v", value)
+	}
+
+	logFields(ctx, flags, LevelError, 0, "Recovered panic",
+		Str("panic", stringifyMessage(value)),
+		Str("stack", panicStack()),
+	)
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), panicFlushTimeout)
+	defer cancel()
+	_ = flushLogger(flushCtx)
+	return err
+}
+
+// panicStack returns the stack of the panicking goroutine from the frame that panicked. Called from a
+// deferred function, the stack still includes the frames of the panic, the recovering frames above the
+// runtime panic call are cut.
+func panicStack() string {
+	stack := string(debug.Stack())
+	header, frames, _ := strings.Cut(stack, "\n")
+	if i := strings.Index(frames, "\npanic("); i >= 0 {
+		// Skip the panic call and its location line
+		rest := frames[i+1:]
+		for n := 0; n < 2; n++ {
+			if _, after, ok := strings.Cut(rest, "\n"); ok {
+				rest = after
+			}
+		}
+		frames = rest
+	}
+	return header + "\n" + strings.TrimRight(frames, "\n")
+}
+
+// capturePanic runs fn, logging and returning a panic as an error
+func capturePanic(ctx context.Context, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = logPanic(ctx, r)
+		}
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Queue types selecting the buffer between logging calls and the processor
+const (
+	QueueChannel = "channel" // buffered Go channel
+	QueueRing    = "ring"    // lock-free ring buffer, for many concurrent producers
+)
+
+// ringRetryInterval is how often a producer waiting for room in a full ring retries
+const ringRetryInterval = 50 * time.Microsecond
+
+// logRing replaces the channel as the record queue with QueueRing, nil otherwise.
+// Like logChannel, it is only replaced while the processor is stopped.
+var logRing *recordRing
+
+// cacheLinePad separates fields written by different goroutines into their own cache lines
+type cacheLinePad [64]byte
+
+// ringSlot holds one queued record. seq equals the position for a free slot of the lap and the
+// position + 1 once the record is published.
+type ringSlot struct {
+	seq    atomic.Uint64
+	record logRecord
+}
+
+// recordRing is a bounded lock-free multi-producer single-consumer queue of records with a capacity
+// of a power of two. Producers claim a position with a CAS on head, the processor alone reads at tail.
+type recordRing struct {
+	_     cacheLinePad
+	head  atomic.Uint64 // next position claimed by a producer
+	_     cacheLinePad
+	tail  atomic.Uint64 // next position read by the processor
+	_     cacheLinePad
+	mask  uint64
+	size  int64 // requested capacity, BufferSize
+	slots []ringSlot
+	wake  chan struct{} // signals the processor that records were published
+}
+
+// newRecordRing returns a ring holding at least size records
+func newRecordRing(size int64) *recordRing {
+	n := uint64(1)
+	for n < uint64(max(size, 1)) {
+		n <<= 1
+	}
+	r := &recordRing{
+		mask:  n - 1,
+		size:  size,
+		slots: make([]ringSlot, n),
+		wake:  make(chan struct{}, 1),
+	}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+// push queues a record without waiting and reports false if the ring is full
+func (r *recordRing) push(record logRecord) bool {
+	for {
+		pos := r.head.Load()
+		slot := &r.slots[pos&r.mask]
+		switch diff := int64(slot.seq.Load() - pos); {
+		case diff == 0:
+			if !r.head.CompareAndSwap(pos, pos+1) {
+				continue
+			}
+			slot.record = record
+			slot.seq.Store(pos + 1)
+			select {
+			case r.wake <- struct{}{}:
+			default:
+			}
+			return true
+		case diff < 0:
+			// The slot still holds the record of the previous lap
+			return false
+		}
+		// Another producer claimed the position, retry with the next one
+	}
+}
+
+// pop takes the next published record. It must only be called by the processor.
+func (r *recordRing) pop() (logRecord, bool) {
+	pos := r.tail.Load()
+	slot := &r.slots[pos&r.mask]
+	if slot.seq.Load() != pos+1 {
+		return logRecord{}, false
+	}
+	record := slot.record
+	slot.record = logRecord{} // release references held by the record
+	slot.seq.Store(pos + r.mask + 1)
+	r.tail.Store(pos + 1)
+	return record, true
+}
+
+// len returns the number of queued records, including records claimed but not yet published
+func (r *recordRing) len() int {
+	return int(r.head.Load() - r.tail.Load())
+}
+
+// cap returns the number of records the ring holds
+func (r *recordRing) cap() int {
+	return len(r.slots)
+}
+
+// pushWait queues a record, retrying while the ring is full until stop is closed, and reports whether it was queued
+func (r *recordRing) pushWait(record logRecord, stop <-chan struct{}) bool {
+	for !r.push(record) {
+		select {
+		case <-stop:
+			return false
+		case <-time.After(ringRetryInterval):
+		}
+	}
+	return true
+}
+
+// queueLen returns the number of records in the active queue
+func queueLen() int {
+	if ring := logRing; ring != nil {
+		return ring.len()
+	}
+	return len(logChannel)
+}
+
+// queueCap returns the capacity of the active queue
+func queueCap() int {
+	if ring := logRing; ring != nil {
+		return ring.cap()
+	}
+	return cap(logChannel)
+}
+
+// enqueue queues a record in the active queue without waiting and reports false if it is full
+func enqueue(record logRecord) bool {
+	if ring := logRing; ring != nil {
+		return ring.push(record)
+	}
+	select {
+	case logChannel <- record:
+		return true
+	default:
+		return false
+	}
+}
+
+// queueChanged reports whether the active queue differs in type or size from the configuration
+func queueChanged() bool {
+	if queueType == QueueRing {
+		return logRing == nil || logRing.size != bufferSize.Load()
+	}
+	return logRing != nil || int64(cap(logChannel)) != bufferSize.Load()
+}
+
+// newQueue replaces the active queue with an empty one of the configured type and size, mu must be held
+// with the processor stopped. With a ring, the channel stays unbuffered and unused.
+func newQueue() {
+	if queueType == QueueRing {
+		logRing = newRecordRing(bufferSize.Load())
+		logChannel = make(chan logRecord)
+		return
+	}
+	logRing = nil
+	logChannel = make(chan logRecord, bufferSize.Load())
+}
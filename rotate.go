@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,10 +13,33 @@ import (
 var (
 	name      string
 	extension string
+
+	fileSeq atomic.Uint64 // monotonic fallback for filename collisions
+
+	minRotateInterval time.Duration
+	lastRotation      time.Time // only accessed by the processor goroutine
+)
+
+// Deferred file creation retry policy
+const (
+	lazyOpenBackoff  = 50 * time.Millisecond // delay before retrying after a failed attempt, doubled each time
+	lazyOpenInterval = 1 * time.Second       // maximum delay between attempts
 )
 
+// Deferred file creation state, the retry times are only accessed by the processor goroutine
+var (
+	lazyOpen        bool
+	nextOpenAttempt time.Time     // earliest time of the next attempt, zero if none is scheduled
+	openRetryDelay  time.Duration // delay after the next failed attempt
+)
+
+// maxFileSeqAttempts bounds the sequence fallback in case of persistent stat failures
+const maxFileSeqAttempts = 10000
+
 // generateLogFileName creates a unique log filename using timestamp with increasing precision.
 // It ensures uniqueness by progressively adding more precise subsecond components.
+// If all precisions collide, e.g. after the wall clock stepped backwards, a monotonic
+// sequence number is appended so that a name can always be generated.
 func generateLogFileName(baseName string, timestamp time.Time) (string, error) {
 	baseTimestamp := timestamp.Format("060102_150405")
 	// Always include first decimal place (tenth of a second)
@@ -39,6 +63,17 @@ func generateLogFileName(baseName string, timestamp time.Time) (string, error) {
 			return filename, nil
 		}
 	}
+
+	// Clock independent fallback: <name>_<timestamp>_<nanoseconds>_<seq>.<ext>
+	for attempt := 0; attempt < maxFileSeqAttempts; attempt++ {
+		filename = fmt.Sprintf("%s_%s_%09d_%d.%s",
+			baseName, baseTimestamp, timestamp.UnixNano()%1e9, fileSeq.Add(1), extension)
+
+		fullPath = filepath.Join(directory, filename)
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			return filename, nil
+		}
+	}
 	return "", fmt.Errorf("failed to generate unique log filename")
 }
 
@@ -52,30 +87,59 @@ func pow10(n int) int64 {
 	return result
 }
 
+// createNewLogFileAttempts bounds retries when another process creates the same file name first
+const createNewLogFileAttempts = 3
+
 // createNewLogFile generates and opens a new log file with proper permissions.
-// It ensures unique naming and proper file creation with append mode.
+// It ensures unique naming and proper file creation with append mode. The file is created exclusively,
+// so processes sharing the directory never write to the same file, and holds a shared lock while
+// open, which keeps cleanup in other processes from deleting it.
 func createNewLogFile(ctx context.Context) (*os.File, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		filename, err := generateLogFileName(name, time.Now())
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate log filename: %w", err)
+		var file *os.File
+		for attempt := 1; ; attempt++ {
+			filename, err := generateLogFileName(name, clockNow())
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate log filename: %w", err)
+			}
+			file, err = openLogFile(filepath.Join(directory, filename))
+			if err == nil {
+				break
+			}
+			if !os.IsExist(err) || attempt == createNewLogFileAttempts {
+				return nil, fmt.Errorf("failed to create log file: %w", err)
+			}
+		}
+		if err := lockShared(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock log file: %w", err)
 		}
 
-		file, err := os.OpenFile(
-			filepath.Join(directory, filename),
-			os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-			0644,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create log file: %w", err)
+		// Files written through a pipeline start with the magic of each stage
+		if len(pipeline) > 0 {
+			if _, err := file.Write(pipelineHeader()); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to write pipeline file header: %w", err)
+			}
 		}
+		filesCreated.Add(1)
 		return file, nil
 	}
 }
 
+// rotationAllowed prevents rotation thrashing under bursts. A file without records is never rotated,
+// so a single record larger than the size limit is written to the current file instead of opening
+// empty files, and size based rotations are spaced at least minRotateInterval apart.
+func rotationAllowed(size int64) bool {
+	if size == 0 {
+		return false
+	}
+	return minRotateInterval <= 0 || clockNow().Sub(lastRotation) >= minRotateInterval
+}
+
 // rotateLogFile handles the log rotation process, creating new file and closing old one.
 // It updates all necessary state while maintaining thread safety.
 func rotateLogFile(ctx context.Context) error {
@@ -83,6 +147,7 @@ func rotateLogFile(ctx context.Context) error {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
+		flushWrites()
 		newFile, err := createNewLogFile(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to create new log file: %w", err)
@@ -90,15 +155,203 @@ func rotateLogFile(ctx context.Context) error {
 
 		oldFile := currentFile.Load().(*os.File)
 		if oldFile != nil {
+			if err := writeFileFooter(oldFile, filepath.Base(newFile.Name())); err != nil {
+				reportError(fmt.Errorf("failed to write file footer: %w", err))
+			}
+			if err := writeAuditFooter(oldFile); err != nil {
+				reportError(fmt.Errorf("failed to write audit footer: %w", err))
+			}
 			if err := oldFile.Close(); err != nil {
 				newFile.Close()
 				return fmt.Errorf("failed to close old log file: %w", err)
 			}
 		}
 
-		currentFile.Store(newFile)
-		currentSize.Store(0)
-
+		switchFile(newFile)
 		return nil
 	}
-}
\ No newline at end of file
+}
+
+// startFile makes f the active file, continuing the audit chain and writing the file header.
+// previous names the file written before, if any.
+func startFile(f *os.File, previous string) {
+	currentFile.Store(f)
+	currentSize.Store(0)
+	if f != nil {
+		if err := writeAuditHeader(f); err != nil {
+			reportError(fmt.Errorf("failed to write audit header: %w", err))
+		}
+		writeFileHeader(f, previous)
+	}
+}
+
+// switchFile makes a new file the active one after the previous one was closed
+func switchFile(newFile *os.File) {
+	var previous string
+	if oldFile := currentFile.Load().(*os.File); oldFile != nil {
+		previous = filepath.Base(oldFile.Name())
+	}
+	startFile(newFile, previous)
+	lastRotation = clockNow()
+	invalidateDiskCheck()
+	emitDiag(DiagEvent{Kind: DiagRotation, File: filepath.Base(newFile.Name()), Previous: previous})
+
+	rotationCount.Add(1)
+	saveStats(shutdownRunning)
+}
+
+// Write recovery policy
+const (
+	writeRetryBackoff  = 10 * time.Millisecond // delay before recovering again after a failed attempt, doubled each time
+	writeRetryInterval = 1 * time.Second       // maximum delay between attempts
+)
+
+// Write recovery state, only accessed by the processor goroutine
+var (
+	nextWriteRetry  time.Time     // earliest time a failed write is recovered again
+	writeRetryDelay time.Duration // delay after the next failed attempt
+)
+
+// recoverWrite writes n records of data that failed to be written into a new log file, recreating the log
+// directory if needed, e.g. after the directory was deleted or the device reported an I/O error. The failed
+// file is closed once the new file is created. A failed attempt is not retried in place, the processor
+// keeps serving records and the next write after a backoff growing to writeRetryInterval attempts again,
+// records written meanwhile are dropped.
+// It must only be called from the processor goroutine.
+func recoverWrite(data []byte, n uint64, writeErr error) error {
+	if clockNow().Before(nextWriteRetry) {
+		return writeErr
+	}
+
+	err := os.MkdirAll(directory, 0755)
+	if err == nil {
+		var newFile *os.File
+		if newFile, err = createNewLogFile(context.Background()); err == nil {
+			if err = switchRecoveryFile(newFile, data, n); err == nil {
+				nextWriteRetry, writeRetryDelay = time.Time{}, 0
+				return nil
+			}
+		}
+	}
+
+	writeRetryDelay = min(max(2*writeRetryDelay, writeRetryBackoff), writeRetryInterval)
+	nextWriteRetry = clockNow().Add(writeRetryDelay)
+	return err
+}
+
+// switchRecoveryFile makes a new file the active one in place of a file that failed to write n records of
+// data, and writes them to it. The records were chained and encoded for the failed file: they are written
+// right after the audit header continuing the chain of the records the failed file holds, followed by the
+// file header. Records are encoded independently, so pipeline frames remain valid in the new file.
+func switchRecoveryFile(newFile *os.File, data []byte, n uint64) error {
+	var previous string
+	oldFile := currentFile.Load().(*os.File)
+	if oldFile != nil {
+		previous = filepath.Base(oldFile.Name())
+	}
+
+	chained := saveAuditState()
+	if auditChain {
+		// The failed file ends before the records of data
+		pendingChain.restore()
+		if err := writeAuditFooter(oldFile); err != nil {
+			reportError(fmt.Errorf("failed to write audit footer: %w", err))
+		}
+	}
+	if oldFile != nil {
+		oldFile.Close()
+	}
+
+	currentFile.Store(newFile)
+	currentSize.Store(0)
+	if err := writeAuditHeader(newFile); err != nil {
+		return err
+	}
+	if _, err := newFile.Write(data); err != nil {
+		return err
+	}
+	if auditChain {
+		chained.restore()
+		auditCount = int64(n)
+	}
+	writeFileHeader(newFile, previous)
+
+	lastRotation = clockNow()
+	invalidateDiskCheck()
+	emitDiag(DiagEvent{Kind: DiagRotation, File: filepath.Base(newFile.Name()), Previous: previous})
+	rotationCount.Add(1)
+	saveStats(shutdownRunning)
+	return nil
+}
+
+// reopenChan carries reopen requests to the processor goroutine, which owns the active file.
+// Each request carries a channel receiving the reopen result.
+var reopenChan = make(chan chan error)
+
+// activeFileMissing reports whether the active file was deleted or replaced by an external tool,
+// e.g. logrotate moving it away, in which case writes would go to an unlinked inode.
+func activeFileMissing(f *os.File) bool {
+	pathInfo, err := os.Stat(f.Name())
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return !os.SameFile(pathInfo, fileInfo)
+}
+
+// reopenLogFile recreates the log directory if needed and switches to a new log file.
+// It must only be called from the processor goroutine.
+func reopenLogFile(ctx context.Context) error {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return rotateLogFile(ctx)
+}
+
+// requestReopen asks the running processor to reopen the log file and waits for the result.
+func requestReopen() error {
+	mu.RLock()
+	ctx := processCtx
+	initialized := isInitialized.Load()
+	mu.RUnlock()
+
+	if !initialized || ctx == nil {
+		return ErrNotInitialized
+	}
+
+	done := make(chan error, 1)
+	select {
+	case reopenChan <- done:
+	case <-ctx.Done():
+		return ErrShutdown
+	}
+	return <-done
+}
+
+// openDeferredFile creates the log directory and file when Init could not, e.g. because a volume
+// was not mounted yet. A failed attempt schedules the next one after a backoff growing to lazyOpenInterval,
+// made by the next write or the flush ticker, so records are dropped quickly while the directory stays
+// unavailable and the processor never waits.
+// It must only be called from the processor goroutine.
+func openDeferredFile(ctx context.Context) error {
+	if clockNow().Before(nextOpenAttempt) {
+		return fmt.Errorf("log file not available")
+	}
+
+	err := os.MkdirAll(directory, 0755)
+	if err == nil {
+		var file *os.File
+		if file, err = createNewLogFile(ctx); err == nil {
+			nextOpenAttempt, openRetryDelay = time.Time{}, 0
+			startFile(file, "")
+			return nil
+		}
+	}
+
+	openRetryDelay = min(max(2*openRetryDelay, lazyOpenBackoff), lazyOpenInterval)
+	nextOpenAttempt = clockNow().Add(openRetryDelay)
+	return fmt.Errorf("failed to open deferred log file: %w", err)
+}
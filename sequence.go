@@ -0,0 +1,15 @@
+package logger
+
+import "sync/atomic"
+
+// recordSeq is the last sequence number given to a record, numbers start at 1 and are never reused
+// within the process, also across reconfiguration
+var recordSeq atomic.Uint64
+
+// stampSequence numbers the record if its flags enable sequence numbers and it has no number yet.
+// Records are numbered at the logging call, records created by the logger itself when written.
+func stampSequence(record *logRecord) {
+	if record.Flags&FlagShowSequence != 0 && record.Seq == 0 {
+		record.Seq = recordSeq.Add(1)
+	}
+}
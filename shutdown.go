@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// exitTimeout bounds the shutdown of Exit, records still queued after it are abandoned
+const exitTimeout = 5 * time.Second
+
+// exitFunc ends the process in Exit, os.Exit unless replaced by SetExitFunc
+var exitFunc atomic.Pointer[func(int)]
+
+// setExitFunc replaces the function ending the process in Exit, nil restores os.Exit
+func setExitFunc(fn func(code int)) {
+	if fn == nil {
+		exitFunc.Store(nil)
+		return
+	}
+	exitFunc.Store(&fn)
+}
+
+// exitLogger shuts the logger down, writing and syncing the queued records, then ends the process with code.
+// Unlike finalizers, which are not run by os.Exit, it does not lose the records still queued.
+func exitLogger(code int) {
+	if isInitialized.Load() {
+		ctx, cancel := context.WithTimeout(context.Background(), exitTimeout)
+		if err := shutdownLogger(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Logger shutdown error: %v\n", err)
+		}
+		cancel()
+	}
+	if fn := exitFunc.Load(); fn != nil {
+		(*fn)(code)
+		return
+	}
+	os.Exit(code)
+}
+
+// ShutdownError is returned by Shutdown when its context ended before all queued records were written.
+// The abandoned records are counted as dropped.
+type ShutdownError struct {
+	Flushed   uint64 // queued records written during the shutdown
+	Abandoned uint64 // queued records left unwritten at the deadline
+	Err       error  // the context error, or the error closing the log file
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("shutdown abandoned %d queued records after writing %d: %v", e.Abandoned, e.Flushed, e.Err)
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// statsEvent identifies stats dumps in the "event" field
+const statsEvent = "stats"
+
+// Signal handler state, guarded by signalMu
+var (
+	signalMu            sync.Mutex
+	signalsEnabled      bool
+	signalDebugDuration time.Duration
+	signalStop          chan struct{}
+	signalDone          chan struct{}
+)
+
+// Debug boost state of SIGUSR1, guarded by boostMu. While boosting, the level is LevelDebug and
+// boostLevel holds the level restored when boostTimer fires.
+var (
+	boostMu    sync.Mutex
+	boostTimer *time.Timer
+	boostLevel int64
+)
+
+// configureSignals starts or stops the signal handler to match the configuration
+func configureSignals(enabled bool, debugDuration time.Duration) {
+	signalMu.Lock()
+	defer signalMu.Unlock()
+
+	signalDebugDuration = debugDuration
+	// Notify without signals would relay all of them
+	enabled = enabled && len(controlSignals) > 0
+	if enabled == signalsEnabled {
+		return
+	}
+	if !enabled {
+		stopSignalsLocked()
+		return
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, controlSignals...)
+	signalStop = make(chan struct{})
+	signalDone = make(chan struct{})
+	signalsEnabled = true
+	go handleSignals(sigs, signalStop, signalDone)
+}
+
+// stopSignals stops the signal handler if running
+func stopSignals() {
+	signalMu.Lock()
+	defer signalMu.Unlock()
+	stopSignalsLocked()
+}
+
+// stopSignalsLocked stops the signal handler and waits for it to exit, signalMu must be held
+func stopSignalsLocked() {
+	if !signalsEnabled {
+		return
+	}
+	close(signalStop)
+	<-signalDone
+	signalsEnabled = false
+}
+
+// handleSignals serves signals until stop is closed. Handlers that wait for the processor run in their own
+// goroutine, as the processor may be waiting for mu held by a reconfiguration stopping this handler.
+func handleSignals(sigs chan os.Signal, stop, done chan struct{}) {
+	defer close(done)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case sig := <-sigs:
+			switch sig {
+			case signalReopen:
+				go requestReopen()
+			case signalDebug:
+				signalMu.Lock()
+				duration := signalDebugDuration
+				signalMu.Unlock()
+				boostDebug(duration)
+			case signalStats:
+				logStats()
+			}
+		}
+	}
+}
+
+// boostDebug sets the level to LevelDebug for duration, extending a running boost.
+// The previous level is restored when the boost ends, unless the level was changed meanwhile.
+func boostDebug(duration time.Duration) {
+	boostMu.Lock()
+	defer boostMu.Unlock()
+
+	if boostTimer != nil {
+		boostTimer.Reset(duration)
+		return
+	}
+	boostLevel = logLevel.Load().(int64)
+	logLevel.Store(LevelDebug)
+	boostTimer = time.AfterFunc(duration, endDebugBoost)
+}
+
+// endDebugBoost restores the level saved by boostDebug
+func endDebugBoost() {
+	boostMu.Lock()
+	defer boostMu.Unlock()
+
+	if boostTimer == nil {
+		return
+	}
+	boostTimer.Stop()
+	boostTimer = nil
+	logLevel.CompareAndSwap(LevelDebug, boostLevel)
+}
+
+// cancelDebugBoost ends a running boost without restoring the level, used when the level is reconfigured
+func cancelDebugBoost() {
+	boostMu.Lock()
+	defer boostMu.Unlock()
+
+	if boostTimer != nil {
+		boostTimer.Stop()
+		boostTimer = nil
+	}
+}
+
+// configuredLevel returns the level of the running config, the level saved by a running boost
+func configuredLevel() int64 {
+	boostMu.Lock()
+	defer boostMu.Unlock()
+
+	if boostTimer != nil {
+		return boostLevel
+	}
+	return logLevel.Load().(int64)
+}
+
+// logStats writes the running counters to the log as an info record, regardless of the level
+func logStats() {
+	if !isInitialized.Load() {
+		return
+	}
+
+	var file string
+	if f, ok := currentFile.Load().(*os.File); ok && f != nil {
+		file = f.Name()
+	}
+	record := logRecord{
+		LogCtx:    context.Background(),
+		Flags:     flags,
+		TimeStamp: clockNow(),
+		Level:     LevelInfo,
+		HasMsg:    true,
+		Msg:       "Logger stats",
+	}
+	record.Fields = []Field{
+		Str("event", statsEvent),
+		Str("level", levelToString(logLevel.Load().(int64))),
+		Str("file", file),
+		Uint64("bytes_written", bytesWritten.Load()),
+		Uint64("records_written", recordsWritten.Load()),
+		Uint64("rotations", rotationCount.Load()),
+		Uint64("dropped", droppedLogs.Load()),
+		Int("queued", queueLen()),
+	}
+	sendLogRecord(record)
+}
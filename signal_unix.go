@@ -0,0 +1,17 @@
+//go:build !windows
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// Control signals handled with Signals enabled
+var (
+	signalReopen os.Signal = syscall.SIGHUP
+	signalDebug  os.Signal = syscall.SIGUSR1
+	signalStats  os.Signal = syscall.SIGUSR2
+
+	controlSignals = []os.Signal{signalReopen, signalDebug, signalStats}
+)
@@ -0,0 +1,14 @@
+//go:build windows
+
+package logger
+
+import "os"
+
+// Control signals handled with Signals enabled. Windows has no user signals, Signals has no effect.
+var (
+	signalReopen os.Signal
+	signalDebug  os.Signal
+	signalStats  os.Signal
+
+	controlSignals []os.Signal
+)
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is a log record as delivered to sinks after it was written to the log file.
+type Entry struct {
+	Time  time.Time
+	Level int64
+	Flags int64
+	Trace string
+	// Values are the positional values as logged: variadic args, followed by the message
+	// and a key and a value for each typed field of the *Fields API.
+	Values []any
+	// Line is the serialized record in the configured format, before encryption.
+	// It is only valid during the Write call and must be copied if retained.
+	Line []byte
+}
+
+// Message returns the message of the entry as the json format writes it: the first value, in its string form,
+// unless it is a Field.
+func (e *Entry) Message() string {
+	if len(e.Values) == 0 {
+		return ""
+	}
+	if _, ok := e.Values[0].(Field); ok {
+		return ""
+	}
+	return stringifyMessage(e.Values[0])
+}
+
+// Fields returns the key/value pairs following the message, paired as the json format writes them.
+// A Field value is a pair on its own and values without a string key are returned under "!BADKEY".
+func (e *Entry) Fields() []Field {
+	values := e.Values
+	if len(values) > 0 {
+		if _, ok := values[0].(Field); !ok {
+			values = values[1:]
+		}
+	}
+	fields := make([]Field, 0, len(values)/2+1)
+	for i := 0; i < len(values); i++ {
+		switch key := values[i].(type) {
+		case Field:
+			fields = append(fields, key)
+		case string:
+			if i+1 == len(values) {
+				fields = append(fields, Str(badKey, key))
+				break
+			}
+			fields = append(fields, Any(key, values[i+1]))
+			i++
+		default:
+			fields = append(fields, Any(badKey, key))
+		}
+	}
+	return fields
+}
+
+// Sink receives every written record in addition to the log file.
+// Write is called from the processor goroutine and should not block.
+type Sink interface {
+	Write(entry *Entry) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(entry *Entry) error
+
+// Write calls f(entry).
+func (f SinkFunc) Write(entry *Entry) error {
+	return f(entry)
+}
+
+// namedSink is a registered sink
+type namedSink struct {
+	name string
+	sink Sink
+}
+
+// Registered sinks, replaced copy-on-write so the processor reads them without locking
+var (
+	sinksMu sync.Mutex
+	sinks   atomic.Pointer[[]namedSink]
+)
+
+// addSink registers a sink under a unique name
+func addSink(name string, sink Sink) error {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	var current []namedSink
+	if p := sinks.Load(); p != nil {
+		current = *p
+	}
+	for _, s := range current {
+		if s.name == name {
+			return fmt.Errorf("sink already registered: %s", name)
+		}
+	}
+
+	updated := make([]namedSink, 0, len(current)+1)
+	updated = append(updated, current...)
+	updated = append(updated, namedSink{name: name, sink: sink})
+	sinks.Store(&updated)
+	return nil
+}
+
+// removeSink unregisters a sink, reporting whether it was registered
+func removeSink(name string) bool {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	p := sinks.Load()
+	if p == nil {
+		return false
+	}
+	updated := make([]namedSink, 0, len(*p))
+	for _, s := range *p {
+		if s.name != name {
+			updated = append(updated, s)
+		}
+	}
+	sinks.Store(&updated)
+	return len(updated) != len(*p)
+}
+
+// recordValues returns the positional values of a record, expanding typed fields
+func recordValues(r *logRecord) []any {
+	if !r.HasMsg && len(r.Fields) == 0 {
+		return r.Args
+	}
+	values := make([]any, 0, valueCount(r))
+	values = append(values, r.Args...)
+	if r.HasMsg {
+		values = append(values, r.Msg)
+	}
+	for i := range r.Fields {
+		values = append(values, r.Fields[i].Key, r.Fields[i].Value())
+	}
+	return values
+}
+
+// dispatchSinks delivers a written record to all registered sinks
+func dispatchSinks(r *logRecord, line []byte) {
+	p := sinks.Load()
+	if p == nil || len(*p) == 0 {
+		return
+	}
+
+	entry := Entry{
+		Time:   r.TimeStamp,
+		Level:  r.Level,
+		Flags:  r.Flags,
+		Trace:  r.Trace,
+		Values: recordValues(r),
+		Line:   line,
+	}
+	for _, s := range *p {
+		_ = s.sink.Write(&entry)
+	}
+}
@@ -0,0 +1,245 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// spillFrameHeader is the size of a spill frame header: 4-byte line length, 8-byte timestamp, 8-byte level
+const spillFrameHeader = 20
+
+// Spill draining limits: records drained at once, so queued records are not starved, and bytes read at once
+const (
+	spillDrainBatch = 256
+	spillReadChunk  = 1024 * 1024
+)
+
+// Spill queue state. Records overflowing the channel are appended by callers and drained by
+// the processor, the file is truncated whenever the queue runs empty.
+var (
+	spillMu       sync.Mutex
+	spillFile     *os.File // locked exclusively while open
+	spillBase     string   // directory and name the spill queue belongs to
+	spillMaxMB    int64
+	spillWriteOff int64
+	spillReadOff  int64
+	spillQueued   atomic.Bool // skips locking while the queue is empty
+)
+
+// spillSerializers provides serializers to callers spilling records
+var spillSerializers = sync.Pool{
+	New: func() any { return newSerializer() },
+}
+
+// openSpill opens the spill queue of the running config, keeping an open queue of the same directory and
+// name. Each logger of a directory and name spills to a slot file of its own, <name>.spill or
+// <name>.<slot>.spill, locked while it runs. Records left by ended processes are queued for draining.
+func openSpill(maxMB int64) error {
+	spillMu.Lock()
+	defer spillMu.Unlock()
+
+	base := filepath.Join(directory, name)
+	if spillFile != nil && spillBase == base && maxMB > 0 {
+		spillMaxMB = maxMB
+		return nil
+	}
+	closeSpillLocked()
+	spillMaxMB = maxMB
+	if maxMB <= 0 {
+		return nil
+	}
+
+	f, own, err := claimSlotFile("spill")
+	if err != nil {
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+	queued, err := io.ReadAll(io.NewSectionReader(f, 0, math.MaxInt64))
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+
+	// Complete frames of the claimed queue are kept, those of other ended processes are appended behind them
+	end := int64(spillFramesEnd(queued))
+	for _, o := range orphanedSlotFiles("spill", own) {
+		if frames, err := io.ReadAll(io.NewSectionReader(o, 0, math.MaxInt64)); err == nil {
+			frames = frames[:spillFramesEnd(frames)]
+			if _, err := f.WriteAt(frames, end); err == nil {
+				end += int64(len(frames))
+			}
+		}
+		removeSlotFile(o)
+	}
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+
+	spillFile, spillBase = f, base
+	spillReadOff = 0
+	spillWriteOff = end
+	spillQueued.Store(spillWriteOff > 0)
+	return nil
+}
+
+// spillFramesEnd returns the length of the complete frames at the start of data, a torn frame left by a
+// crash ends them
+func spillFramesEnd(data []byte) int {
+	end := 0
+	for end+spillFrameHeader <= len(data) {
+		size := spillFrameHeader + int(binary.BigEndian.Uint32(data[end:]))
+		if end+size > len(data) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+// closeSpill closes the spill queue, removing the file if it is empty
+func closeSpill() {
+	spillMu.Lock()
+	defer spillMu.Unlock()
+	closeSpillLocked()
+}
+
+// closeSpillLocked closes the spill queue, spillMu must be held.
+// Undrained records stay in the file and are drained by the next process.
+func closeSpillLocked() {
+	if spillFile == nil {
+		return
+	}
+	if spillReadOff >= spillWriteOff {
+		removeSlotFile(spillFile)
+	} else {
+		spillFile.Close()
+	}
+	spillFile, spillBase = nil, ""
+	spillReadOff, spillWriteOff = 0, 0
+	spillQueued.Store(false)
+}
+
+// spillRecord serializes a record that did not fit into the channel and appends it to the spill queue.
+// It reports false if spilling is disabled or the queue is full, the record is then dropped.
+func spillRecord(record *logRecord) bool {
+	if spillMaxMB <= 0 {
+		return false
+	}
+
+	s := spillSerializers.Get().(*serializer)
+	defer spillSerializers.Put(s)
+
+	frame := s.staged[0][:0]
+	if record.Batch != nil {
+		for i := range record.Batch {
+			frame = appendSpillFrame(frame, s, &record.Batch[i])
+		}
+	} else {
+		frame = appendSpillFrame(frame, s, record)
+	}
+	s.staged[0] = frame
+
+	spillMu.Lock()
+	defer spillMu.Unlock()
+
+	if spillFile == nil || spillWriteOff+int64(len(frame)) > spillMaxMB*1024*1024 {
+		return false
+	}
+	if _, err := spillFile.WriteAt(frame, spillWriteOff); err != nil {
+		return false
+	}
+	spillWriteOff += int64(len(frame))
+	spillQueued.Store(true)
+	return true
+}
+
+// appendSpillFrame appends the serialized record with its time and level to dst
+func appendSpillFrame(dst []byte, s *serializer, record *logRecord) []byte {
+	line := s.serialize(record)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(line)))
+	dst = binary.BigEndian.AppendUint64(dst, uint64(record.TimeStamp.UnixNano()))
+	dst = binary.BigEndian.AppendUint64(dst, uint64(record.Level))
+	return append(dst, line...)
+}
+
+// drainSpill writes up to max spilled records, or all with max 0, to the log file.
+// It must only be called from the processor goroutine.
+func drainSpill(s *serializer, max int) {
+	for {
+		frames, n := readSpill(max)
+		for len(frames) >= spillFrameHeader {
+			size := int(binary.BigEndian.Uint32(frames))
+			record := logRecord{
+				LogCtx:    context.Background(),
+				TimeStamp: time.Unix(0, int64(binary.BigEndian.Uint64(frames[4:]))),
+				Level:     int64(binary.BigEndian.Uint64(frames[12:])),
+				Raw:       frames[spillFrameHeader : spillFrameHeader+size],
+			}
+			writeGapMarker(s)
+			s.buf = append(s.buf[:0], record.Raw...)
+			writeSerialized(s, &record)
+			frames = frames[spillFrameHeader+size:]
+		}
+		if max > 0 || n == 0 {
+			return
+		}
+	}
+}
+
+// readSpill reads up to max complete frames from the spill queue, all with max 0, and returns them
+// with their count. The queue is truncated once it runs empty, a torn frame left by a crash ends it.
+func readSpill(max int) ([]byte, int) {
+	spillMu.Lock()
+	defer spillMu.Unlock()
+
+	if spillFile == nil || spillReadOff >= spillWriteOff {
+		spillQueued.Store(false)
+		return nil, 0
+	}
+
+	remaining := spillWriteOff - spillReadOff
+	buf := make([]byte, min(remaining, spillReadChunk))
+	n, _ := spillFile.ReadAt(buf, spillReadOff)
+	buf = buf[:n]
+
+	// A single frame larger than the chunk is read whole
+	if n >= spillFrameHeader {
+		size := spillFrameHeader + int64(binary.BigEndian.Uint32(buf))
+		if size > int64(n) && size <= remaining {
+			buf = make([]byte, size)
+			n, _ = spillFile.ReadAt(buf, spillReadOff)
+			buf = buf[:n]
+		}
+	}
+
+	var valid, count int
+	for valid+spillFrameHeader <= len(buf) && (max <= 0 || count < max) {
+		size := int(binary.BigEndian.Uint32(buf[valid:]))
+		if valid+spillFrameHeader+size > len(buf) {
+			break
+		}
+		valid += spillFrameHeader + size
+		count++
+	}
+
+	advance := int64(valid)
+	if valid == 0 {
+		// Torn frame, discard the rest of the queue
+		advance = remaining
+	}
+	spillReadOff += advance
+	if spillReadOff >= spillWriteOff {
+		spillFile.Truncate(0)
+		spillReadOff, spillWriteOff = 0, 0
+	}
+	spillQueued.Store(spillReadOff < spillWriteOff)
+	return buf[:valid], count
+}
@@ -0,0 +1,306 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Stage transforms serialized records between serialization and the log file. Stages of the
+// configured pipeline are applied in order, the output of the last stage is written as a frame of a
+// 4-byte big-endian length followed by the encoded record.
+//
+// Every file written through a pipeline starts with the magic of each stage in pipeline order.
+// Encode is only called from the processor goroutine and may reuse internal state between calls.
+type Stage interface {
+	// Magic returns the 4-byte identifier written at the start of each file.
+	Magic() string
+	// Encode appends the transformed record to dst.
+	Encode(dst, record []byte) ([]byte, error)
+	// Decode appends the original record of an encoded record to dst.
+	Decode(dst, data []byte) ([]byte, error)
+}
+
+// Built-in stage names usable in LoggerConfig.Pipeline
+const (
+	StageCompress = "compress"
+	StageChecksum = "checksum"
+	StageEncrypt  = "encrypt"
+)
+
+// Stage file magic identifiers
+const (
+	compressMagic = "LGZ1"
+	checksumMagic = "LGC1"
+)
+
+// maxFrameSize guards against reading garbage lengths from corrupted files
+const maxFrameSize = 64 << 20
+
+// Pipeline state, only replaced during initialization while the processor is stopped
+var (
+	pipelineNames []string
+	pipeline      []Stage // nil when records are written as plain lines
+)
+
+// Stages registered by name for use in LoggerConfig.Pipeline
+var (
+	stagesMu         sync.Mutex
+	registeredStages = make(map[string]Stage)
+)
+
+// registerStage makes a custom stage available to the pipeline under a name
+func registerStage(name string, stage Stage) error {
+	if stage == nil {
+		return fmt.Errorf("stage is nil: %s", name)
+	}
+	if len(stage.Magic()) != 4 {
+		return fmt.Errorf("stage magic must be 4 bytes: %s", name)
+	}
+	switch name {
+	case StageCompress, StageChecksum, StageEncrypt:
+		return fmt.Errorf("stage name is reserved: %s", name)
+	}
+
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+	if _, ok := registeredStages[name]; ok {
+		return fmt.Errorf("stage already registered: %s", name)
+	}
+	registeredStages[name] = stage
+	return nil
+}
+
+// newPipeline builds the stages of the configured pipeline. A configured encryption key without
+// an "encrypt" stage encrypts as the last stage.
+func newPipeline(names []string, hexKey string) ([]Stage, error) {
+	if hexKey != "" && !slices.Contains(names, StageEncrypt) {
+		names = append(slices.Clip(names), StageEncrypt)
+	}
+
+	var stages []Stage
+	for _, name := range names {
+		switch name {
+		case StageCompress:
+			stages = append(stages, CompressStage())
+		case StageChecksum:
+			stages = append(stages, ChecksumStage())
+		case StageEncrypt:
+			if hexKey == "" {
+				return nil, fmt.Errorf("encrypt stage requires an encryption key")
+			}
+			stage, err := EncryptStage(hexKey)
+			if err != nil {
+				return nil, err
+			}
+			stages = append(stages, stage)
+		default:
+			stagesMu.Lock()
+			stage, ok := registeredStages[name]
+			stagesMu.Unlock()
+			if !ok {
+				return nil, fmt.Errorf("unknown pipeline stage: %s", name)
+			}
+			stages = append(stages, stage)
+		}
+	}
+	return stages, nil
+}
+
+// pipelineHeader returns the magic identifiers written at the start of each file
+func pipelineHeader() []byte {
+	var header []byte
+	for _, stage := range pipeline {
+		header = append(header, stage.Magic()...)
+	}
+	return header
+}
+
+// encode runs a serialized record through the pipeline and returns the length-prefixed frame.
+// Stages alternate between two buffers, the last stage writes behind the reserved length prefix.
+func (s *serializer) encode(record []byte) ([]byte, error) {
+	data := record
+	for i, stage := range pipeline {
+		dst := s.staged[i%2][:0]
+		if i == len(pipeline)-1 {
+			dst = append(dst, 0, 0, 0, 0)
+		}
+		out, err := stage.Encode(dst, data)
+		if err != nil {
+			return nil, err
+		}
+		s.staged[i%2] = out
+		data = out
+	}
+	binary.BigEndian.PutUint32(data, uint32(len(data)-4))
+	return data, nil
+}
+
+// compressStage deflates each record independently, so records remain decodable after truncation
+type compressStage struct {
+	w   *flate.Writer
+	out appendWriter
+}
+
+// appendWriter collects compressed output into a reused slice
+type appendWriter struct {
+	buf []byte
+}
+
+func (a *appendWriter) Write(p []byte) (int, error) {
+	a.buf = append(a.buf, p...)
+	return len(p), nil
+}
+
+// CompressStage returns a stage compressing each record with DEFLATE.
+func CompressStage() Stage {
+	c := &compressStage{}
+	c.w, _ = flate.NewWriter(&c.out, flate.BestSpeed)
+	return c
+}
+
+func (c *compressStage) Magic() string { return compressMagic }
+
+func (c *compressStage) Encode(dst, record []byte) ([]byte, error) {
+	c.out.buf = dst
+	c.w.Reset(&c.out)
+	if _, err := c.w.Write(record); err != nil {
+		return nil, err
+	}
+	if err := c.w.Close(); err != nil {
+		return nil, err
+	}
+	return c.out.buf, nil
+}
+
+func (c *compressStage) Decode(dst, data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	buf := bytes.NewBuffer(dst)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("failed to decompress record: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// checksumStage prefixes each record with its CRC-32C
+type checksumStage struct{}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumStage returns a stage prefixing each record with a 4-byte big-endian CRC-32C checksum.
+func ChecksumStage() Stage {
+	return checksumStage{}
+}
+
+func (checksumStage) Magic() string { return checksumMagic }
+
+func (checksumStage) Encode(dst, record []byte) ([]byte, error) {
+	dst = binary.BigEndian.AppendUint32(dst, crc32.Checksum(record, crc32c))
+	return append(dst, record...), nil
+}
+
+func (checksumStage) Decode(dst, data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("record too short for checksum")
+	}
+	if crc32.Checksum(data[4:], crc32c) != binary.BigEndian.Uint32(data) {
+		return nil, fmt.Errorf("record checksum mismatch")
+	}
+	return append(dst, data[4:]...), nil
+}
+
+// StageReader decodes a file written through a pipeline record by record.
+type StageReader struct {
+	r      *bufio.Reader
+	stages []Stage
+	frame  []byte
+	buf    []byte // remaining decoded bytes of the current record
+}
+
+// NewStageReader validates the file header against the stages, given in pipeline order,
+// and returns a reader producing the original log lines.
+func NewStageReader(r io.Reader, stages ...Stage) (*StageReader, error) {
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("no stages given")
+	}
+
+	br := bufio.NewReader(r)
+	for _, stage := range stages {
+		magic := make([]byte, len(stage.Magic()))
+		if _, err := io.ReadFull(br, magic); err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		if string(magic) != stage.Magic() {
+			return nil, fmt.Errorf("file header %q does not match stage %q", magic, stage.Magic())
+		}
+	}
+	return &StageReader{r: br, stages: stages}, nil
+}
+
+// Next returns the next decoded record, or io.EOF at the end of the stream.
+func (d *StageReader) Next() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(d.r, size[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("truncated frame header")
+		}
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrameSize {
+		return nil, fmt.Errorf("invalid frame size: %d", n)
+	}
+	d.frame = slices.Grow(d.frame[:0], int(n))[:n]
+	if _, err := io.ReadFull(d.r, d.frame); err != nil {
+		return nil, fmt.Errorf("truncated frame: %w", err)
+	}
+
+	data := d.frame
+	for i := len(d.stages) - 1; i >= 0; i-- {
+		decoded, err := d.stages[i].Decode(nil, data)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+	return data, nil
+}
+
+// Read implements io.Reader over the concatenated decoded records.
+func (d *StageReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		record, err := d.Next()
+		if err != nil {
+			return 0, err
+		}
+		d.buf = record
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// decodeFile decodes a whole log file written through the given stages
+func decodeFile(path string, stages ...Stage) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := NewStageReader(f, stages...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
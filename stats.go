@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Shutdown states recorded in the stats file
+const (
+	shutdownRunning = "running" // logger is running, not a shutdown state
+	shutdownClean   = "clean"
+	shutdownError   = "error"
+	shutdownUnclean = "unclean" // process exited while the logger was running
+)
+
+// Process lifetime counters, updated by the processor
+var (
+	bytesWritten   atomic.Uint64
+	recordsWritten atomic.Uint64
+	rotationCount  atomic.Uint64
+	diskFullPauses atomic.Uint64
+	filesCreated   atomic.Uint64
+)
+
+// Span and counters of the current or last run, from Init to Shutdown, guarded by mu. runStopped is zero
+// while the logger runs, runEnd holds the counters at Shutdown.
+var (
+	runStarted time.Time
+	runStopped time.Time
+	runBase    RunStats
+	runEnd     RunStats
+)
+
+// RunStats summarizes the logging of a run, returned by Stats. Counters cover the run from Init to Shutdown,
+// reconfiguration continues the run.
+type RunStats struct {
+	RecordsWritten uint64        // records written to log files, including headers, footers and drop reports
+	BytesWritten   uint64        // bytes written to log files
+	Drops          uint64        // records dropped
+	FilesCreated   uint64        // log files created, the initial file and rotations
+	Duration       time.Duration // from Init to Shutdown, or to now while running
+	Directory      string        // log directory
+	File           string        // path of the active log file, or the last one after Shutdown
+}
+
+// runCounters returns the process lifetime counters summarized by RunStats
+func runCounters() RunStats {
+	return RunStats{
+		RecordsWritten: recordsWritten.Load(),
+		BytesWritten:   bytesWritten.Load(),
+		Drops:          droppedLogs.Load(),
+		FilesCreated:   filesCreated.Load(),
+	}
+}
+
+// startRun begins a run at Init, counting from the current counters. The caller must hold mu.
+func startRun() {
+	runStarted, runStopped = clockNow(), time.Time{}
+	runBase = runCounters()
+}
+
+// stopRun ends the run at Shutdown, keeping its counters for Stats. The caller must hold mu.
+func stopRun() {
+	runStopped = clockNow()
+	runEnd = runCounters()
+}
+
+// runStats returns the summary of the current run, or of the last one after Shutdown
+func runStats() RunStats {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	stats := RunStats{Directory: directory}
+	if !runStarted.IsZero() {
+		end, counters := runStopped, runEnd
+		if end.IsZero() {
+			end, counters = clockNow(), runCounters()
+		}
+		stats.RecordsWritten = counters.RecordsWritten - runBase.RecordsWritten
+		stats.BytesWritten = counters.BytesWritten - runBase.BytesWritten
+		stats.Drops = counters.Drops - runBase.Drops
+		stats.FilesCreated = counters.FilesCreated - runBase.FilesCreated
+		stats.Duration = end.Sub(runStarted)
+	}
+	if f, ok := currentFile.Load().(*os.File); ok && f != nil {
+		stats.File = f.Name()
+	}
+	return stats
+}
+
+// Stats file state
+var (
+	statsMu      sync.Mutex
+	statsEnabled bool
+	statsPath    string
+	statsBase    lifetimeStats // stats loaded from file
+	statsOffset  lifetimeStats // counter values at the time the file was loaded
+)
+
+// lifetimeStats is the content of the stats file, accumulated across process runs.
+type lifetimeStats struct {
+	Created          time.Time         `json:"created"`
+	Updated          time.Time         `json:"updated"`
+	Starts           uint64            `json:"starts"`
+	BytesWritten     uint64            `json:"bytes_written"`
+	RecordsWritten   uint64            `json:"records_written"`
+	Rotations        uint64            `json:"rotations"`
+	Drops            uint64            `json:"drops"`
+	DropCauses       map[string]uint64 `json:"drop_causes,omitempty"`
+	DiskFullPauses   uint64            `json:"disk_full_pauses"`
+	UncleanShutdowns uint64            `json:"unclean_shutdowns"`
+	Running          bool              `json:"running"`
+	LastShutdown     string            `json:"last_shutdown,omitempty"`
+	LastShutdownTime time.Time         `json:"last_shutdown_time,omitempty"`
+}
+
+// statsFileName returns the stats file name, using an extension that is never treated as a log file
+func statsFileName() string {
+	return name + ".stats"
+}
+
+// currentCounters returns the process lifetime counters as stats
+func currentCounters() lifetimeStats {
+	return lifetimeStats{
+		BytesWritten:   bytesWritten.Load(),
+		RecordsWritten: recordsWritten.Load(),
+		Rotations:      rotationCount.Load(),
+		Drops:          droppedLogs.Load(),
+		DropCauses:     dropCauseCounts(),
+		DiskFullPauses: diskFullPauses.Load(),
+	}
+}
+
+// dropCauseCounts returns the dropped records of each cause by name, omitting causes without drops
+func dropCauseCounts() map[string]uint64 {
+	counts := make(map[string]uint64)
+	for i := range dropsByCause {
+		if n := dropsByCause[i].Load(); n > 0 {
+			counts[dropCauseNames[i]] = n
+		}
+	}
+	return counts
+}
+
+// initStats loads the stats file of the configured directory and marks the logger as running.
+// A previous run still marked as running is counted as an unclean shutdown.
+func initStats(enabled bool) error {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	statsEnabled = enabled
+	if !enabled {
+		return nil
+	}
+
+	path := filepath.Join(directory, statsFileName())
+	if path != statsPath {
+		// New stats file, load its history and count from the current counters
+		statsPath = path
+		statsBase = lifetimeStats{Created: time.Now()}
+		if data, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(data, &statsBase)
+		}
+		if statsBase.Running {
+			statsBase.UncleanShutdowns++
+			statsBase.LastShutdown = shutdownUnclean
+			statsBase.LastShutdownTime = statsBase.Updated
+		}
+		statsBase.Starts++
+		statsOffset = currentCounters()
+	}
+
+	return writeStatsLocked(shutdownRunning)
+}
+
+// saveStats writes the accumulated stats with the given shutdown state, if enabled.
+func saveStats(state string) error {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	if !statsEnabled {
+		return nil
+	}
+	return writeStatsLocked(state)
+}
+
+// writeStatsLocked merges the counters into the loaded stats and atomically replaces the file.
+// The caller must hold statsMu.
+func writeStatsLocked(state string) error {
+	now := time.Now()
+	current := currentCounters()
+
+	stats := statsBase
+	stats.Updated = now
+	stats.BytesWritten += current.BytesWritten - statsOffset.BytesWritten
+	stats.RecordsWritten += current.RecordsWritten - statsOffset.RecordsWritten
+	stats.Rotations += current.Rotations - statsOffset.Rotations
+	stats.Drops += current.Drops - statsOffset.Drops
+	stats.DiskFullPauses += current.DiskFullPauses - statsOffset.DiskFullPauses
+	stats.DropCauses = maps.Clone(statsBase.DropCauses)
+	for cause, n := range current.DropCauses {
+		if n -= statsOffset.DropCauses[cause]; n > 0 {
+			if stats.DropCauses == nil {
+				stats.DropCauses = make(map[string]uint64)
+			}
+			stats.DropCauses[cause] += n
+		}
+	}
+	stats.Running = state == shutdownRunning
+	if !stats.Running {
+		// Keep shutdown status if the logger is initialized again
+		statsBase.LastShutdown, statsBase.LastShutdownTime = state, now
+		stats.LastShutdown, stats.LastShutdownTime = state, now
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := statsPath + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, statsPath)
+}
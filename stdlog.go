@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"context"
+	"io"
+	stdlog "log"
+)
+
+// levelWriter logs every write as one record at a fixed level
+type levelWriter struct {
+	level int64
+}
+
+// Write logs p as the message of a record, without its trailing newline. It never fails,
+// records dropped by the level or a full queue are counted like other records.
+func (w levelWriter) Write(p []byte) (int, error) {
+	msg := p
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	if n := len(msg); n > 0 && msg[n-1] == '\r' {
+		msg = msg[:n-1]
+	}
+	logFields(context.Background(), flags, w.level, 0, string(msg))
+	return len(p), nil
+}
+
+// newWriter returns an io.Writer logging at the level
+func newWriter(level int64) io.Writer {
+	return levelWriter{level: level}
+}
+
+// newStdLogger returns a standard library logger writing through the level writer. Prefix and flags are
+// left empty, timestamps and levels are added by the logger.
+func newStdLogger(level int64) *stdlog.Logger {
+	return stdlog.New(newWriter(level), "", 0)
+}
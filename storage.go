@@ -2,13 +2,14 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync/atomic"
-	"syscall"
 	"time"
 )
 
@@ -18,29 +19,238 @@ var (
 	currentSize atomic.Int64
 	currentFile atomic.Value // stores *os.File
 
-	maxSizeMB      int64
-	maxTotalSizeMB int64
-	minDiskFreeMB  int64
+	maxSizeMB         int64
+	maxTotalSizeMB    int64
+	minDiskFreeMB     int64
+	maxSizeBytes      int64
+	maxTotalSizeBytes int64
+	minDiskFreeBytes  int64
+
+	// Effective limits in bytes, from the byte fields if set and the MB fields otherwise
+	maxSize      int64
+	maxTotalSize int64
+	minDiskFree  int64
 
-	diskFullLogged   atomic.Bool
 	earliestFileTime atomic.Value // stores time.Time
 	retentionPeriod  time.Duration
 	retentionCheck   time.Duration
+	retentionExclude []string
+	cleanupScope     string
 )
 
-// getDiskStats retrieves filesystem statistics for the log directory.
-// It returns available and total space in bytes.
-func getDiskFreeSpace(path string) (int64, error) {
-	var stat syscall.Statfs_t
-	if err := syscall.Statfs(path, &stat); err != nil {
-		return 0, err
+// sizeLimit returns a size limit in bytes, given in bytes if positive and in MB otherwise
+func sizeLimit(mb, bytes int64) int64 {
+	if bytes > 0 {
+		return bytes
+	}
+	return mb * 1024 * 1024
+}
+
+// errFileInUse reports a log file that cannot be deleted while another process holds it open
+var errFileInUse = errors.New("log file in use")
+
+// Cleanup scopes, selecting the files counted against MaxTotalSizeMB and deleted by cleanup and retention
+const (
+	CleanupScopeName      = "name"      // files named after the configured Name
+	CleanupScopeDirectory = "directory" // every file with the configured extension in the directory
+)
+
+// reservedExtensions are used for the logger's own files in the log directory: stats, spill queue, journal,
+// cleanup lock and files being rewritten by retention tiers
+var reservedExtensions = []string{"stats", "spill", "journal", "lock", "tier"}
+
+// validateExtension checks the log file extension: letters, digits, '_' and '-' without a leading dot,
+// and none of the extensions of the logger's own files, which cleanup would delete. Files are matched
+// by their last extension, so inner dots are not supported.
+func validateExtension(ext string) error {
+	if strings.HasPrefix(ext, ".") {
+		return fmt.Errorf("extension should not start with dot: %s", ext)
+	}
+	for _, c := range ext {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return fmt.Errorf("invalid extension %s: only letters, digits, '_' and '-' are allowed", ext)
+		}
+	}
+	for _, reserved := range reservedExtensions {
+		if strings.EqualFold(ext, reserved) {
+			return fmt.Errorf("invalid extension %s: reserved for files of the logger", ext)
+		}
+	}
+	return nil
+}
+
+// isExcluded reports whether the file name matches any of the configured
+// retention exclusion patterns. Excluded files are never deleted by the logger.
+func isExcluded(fname string) bool {
+	for _, pattern := range retentionExclude {
+		if matched, _ := filepath.Match(pattern, fname); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isManagedFile reports whether disk accounting, cleanup and retention apply to the file: it has the
+// configured extension, is not excluded and, unless the scope is the whole directory, was named by
+// generateLogFileName for the configured Name. Other loggers sharing the directory keep their files.
+func isManagedFile(fname string) bool {
+	if filepath.Ext(fname) != "."+extension || isExcluded(fname) {
+		return false
+	}
+	if cleanupScope == CleanupScopeDirectory {
+		return true
+	}
+	return isOwnLogFile(fname)
+}
+
+// isOwnLogFile reports whether the file name is <name>_<yymmdd>_<hhmmss>_..., or a journal recovery file
+// <name>_recovered_<yymmdd>_<hhmmss>_..., so the files of a logger named "app_worker" don't match the name "app"
+func isOwnLogFile(fname string) bool {
+	rest, ok := strings.CutPrefix(fname, name+"_")
+	rest = strings.TrimPrefix(rest, journalRecoveredTag+"_")
+	if !ok || len(rest) < 14 {
+		return false
+	}
+	for i, c := range rest[:14] {
+		switch {
+		case i == 6 || i == 13:
+			if c != '_' {
+				return false
+			}
+		case c < '0' || c > '9':
+			return false
+		}
 	}
-	// Available blocks * block size
-	return int64(stat.Bavail) * int64(stat.Bsize), nil
+	return true
+}
+
+// cleanupLockName is the lock file serializing cleanup between processes sharing the directory
+const cleanupLockName = ".logger.lock"
+
+// lockCleanup takes the cleanup lock of the directory without waiting. It reports false if cleanup
+// is running in another process or goroutine. Without lock support, cleanup proceeds unlocked.
+func lockCleanup() (unlock func(), ok bool) {
+	f, err := os.OpenFile(filepath.Join(directory, cleanupLockName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return func() {}, true
+	}
+	locked, err := tryLockExclusive(f)
+	if err != nil {
+		f.Close()
+		return func() {}, true
+	}
+	if !locked {
+		f.Close()
+		return nil, false
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, true
+}
+
+// fileInUse reports whether a logger, in this or another process, holds the file open for writing
+func fileInUse(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	locked, err := tryLockExclusive(f)
+	if locked {
+		unlockFile(f)
+	}
+	return !locked && err == nil
+}
+
+// maxFileSlots bounds the loggers of the same directory and name keeping a journal or spill queue at once
+const maxFileSlots = 64
+
+// slotFileName returns the name of the journal or spill file of a slot: <name>.<ext> for the first slot,
+// <name>.<slot>.<ext> for the others
+func slotFileName(ext string, slot int) string {
+	if slot == 1 {
+		return name + "." + ext
+	}
+	return name + "." + strconv.Itoa(slot) + "." + ext
+}
+
+// claimSlotFile opens the first slot file of ext not held by a logger, in this or another process, creating
+// it if needed, and returns it locked exclusively with its slot. A file left by an ended process is claimed
+// with its content. Without lock support, the first slot is used unlocked.
+func claimSlotFile(ext string) (*os.File, int, error) {
+	for slot := 1; slot <= maxFileSlots; slot++ {
+		f, err := os.OpenFile(filepath.Join(directory, slotFileName(ext, slot)), os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, 0, err
+		}
+		locked, err := tryLockExclusive(f)
+		if locked || (err != nil && slot == 1) {
+			return f, slot, nil
+		}
+		f.Close()
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return nil, 0, fmt.Errorf("all %d %s files of %s are in use", maxFileSlots, ext, name)
+}
+
+// orphanedSlotFiles opens the existing slot files of ext left by ended processes, leaving out the slot
+// of this logger, and returns them locked exclusively. Files held by a running logger are left alone.
+func orphanedSlotFiles(ext string, own int) []*os.File {
+	var files []*os.File
+	for slot := 1; slot <= maxFileSlots; slot++ {
+		if slot == own {
+			continue
+		}
+		f, err := os.OpenFile(filepath.Join(directory, slotFileName(ext, slot)), os.O_RDWR, 0)
+		if err != nil {
+			continue
+		}
+		if locked, _ := tryLockExclusive(f); !locked {
+			f.Close()
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// removeSlotFile empties, closes and removes a locked slot file. The file is emptied first, so a logger
+// locking it between closing and removal finds nothing to take over.
+func removeSlotFile(f *os.File) {
+	f.Truncate(0)
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// resolveDirectory expands a leading "~" to the home directory and environment variables in dir, and makes
+// it absolute against the working directory at Init, so a later chdir does not move the log files.
+// An empty dir is the working directory.
+func resolveDirectory(dir string) (string, error) {
+	dir = os.ExpandEnv(dir)
+	if dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand log directory %s: %w", dir, err)
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve log directory %s: %w", dir, err)
+	}
+	return abs, nil
 }
 
 // getLogDirSize calculates total size of all log files in the directory.
-// It only counts files with .log extension.
+// It only counts files managed by the logger, skipping excluded files
+// since they cannot be deleted to free up space.
 func getLogDirSize(dir string) (int64, error) {
 	var size int64
 	entries, err := os.ReadDir(dir)
@@ -53,7 +263,7 @@ func getLogDirSize(dir string) (int64, error) {
 		if err != nil {
 			continue
 		}
-		if !info.IsDir() && filepath.Ext(entry.Name()) == "."+extension {
+		if !info.IsDir() && isManagedFile(entry.Name()) {
 			size += info.Size()
 		}
 	}
@@ -63,6 +273,13 @@ func getLogDirSize(dir string) (int64, error) {
 // cleanOldLogs removes oldest log files to free up required disk space.
 // It sorts files by modification time and removes them until enough space is freed.
 func cleanOldLogs(ctx context.Context, required int64) error {
+	// Another process is cleaning up, the next check sees the result
+	unlock, ok := lockCleanup()
+	if !ok {
+		return nil
+	}
+	defer unlock()
+
 	entries, err := os.ReadDir(directory)
 	if err != nil {
 		return err
@@ -77,7 +294,7 @@ func cleanOldLogs(ctx context.Context, required int64) error {
 
 	var logs []logFile
 	for _, entry := range entries {
-		if filepath.Ext(entry.Name()) != "."+extension {
+		if !isManagedFile(entry.Name()) {
 			continue
 		}
 		info, err := entry.Info()
@@ -107,10 +324,17 @@ func cleanOldLogs(ctx context.Context, required int64) error {
 		if deleted >= required {
 			break
 		}
-		if err := os.Remove(filepath.Join(directory, log.name)); err != nil {
+		// Active files of other processes and files held open by other programs are skipped
+		path := filepath.Join(directory, log.name)
+		if fileInUse(path) {
+			continue
+		}
+		// Archived files are deleted once uploaded, a later check sees the space freed
+		if err := deleteLogFile(path); err != nil {
 			continue
 		}
 		deleted += log.size
+		emitDiag(DiagEvent{Kind: DiagCleanup, File: log.name, ModTime: log.modTime, Bytes: log.size})
 	}
 	if deleted < required {
 		return fmt.Errorf("could not free enough space: freed %d bytes, needed %d bytes", deleted, required)
@@ -118,11 +342,58 @@ func cleanOldLogs(ctx context.Context, required int64) error {
 	return nil
 }
 
-// checkDiskSpace ensures sufficient disk space is available for logging.
-// It manages disk space by cleaning up old logs and pausing logging if necessary.
-func checkDiskSpace(ctx context.Context) error {
+// Disk check state. DiskCheckInterval limits how often the maintenance goroutine scans the log directory,
+// logging calls only read the result of the last scan.
+var (
+	diskCheckInterval time.Duration
+	lastDiskCheck     atomic.Int64 // unix nano time of the last scan
+	diskCheckFailed   atomic.Bool  // result of the last scan
+)
+
+// diskCheckRequest wakes the maintenance goroutine for a disk space scan
+var diskCheckRequest = make(chan struct{}, 1)
+
+// invalidateDiskCheck makes the maintenance goroutine scan the directory now, e.g. after a rotation changed the files
+func invalidateDiskCheck() {
+	lastDiskCheck.Store(0)
+	select {
+	case diskCheckRequest <- struct{}{}:
+	default:
+	}
+}
+
+// checkDiskSpace reports whether sufficient disk space was available at the last scan.
+// It never touches the file system, the maintenance goroutine scans with refreshDiskSpace.
+func checkDiskSpace() error {
+	if diskCheckFailed.Load() {
+		return ErrDiskFull
+	}
+	return nil
+}
+
+// refreshDiskSpace scans the directory against the configured limits once diskCheckInterval passed since
+// the last scan, deleting old logs if needed, and stores the result read by checkDiskSpace.
+// It must only be called from the maintenance goroutine.
+func refreshDiskSpace() {
 	// Skip check if disk management not configured
-	if maxTotalSizeMB == 0 && minDiskFreeMB == 0 {
+	if maxTotalSize == 0 && minDiskFree == 0 {
+		setDiskPause(nil)
+		return
+	}
+
+	now := clockNow().UnixNano()
+	if now-lastDiskCheck.Load() < int64(diskCheckInterval) {
+		return
+	}
+	lastDiskCheck.Store(now)
+	setDiskPause(scanDiskSpace(context.Background()))
+}
+
+// scanDiskSpace checks free space and directory size against the configured limits.
+// It manages disk space by cleaning up old logs and pausing logging if necessary.
+func scanDiskSpace(ctx context.Context) error {
+	// Directory may not exist yet with lazy open, accounting starts once the file is created
+	if lazyOpen && currentFile.Load().(*os.File) == nil {
 		return nil
 	}
 
@@ -137,8 +408,8 @@ func checkDiskSpace(ctx context.Context) error {
 		return err
 	}
 
-	minFree := minDiskFreeMB * 1024 * 1024
-	maxTotal := maxTotalSizeMB * 1024 * 1024
+	minFree := minDiskFree
+	maxTotal := maxTotalSize
 
 	if free < minFree || (maxTotal > 0 && dirSize > maxTotal) {
 		required := int64(0)
@@ -153,14 +424,9 @@ func checkDiskSpace(ctx context.Context) error {
 		}
 
 		if err := cleanOldLogs(ctx, required); err != nil {
-			if !diskFullLogged.Load() {
-				diskFullLogged.Store(true)
-				return fmt.Errorf("disk full")
-			}
+			return fmt.Errorf("%w: %w", ErrDiskFull, err)
 		}
 	}
-
-	diskFullLogged.Store(false)
 	return nil
 }
 
@@ -179,9 +445,6 @@ func updateEarliestFileTime() {
 		currentLogFile = filepath.Base(f.Name())
 	}
 
-	// Format: <name>_<timestamp>.log or <name>_<timestamp>.<subsec>.log
-	prefix := name + "_"
-
 	for _, entry := range entries {
 		// Skip nil entries
 		if entry == nil {
@@ -194,8 +457,8 @@ func updateEarliestFileTime() {
 			continue
 		}
 
-		// Skip if not a log file or doesn't match the instance prefix
-		if !strings.HasPrefix(fname, prefix) || filepath.Ext(fname) != "."+extension {
+		// Skip files of other loggers and files protected from retention
+		if !isManagedFile(fname) {
 			continue
 		}
 
@@ -209,6 +472,11 @@ func updateEarliestFileTime() {
 			continue
 		}
 
+		// Skip active files of other processes sharing the directory
+		if fileInUse(filepath.Join(directory, fname)) {
+			continue
+		}
+
 		if earliest.IsZero() || info.ModTime().Before(earliest) {
 			earliest = info.ModTime()
 		}
@@ -222,6 +490,12 @@ func updateEarliestFileTime() {
 // time in the directory. It skips the currently active log file and respects
 // context cancellation.
 func cleanExpiredLogs(ctx context.Context, oldest time.Time) error {
+	unlock, ok := lockCleanup()
+	if !ok {
+		return nil
+	}
+	defer unlock()
+
 	entries, err := os.ReadDir(directory)
 	if err != nil {
 		return err
@@ -233,7 +507,7 @@ func cleanExpiredLogs(ctx context.Context, oldest time.Time) error {
 			return ctx.Err()
 
 		default:
-			if filepath.Ext(entry.Name()) != "."+extension {
+			if !isManagedFile(entry.Name()) {
 				continue
 			}
 			info, err := entry.Info()
@@ -245,12 +519,21 @@ func cleanExpiredLogs(ctx context.Context, oldest time.Time) error {
 					entry.Name() == filepath.Base(f.Name()) {
 					continue
 				}
-				if err := os.Remove(filepath.Join(directory, entry.Name())); err != nil {
+				path := filepath.Join(directory, entry.Name())
+				if fileInUse(path) {
+					continue
+				}
+				if err := deleteLogFile(path); err != nil {
+					if errors.Is(err, errFileInUse) || errors.Is(err, errArchivePending) {
+						// Retried at the next check
+						continue
+					}
 					return err
 				}
+				emitDiag(DiagEvent{Kind: DiagRetention, File: entry.Name(), ModTime: info.ModTime(), Bytes: info.Size()})
 				break
 			}
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
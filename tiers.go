@@ -0,0 +1,251 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retentionTier keeps only records of at least level in files older than age
+type retentionTier struct {
+	age   time.Duration
+	level int64
+}
+
+// maxLevelTokens bounds the leading tokens of a txt record searched for the level, past the time stamp
+// and before the message
+const maxLevelTokens = 4
+
+// Retention tier state, tieredFiles maps filtered files to the level they were filtered to and is only
+// used by the maintenance goroutine
+var (
+	retentionTiers []retentionTier
+	tierSpecs      []string
+	tieredFiles    = make(map[string]int64)
+)
+
+// parseRetentionTiers parses tiers given as "<hours>:<level>", e.g. "24:warn". Ages and levels must
+// both increase from one tier to the next, and ages must be below the retention period if set.
+func parseRetentionTiers(specs []string, period time.Duration) ([]retentionTier, error) {
+	tiers := make([]retentionTier, 0, len(specs))
+	for _, spec := range specs {
+		hours, levelName, ok := strings.Cut(spec, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention tier %q: expected <hours>:<level>", spec)
+		}
+		h, err := strconv.ParseFloat(strings.TrimSpace(hours), 64)
+		if err != nil || h <= 0 {
+			return nil, fmt.Errorf("invalid retention tier %q: hours must be positive", spec)
+		}
+		level, err := parseLevel(levelName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention tier %q: %w", spec, err)
+		}
+		tier := retentionTier{age: time.Duration(h * float64(time.Hour)), level: level}
+		if n := len(tiers); n > 0 && (tier.age <= tiers[n-1].age || tier.level <= tiers[n-1].level) {
+			return nil, fmt.Errorf("invalid retention tier %q: ages and levels must increase", spec)
+		}
+		if period > 0 && tier.age >= period {
+			return nil, fmt.Errorf("invalid retention tier %q: must be younger than the retention period", spec)
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// tierLevel returns the minimum level kept in a file of the age, false if no tier applies yet
+func tierLevel(age time.Duration) (int64, bool) {
+	for i := len(retentionTiers) - 1; i >= 0; i-- {
+		if age >= retentionTiers[i].age {
+			return retentionTiers[i].level, true
+		}
+	}
+	return 0, false
+}
+
+// applyRetentionTiers filters the managed files past a tier to the records of the tier level, skipping the
+// active file and files open in other processes. Filtered files keep their modification time, so the
+// retention period still counts from their last write. Tiers are not applied with an audit chain, a
+// pipeline or the msgpack format, as filtering would break the chain or the encoded records.
+// It must only be called from the maintenance goroutine.
+func applyRetentionTiers() {
+	if len(retentionTiers) == 0 || auditChain || len(pipeline) > 0 || format == FormatMsgpack {
+		return
+	}
+	unlock, ok := lockCleanup()
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return
+	}
+	active := ""
+	if f := currentFile.Load().(*os.File); f != nil {
+		active = filepath.Base(f.Name())
+	}
+	now := clockNow()
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		fname := entry.Name()
+		if fname == active || !isManagedFile(fname) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(directory, fname)
+		seen[path] = true
+		level, due := tierLevel(now.Sub(info.ModTime()))
+		if applied, ok := tieredFiles[path]; !due || (ok && applied >= level) || fileInUse(path) {
+			continue
+		}
+		if err := filterLogFile(path, info, level); err == nil {
+			tieredFiles[path] = level
+		}
+	}
+	// Forget files deleted meanwhile
+	for path := range tieredFiles {
+		if !seen[path] {
+			delete(tieredFiles, path)
+		}
+	}
+}
+
+// filterLogFile rewrites the file with only the records of at least level, and lines without a level
+// such as footers. Records spanning several lines, with FoldLines or JSONIndent, are kept or removed as a
+// whole. The file is replaced only if records were removed.
+func filterLogFile(path string, info os.FileInfo, level int64) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpPath := path + ".tier"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath) // no-op once renamed
+
+	removed := false
+	r := bufio.NewReader(src)
+	w := bufio.NewWriter(tmp)
+	var record []byte
+	// flush writes the record read so far unless it is below level
+	flush := func() error {
+		defer func() { record = record[:0] }()
+		if len(record) == 0 {
+			return nil
+		}
+		if l, ok := lineLevel(record); ok && l < level {
+			removed = true
+			return nil
+		}
+		_, err := w.Write(record)
+		return err
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if !isContinuationLine(line) {
+				if werr := flush(); werr != nil {
+					tmp.Close()
+					return werr
+				}
+			}
+			record = append(record, line...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if !removed {
+		return nil
+	}
+	if err := os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// isContinuationLine reports whether a line continues the record of the previous line: an indented line,
+// a continuation line of FoldLines or a member of a JSONIndent record, or the closing brace of the record
+func isContinuationLine(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("  ")) || line[0] == '}'
+}
+
+// lineLevel returns the level of a serialized record: the "level" or "log.level" member of a JSON record,
+// or the first level name among the leading tokens of a txt record
+func lineLevel(line []byte) (int64, bool) {
+	if len(line) > 0 && line[0] == '{' {
+		var rec struct {
+			Level    string `json:"level"`
+			ECSLevel string `json:"log.level"`
+		}
+		if json.Unmarshal(line, &rec) != nil {
+			return 0, false
+		}
+		name := rec.Level
+		if name == "" {
+			name = rec.ECSLevel
+		}
+		if name == "" {
+			return 0, false
+		}
+		level, err := parseLevel(name)
+		return level, err == nil
+	}
+
+	tokens := strings.SplitN(strings.TrimRight(string(line), "\r\n"), " ", maxLevelTokens+1)
+	for _, token := range tokens[:min(len(tokens), maxLevelTokens)] {
+		if isLevelName(token) {
+			level, err := parseLevel(token)
+			return level, err == nil
+		}
+	}
+	return 0, false
+}
+
+// isLevelName reports whether a token is a level name as written by levelToString, e.g. "WARN" or "INFO+2"
+func isLevelName(token string) bool {
+	name := token
+	if i := strings.IndexAny(token, "+-"); i > 0 {
+		if _, err := strconv.ParseUint(token[i+1:], 10, 63); err != nil {
+			return false
+		}
+		name = token[:i]
+	}
+	for _, l := range levelNames {
+		if l.name == name {
+			return true
+		}
+	}
+	return false
+}
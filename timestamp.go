@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp formats, other values are Go time layouts such as "2006-01-02 15:04:05.000"
+const (
+	TimestampRFC3339Nano = "rfc3339nano" // 2006-01-02T15:04:05.999999999Z07:00, the default
+	TimestampRFC3339     = "rfc3339"     // 2006-01-02T15:04:05Z07:00
+	TimestampUnix        = "unix"        // seconds since the Unix epoch
+	TimestampUnixMilli   = "unix_ms"     // milliseconds since the Unix epoch
+	TimestampUnixMicro   = "unix_us"     // microseconds since the Unix epoch
+	TimestampUnixNano    = "unix_ns"     // nanoseconds since the Unix epoch
+)
+
+// Timestamp state, timestampUnit is non-zero for epoch formats, which are written as numbers
+var (
+	timestampFormat   string
+	timestampLayout   = time.RFC3339Nano
+	timestampUnit     time.Duration
+	timeZone          string
+	timestampLocation *time.Location // nil keeps the location of the record time
+)
+
+// durationNanos writes time.Duration values as integer nanoseconds instead of their String form
+var durationNanos bool
+
+// configureTimestamp sets the format and time zone of record timestamps. An empty format is
+// TimestampRFC3339Nano, an empty zone keeps the local time of the process.
+func configureTimestamp(format, zone string) error {
+	format, layout, unit, loc, err := parseTimestamp(format, zone)
+	if err != nil {
+		return err
+	}
+	timestampFormat, timestampLayout, timestampUnit = format, layout, unit
+	timeZone, timestampLocation = zone, loc
+	return nil
+}
+
+// parseTimestamp resolves a timestamp format and time zone to the layout or unit and the location to format with
+func parseTimestamp(format, zone string) (string, string, time.Duration, *time.Location, error) {
+	layout, unit := time.RFC3339Nano, time.Duration(0)
+	switch strings.ToLower(format) {
+	case "", TimestampRFC3339Nano:
+		format = TimestampRFC3339Nano
+	case TimestampRFC3339:
+		layout = time.RFC3339
+	case TimestampUnix:
+		unit = time.Second
+	case TimestampUnixMilli:
+		unit = time.Millisecond
+	case TimestampUnixMicro:
+		unit = time.Microsecond
+	case TimestampUnixNano:
+		unit = time.Nanosecond
+	default:
+		// A layout without any element formats as itself, most likely a misspelled format name
+		if time.Unix(0, 0).UTC().Format(format) == format {
+			return "", "", 0, nil, fmt.Errorf("invalid timestamp format: %s", format)
+		}
+		layout = format
+	}
+
+	var loc *time.Location
+	if zone != "" {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return "", "", 0, nil, fmt.Errorf("invalid time zone: %s: %w", zone, err)
+		}
+	}
+	return format, layout, unit, loc, nil
+}
+
+// writeTimestamp writes the record time in the configured format, layouts quoted if quote is set
+// and epoch formats as numbers.
+func (s *serializer) writeTimestamp(t time.Time, quote bool) {
+	if timestampUnit != 0 {
+		s.buf = strconv.AppendInt(s.buf, t.UnixNano()/int64(timestampUnit), 10)
+		return
+	}
+	if timestampLocation != nil {
+		t = t.In(timestampLocation)
+	}
+	if quote {
+		s.buf = append(s.buf, '"')
+	}
+	s.buf = t.AppendFormat(s.buf, timestampLayout)
+	if quote {
+		s.buf = append(s.buf, '"')
+	}
+}
+
+// writeTimeValue writes a time.Time value of a record like the record time stamp, quoted if quote is set and the
+// layout writes a string. In text form, a layout with spaces is quoted to keep the value a single token.
+func (s *serializer) writeTimeValue(t time.Time, quote bool) {
+	s.writeTimestamp(t, quote || timestampUnit == 0 && strings.ContainsRune(timestampLayout, ' '))
+}
+
+// writeDurationValue writes a time.Duration value of a record in its String form, e.g. 150ms, quoted if quote
+// is set, or as integer nanoseconds with DurationNanos
+func (s *serializer) writeDurationValue(d time.Duration, quote bool) {
+	if durationNanos {
+		s.buf = strconv.AppendInt(s.buf, int64(d), 10)
+		return
+	}
+	if quote {
+		s.buf = append(s.buf, '"')
+	}
+	s.buf = append(s.buf, d.String()...)
+	if quote {
+		s.buf = append(s.buf, '"')
+	}
+}
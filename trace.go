@@ -0,0 +1,37 @@
+package logger
+
+import "strconv"
+
+// Trace state. structuredTrace writes the trace of json and ecs records as an array of frames instead of a
+// string, traceWithLines adds the file and line to every function of the trace string.
+var (
+	structuredTrace bool
+	traceWithLines  bool
+)
+
+// traceFrame is a function call of a trace captured with StructuredTrace
+type traceFrame struct {
+	function string // fully qualified function name
+	file     string
+	line     int
+}
+
+// writeTraceFrames writes trace frames as a json array in outer to inner order:
+//
+//	[{"function":"main.handle","file":"/src/app/main.go","line":42},...]
+func (s *serializer) writeTraceFrames(frames []traceFrame) {
+	s.buf = append(s.buf, '[')
+	for i := range frames {
+		if i > 0 {
+			s.buf = append(s.buf, ',')
+		}
+		s.buf = append(s.buf, `{"function":`...)
+		s.writeJSONString(frames[i].function)
+		s.buf = append(s.buf, `,"file":`...)
+		s.writeJSONString(frames[i].file)
+		s.buf = append(s.buf, `,"line":`...)
+		s.buf = strconv.AppendInt(s.buf, int64(frames[i].line), 10)
+		s.buf = append(s.buf, '}')
+	}
+	s.buf = append(s.buf, ']')
+}
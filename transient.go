@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// transientSuffix is appended to Name for the files of transient records
+const transientSuffix = "_transient"
+
+// Transient stream state. The file is owned by the processor, or by initLogger and shutdownLogger
+// with the processor stopped.
+var (
+	transientRetention time.Duration
+	transientFile      *os.File
+	transientOpened    time.Time
+	transientSize      int64
+
+	// transientActive is the base name of the open transient file, read by the maintenance goroutine
+	transientActive atomic.Value // stores string
+)
+
+// transientKey is the context key marking records as transient
+type transientKey struct{}
+
+// isTransient reports whether the record was logged with a context marked by ContextWithTransient
+// and transient records are kept in their own files
+func isTransient(record *logRecord) bool {
+	if transientRetention <= 0 || record.LogCtx == nil {
+		return false
+	}
+	marked, _ := record.LogCtx.Value(transientKey{}).(bool)
+	return marked
+}
+
+// transientCheckInterval returns how often transient files are purged
+func transientCheckInterval() time.Duration {
+	return max(transientRetention/4, time.Second)
+}
+
+// writeTransient writes a transient record serialized in s.buf to the transient file, starting a new
+// file by size or once the file is half the retention old, so files expire while records are logged.
+// Transient files are not part of the audit chain.
+// It must only be called from the processor goroutine.
+func writeTransient(s *serializer, record *logRecord) {
+	if recordChecksum {
+		s.appendChecksum()
+	}
+	line := s.buf
+	data := line
+	if format == FormatMsgpack {
+		data = s.pack(data)
+	}
+	if len(pipeline) > 0 {
+		var err error
+		if data, err = s.encode(data); err != nil {
+			recordDrop(1, causeWriteError)
+			return
+		}
+	}
+
+	if transientFile != nil && (clockNow().Sub(transientOpened) > transientRetention/2 ||
+		(maxSize > 0 && transientSize+int64(len(data)) > maxSize)) {
+		closeTransientFile()
+	}
+	if transientFile == nil {
+		if err := openTransientFile(); err != nil {
+			recordDrop(1, causeFileUnavailable)
+			return
+		}
+	}
+
+	n, err := transientFile.Write(data)
+	transientSize += int64(n)
+	if err != nil {
+		recordDrop(1, causeWriteError)
+		return
+	}
+	bytesWritten.Add(uint64(len(data)))
+	recordsWritten.Add(1)
+
+	dispatchSinks(record, line)
+}
+
+// openTransientFile creates a new transient file
+func openTransientFile() error {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	filename, err := generateLogFileName(name+transientSuffix, clockNow())
+	if err != nil {
+		return fmt.Errorf("failed to generate transient log filename: %w", err)
+	}
+	f, err := openLogFile(filepath.Join(directory, filename))
+	if err != nil {
+		return fmt.Errorf("failed to create transient log file: %w", err)
+	}
+	if err := lockShared(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to lock transient log file: %w", err)
+	}
+	transientSize = 0
+	if len(pipeline) > 0 {
+		header := pipelineHeader()
+		if _, err := f.Write(header); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write pipeline file header: %w", err)
+		}
+		transientSize = int64(len(header))
+	}
+	transientFile = f
+	transientActive.Store(filepath.Base(f.Name()))
+	transientOpened = clockNow()
+	return nil
+}
+
+// closeTransientFile syncs and closes the transient file if open
+func closeTransientFile() {
+	if transientFile == nil {
+		return
+	}
+	transientFile.Sync()
+	transientFile.Close()
+	transientFile = nil
+	transientActive.Store("")
+}
+
+// purgeTransientLogs deletes transient files last written more than the transient retention ago,
+// skipping the active file and files of other processes still writing to them.
+func purgeTransientLogs() {
+	if transientRetention <= 0 {
+		return
+	}
+	unlock, ok := lockCleanup()
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return
+	}
+	prefix := name + transientSuffix + "_"
+	active, _ := transientActive.Load().(string)
+	cutoff := clockNow().Add(-transientRetention)
+	for _, entry := range entries {
+		fname := entry.Name()
+		if !strings.HasPrefix(fname, prefix) || filepath.Ext(fname) != "."+extension ||
+			isExcluded(fname) || fname == active {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(directory, fname)
+		if fileInUse(path) {
+			continue
+		}
+		removeLogFile(path)
+	}
+}
+
+// contextWithTransient marks records logged with the context as transient
+func contextWithTransient(ctx context.Context) context.Context {
+	return context.WithValue(ctx, transientKey{}, true)
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// maxRecordBytes is the size above which serialized records are truncated, 0 disables the limit
+var maxRecordBytes int
+
+// Record truncation limits. minRecordBytes is the smallest MaxRecordBytes accepted, minStringLimit the
+// shortest strings are cut to before the fields of a record are dropped.
+const (
+	minRecordBytes = 256
+	minStringLimit = 16
+)
+
+// truncationSuffix ends a string cut by the record size limit
+const truncationSuffix = "..."
+
+// serializeLimited serializes a record in the configured format without indentation, truncating it if the
+// line exceeds MaxRecordBytes. Strings of the record are cut to ever shorter lengths until the line fits,
+// and if that isn't enough, the fields are dropped and only the message is kept. A truncated record carries
+// "truncated":true and its size before truncation as "original_bytes" after the metadata.
+func (s *serializer) serializeLimited(r *logRecord) []byte {
+	line := s.serializeFormat(r)
+	if maxRecordBytes <= 0 || len(line) <= maxRecordBytes {
+		return line
+	}
+
+	s.truncatedFrom = len(line)
+	defer func() { s.stringLimit, s.truncatedFrom = 0, 0 }()
+	for s.stringLimit = maxRecordBytes / 2; s.stringLimit >= minStringLimit; s.stringLimit /= 2 {
+		s.reset()
+		if line = s.serializeFormat(r); len(line) <= maxRecordBytes {
+			return line
+		}
+	}
+
+	// Too many fields or values that can't be cut, such as composite values
+	msg, _, _ := splitMessage(r)
+	short := logRecord{
+		LogCtx:    r.LogCtx,
+		Flags:     r.Flags,
+		TimeStamp: r.TimeStamp,
+		Level:     r.Level,
+		Trace:     r.Trace,
+		Seq:       r.Seq,
+		Goroutine: r.Goroutine,
+		HasMsg:    true,
+		Msg:       msg,
+	}
+	s.stringLimit = minStringLimit
+	s.reset()
+	return s.serializeFormat(&short)
+}
+
+// limitString returns str cut to the string limit of a truncated record at a rune boundary, and whether it
+// was cut
+func (s *serializer) limitString(str string) (string, bool) {
+	if s.stringLimit <= 0 || len(str) <= s.stringLimit {
+		return str, false
+	}
+	n := s.stringLimit
+	for n > 0 && !utf8.RuneStart(str[n]) {
+		n--
+	}
+	return str[:n], true
+}
+
+// writeJSONTruncation writes the truncation marker members of a truncated record
+func (s *serializer) writeJSONTruncation() {
+	if s.truncatedFrom == 0 {
+		return
+	}
+	s.writeJSONKey("truncated")
+	s.buf = append(s.buf, "true"...)
+	s.writeJSONKey("original_bytes")
+	s.buf = strconv.AppendInt(s.buf, int64(s.truncatedFrom), 10)
+}